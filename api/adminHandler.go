@@ -20,7 +20,7 @@ func (a *AppContext) registerUser(w http.ResponseWriter, r *http.Request) {
 		SendError(w, err)
 		return
 	}
-	userHelper := &helpers.UserHelper{StatStorePath:a.StatStorePath}
+	userHelper := &helpers.UserHelper{StatStorePath:a.StatStorePath, RoleAttribute:a.RolePolicy.RoleAttribute}
 	err = InitHelper(r, userHelper)
 	if err != nil {
 		SendError(w, err)
@@ -84,6 +84,21 @@ func (a *AppContext) revokeUser(w http.ResponseWriter, r *http.Request) {
 		SendError(w, err)
 		return
 	}
+	if a.Sessions != nil {
+		a.Sessions.Remove(credentials.UserName)
+	}
+	if a.Tokens != nil {
+		err = a.Tokens.RevokeSubject(credentials.UserName)
+		if err != nil {
+			SendError(w, err)
+			return
+		}
+	}
+	err = helpers.ForgetCredentials(credentials.UserName, a.StatStorePath)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
 	content := []byte("")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -22,6 +22,27 @@ func TestRegisterUserAPINominal(t *testing.T) {
 	}
 }
 
+// the bootstrap admin of a fresh server registers the first users with their roles
+func TestRegisterUserWithRoleAPINominal(t *testing.T) {
+	username := helpers.CreateRandomName()
+	registerUser := helpers.UserRegistrer{Name: username, Type: "user", Affiliation: "org1.department1", Roles: []string{helpers.ROLE_APP}}
+	enrollmentSecret, err := sendRegister(registerUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userCredentials := helpers.UserCredentials{UserName: username, EnrollmentSecret: enrollmentSecret}
+	err = sendEnrollUser(userCredentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles, err := helpers.GetRoles(username, configuration.StatstorePath, configuration.RoleAttribute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 1 || roles[0] != helpers.ROLE_APP {
+		t.Error("bad roles: ", roles)
+	}
+}
 
 func TestEnrollUserAPINominal(t *testing.T) {
 	username := helpers.CreateRandomName()
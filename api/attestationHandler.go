@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"fmt"
+	"github.com/pascallimeux/ocmsV2/attestation"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+type AttestationToken struct {
+	Token string `json:"token"`
+}
+
+//HTTP Post - /ocms/v2/api/attestation/verify
+func (a *AppContext) verifyAttestation(w http.ResponseWriter, r *http.Request) {
+	log.Debug("verifyAttestation() : calling method -")
+	if a.AttestationKey == nil {
+		SendError(w, helpers.NewError(helpers.ERROR_INTERNAL, "attestation is not configured"))
+		return
+	}
+	var token AttestationToken
+	err := json.NewDecoder(r.Body).Decode(&token)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	claims, err := attestation.Verify(token.Token, &a.AttestationKey.PublicKey)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	content, _ := json.Marshal(claims)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+func (a *AppContext) createAttestation(consentHelper *helpers.ConsentHelper, consent helpers.Consent, consentID string) (string, error) {
+	message := fmt.Sprintf("createAttestation(consentID=%s) : calling method -", consentID)
+	log.Debug(message)
+	blockHeight, err := consentHelper.GetBlockHeight()
+	if err != nil {
+		return "", err
+	}
+	claims := attestation.NewClaims(a.AttestationIssuer, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess, consentID, blockHeight, a.AttestationTTL)
+	return attestation.Sign(claims, a.AttestationKey)
+}
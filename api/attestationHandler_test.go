@@ -0,0 +1,79 @@
+package api
+
+import (
+	"testing"
+	"errors"
+	"net/http"
+	"encoding/json"
+	"time"
+	"github.com/pascallimeux/ocmsV2/attestation"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestIsConsentWithAttestationFromAPINominal(t *testing.T) {
+	consent := helpers.Consent{DataType: "All", OwnerID: "ATT1", ConsumerID: "ATT2"}
+	consentID, err := createConsent(consent)
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	response, err := isConsent(consent)
+	if err != nil {
+		t.Error(err)
+	}
+	if response.Consent != "True" || response.Attestation == "" {
+		t.Error("bad response, attestation expected")
+	}
+	claims, err := verifyAttestation(response.Attestation)
+	if err != nil {
+		t.Error(err)
+	}
+	if claims.ConsentID != consentID || claims.OwnerID != consent.OwnerID || claims.ConsumerID != consent.ConsumerID {
+		t.Error("bad claims in attestation")
+	}
+}
+
+func TestVerifyAttestationFromAPIWithBadToken(t *testing.T) {
+	_, err := verifyAttestation("bad.token.value")
+	if err == nil {
+		t.Error("error expected for a bad token")
+	}
+}
+
+func isConsent(consent helpers.Consent) (IsConsent, error) {
+	var response IsConsent
+	consent.Action = "isconsent"
+	consent.AppID = APPID
+	data, _ := json.Marshal(consent)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return response, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return response, err
+	}
+	if status != http.StatusOK {
+		return response, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &response)
+	return response, err
+}
+
+func verifyAttestation(token string) (attestation.Claims, error) {
+	var claims attestation.Claims
+	data, _ := json.Marshal(AttestationToken{Token: token})
+	request, err := buildRequest("POST", httpServerTest.URL+ATTESTATIONAPI, string(data))
+	if err != nil {
+		return claims, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return claims, err
+	}
+	if status != http.StatusOK {
+		return claims, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &claims)
+	return claims, err
+}
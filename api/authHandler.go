@@ -1,12 +1,30 @@
 package api
 
 import (
-	"errors"
+	"context"
 	"net/http"
+	"sync"
 	"github.com/pascallimeux/ocmsV2/helpers"
 )
 
+type contextKey string
+
+// sessions borrowed during a request, released when the request is served
+const SESSIONSKEY = contextKey("sessions")
+
+type borrowedSessions struct {
+	manager		*helpers.SessionManager
+	mutex		sync.Mutex
+	sessions	[]*helpers.Session
+}
+
+// GetUserCredentials returns the identity of the bearer token checked by Authenticate or of the client certificate,
+// else the basic credentials (the enrollment secret is empty without basic auth, the user is loaded from the state store)
 func GetUserCredentials(r *http.Request)(helpers.UserCredentials, error){
+	if userCredentials, ok := r.Context().Value(CREDENTIALSKEY).(helpers.UserCredentials); ok {
+		log.Debug("GetUserCredentials(user:" + userCredentials.UserName + ", authenticated) : calling method -")
+		return userCredentials, nil
+	}
 	userCredentials := helpers.UserCredentials{}
 	username, password, ok :=r.BasicAuth()
 	if ok{
@@ -15,14 +33,67 @@ func GetUserCredentials(r *http.Request)(helpers.UserCredentials, error){
 		userCredentials.EnrollmentSecret = password
 		return userCredentials, nil
 	}
-	return userCredentials, errors.New("no credential in request")
+	return userCredentials, helpers.NewError(helpers.ERROR_UNAUTHORIZED, "no credential in request")
 }
 
+// InitHelper inits the helper with the session of the identity when the request is served by BorrowSessions,
+// else with a new client and chain, the transactions are sent in the commit mode of the request
 func InitHelper (r *http.Request, helper helpers.Helper)  error {
 	userCredentials, err := GetUserCredentials(r)
 	if err != nil {
 		return err
 	}
+	if commit, ok := r.Context().Value(COMMITKEY).(*helpers.Commit); ok {
+		if commitHelper, isCommitHelper := helper.(helpers.CommitHelper); isCommitHelper {
+			commitHelper.SetCommit(commit)
+		}
+	}
+	borrowed, ok := r.Context().Value(SESSIONSKEY).(*borrowedSessions)
+	sessionHelper, isSessionHelper := helper.(helpers.SessionHelper)
+	if ok && isSessionHelper {
+		session, err := borrowed.manager.Borrow(userCredentials)
+		if err != nil {
+			return err
+		}
+		borrowed.mutex.Lock()
+		borrowed.sessions = append(borrowed.sessions, session)
+		borrowed.mutex.Unlock()
+		sessionHelper.InitSession(session)
+		return nil
+	}
 	err = helper.Init(userCredentials)
 	return err
-}
\ No newline at end of file
+}
+
+// BorrowSessions lets the handlers borrow the sessions of the manager, they are released at the end of the request
+func BorrowSessions(manager *helpers.SessionManager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if manager == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		borrowed := &borrowedSessions{manager: manager}
+		defer func() {
+			for _, session := range borrowed.sessions {
+				manager.Release(session)
+			}
+		}()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), SESSIONSKEY, borrowed)))
+	})
+}
+
+// ClientCertIdentity uses the CN of the verified client certificate (mutual TLS) as the identity of the request,
+// a bearer token checked before keeps the priority
+func ClientCertIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, authenticated := r.Context().Value(CREDENTIALSKEY).(helpers.UserCredentials)
+		if !authenticated && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+			if commonName != "" {
+				credentials := helpers.UserCredentials{UserName: commonName}
+				r = r.WithContext(context.WithValue(r.Context(), CREDENTIALSKEY, credentials))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/auth"
+)
+
+func TestClientCertIdentity(t *testing.T) {
+	key, _ := auth.GenerateKey()
+	tokens, _ := auth.NewManager(key, "ocms", time.Minute, time.Hour, "")
+	handler := Authenticate(tokens, false, "", ClientCertIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials, err := GetUserCredentials(r)
+		if err != nil {
+			SendError(w, err)
+			return
+		}
+		w.Write([]byte(credentials.UserName))
+	})))
+	clientCert := &x509.Certificate{Subject: pkix.Name{CommonName: "user1"}}
+	request := httptest.NewRequest("GET", BCINFO, nil)
+	request.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert}}}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "user1" {
+		t.Error("bad identity: ", recorder.Code, recorder.Body.String())
+	}
+
+	// the bearer token keeps the priority
+	token, _, _ := tokens.Issue("user2")
+	request.Header.Set("Authorization", BEARER+token)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Body.String() != "user2" {
+		t.Error("bad identity: ", recorder.Body.String())
+	}
+
+	// a certificate not verified is not an identity
+	request = httptest.NewRequest("GET", BCINFO, nil)
+	request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Error("unverified certificate accepted: ", recorder.Code)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+	"github.com/pascallimeux/ocmsV2/backup"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+type BackupRequest struct {
+	AppID           string `json:"appid"`
+	File            string `json:"file,omitempty"`	// name of the backup file in the backup directory
+}
+
+type BackupReceipt struct {
+	AppID           string `json:"appid"`
+	File            string `json:"file"`
+	Version         int    `json:"version"`
+	Count           int    `json:"count"`
+	Checksum        string `json:"checksum"`
+}
+
+//HTTP Post - /ocms/v2/admin/backup
+func (a *AppContext) backupConsents(w http.ResponseWriter, r *http.Request) {
+	log.Debug("backupConsents() : calling method -")
+	request, consentHelper, err := a.initBackupRequest(r)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if request.File == "" {
+		request.File = request.AppID + "-" + time.Now().UTC().Format("20060102T150405Z") + ".json"
+	}
+	path, err := a.backupFilePath(request.File)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	consents, err := a.exportConsents(consentHelper, a.ChainCodeID, request.AppID)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	file, err := backup.New(request.AppID, a.ChainCodeID, consents)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	err = backup.Write(path, file)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	receipt := BackupReceipt{AppID: request.AppID, File: request.File, Version: file.Version, Count: file.Count, Checksum: file.Checksum}
+	content, _ := json.Marshal(receipt)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+//HTTP Post - /ocms/v2/admin/restore
+func (a *AppContext) restoreConsents(w http.ResponseWriter, r *http.Request) {
+	log.Debug("restoreConsents() : calling method -")
+	request, consentHelper, err := a.initBackupRequest(r)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	path, err := a.backupFilePath(request.File)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	file, err := backup.Read(path)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if file.AppID != request.AppID {
+		SendError(w, validationError("backup file is not a backup of appID: " + request.AppID))
+		return
+	}
+	err = a.importConsents(consentHelper, a.ChainCodeID, file)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	receipt := BackupReceipt{AppID: file.AppID, File: request.File, Version: file.Version, Count: file.Count, Checksum: file.Checksum}
+	content, _ := json.Marshal(receipt)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+func (a *AppContext) initBackupRequest(r *http.Request) (BackupRequest, *helpers.ConsentHelper, error) {
+	var request BackupRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		return request, nil, err
+	}
+	if request.AppID == "" {
+		return request, nil, validationError("appid is mandatory!")
+	}
+	if a.BackupPath == "" {
+		return request, nil, helpers.NewError(helpers.ERROR_INTERNAL, "backup path is not configured")
+	}
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err = InitHelper(r, consentHelper)
+	return request, consentHelper, err
+}
+
+// the backup files are always in the backup directory
+func (a *AppContext) backupFilePath(fileName string) (string, error) {
+	if fileName == "" || fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." {
+		return "", validationError("file must be a file name in the backup directory")
+	}
+	return filepath.Join(a.BackupPath, fileName), nil
+}
+
+func (a *AppContext) exportConsents(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID string) ([]json.RawMessage, error) {
+	message := fmt.Sprintf("exportConsents(applicationID=%s) : calling method -", applicationID)
+	log.Info(message)
+	consents := []json.RawMessage{}
+	bookmark := ""
+	for {
+		page, err := consentHelper.ExportConsents(chainCodeID, applicationID, a.backupPageSize(), bookmark)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, page.Consents...)
+		if !page.HasMore {
+			return consents, nil
+		}
+		bookmark = page.Bookmark
+	}
+}
+
+func (a *AppContext) importConsents(consentHelper *helpers.ConsentHelper, chainCodeID string, file *backup.File) error {
+	message := fmt.Sprintf("importConsents(applicationID=%s, count=%d) : calling method -", file.AppID, file.Count)
+	log.Info(message)
+	// each page is committed before the next one: a failed restore stops at the failed page and can be run again,
+	// the consents already imported are skipped by the chaincode
+	if consentHelper.Commit == nil {
+		consentHelper.SetCommit(&helpers.Commit{})
+	}
+	consentHelper.Commit.Mode = helpers.COMMIT_WAIT
+	pageSize := a.backupPageSize()
+	for start := 0; start < len(file.Consents); start += pageSize {
+		end := start + pageSize
+		if end > len(file.Consents) {
+			end = len(file.Consents)
+		}
+		_, err := consentHelper.ImportConsents(chainCodeID, file.AppID, file.Consents[start:end])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AppContext) backupPageSize() int {
+	if a.BackupPageSize <= 0 {
+		return 100
+	}
+	return a.BackupPageSize
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestBackupFromAPINominal(t *testing.T) {
+	_, err := createConsent(helpers.Consent{DataType: "All", OwnerID: "BCK1", ConsumerID: "BCK2"})
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	receipt, err := postBackup(BACKUP, BackupRequest{AppID: APPID})
+	if err != nil {
+		t.Error(err)
+	}
+	if receipt.File == "" || receipt.Count == 0 || receipt.Checksum == "" {
+		t.Error("bad backup receipt: ", receipt)
+	}
+}
+
+func TestRestoreFromAPIOutsideBackupPath(t *testing.T) {
+	_, err := postBackup(RESTORE, BackupRequest{AppID: APPID, File: "../ocms.toml"})
+	if err == nil {
+		t.Error("restore of a file outside the backup directory must be rejected")
+	}
+}
+
+func postBackup(uri string, backupRequest BackupRequest) (BackupReceipt, error) {
+	receipt := BackupReceipt{}
+	data, _ := json.Marshal(backupRequest)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+uri, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return receipt, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return receipt, err
+	}
+	if status != http.StatusOK {
+		return receipt, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &receipt)
+	return receipt, err
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"time"
 	"fmt"
-	"errors"
 	"github.com/pascallimeux/ocmsV2/helpers"
 )
 
@@ -16,6 +15,9 @@ type Version struct {
 
 type IsConsent struct {
 	Consent string
+	Attestation string `json:",omitempty"`
+	BreakGlassID string `json:",omitempty"`
+	Unmet []helpers.Condition `json:",omitempty"`
 }
 
 //HTTP Get - /ocms/v2/api/version
@@ -59,20 +61,36 @@ func (a *AppContext) processConsent(w http.ResponseWriter, r *http.Request) {
 	case "create":
 		bytes, err = a.createConsent(consentHelper, a.ChainCodeID, consent)
 	case "list":
-		bytes, err = a.listConsents(consentHelper, a.ChainCodeID, consent.AppID)
+		bytes, err = a.listConsents(consentHelper, a.ChainCodeID, consent.AppID, bodyFilter(consent))
 	case "get":
 		bytes, err = a.getConsent(consentHelper, a.ChainCodeID, consent.AppID, consent.ConsentID)
+	case "getbyref":
+		bytes, err = a.getConsentByRef(consentHelper, a.ChainCodeID, consent.AppID, consent.ExternalRef)
 	case "remove":
 		bytes, err = a.unactivateConsent(consentHelper, a.ChainCodeID, consent.AppID, consent.ConsentID)
 	case "list4owner":
-		bytes, err = a.getConsents4Owner(consentHelper, a.ChainCodeID, consent.AppID, consent.OwnerID)
+		bytes, err = a.getConsents4Owner(consentHelper, a.ChainCodeID, consent.AppID, consent.OwnerID, bodyFilter(consent))
 	case "list4consumer":
-		bytes, err = a.getConsents4Consumer(consentHelper, a.ChainCodeID, consent.AppID, consent.ConsumerID)
+		bytes, err = a.getConsents4Consumer(consentHelper, a.ChainCodeID, consent.AppID, consent.ConsumerID, bodyFilter(consent))
 	case "isconsent":
 		bytes, err = a.isConsent(consentHelper, a.ChainCodeID, consent)
+	case "wasconsent":
+		bytes, err = a.wasConsent(consentHelper, a.ChainCodeID, consent)
+	case "logaccess":
+		bytes, err = a.logAccess(consentHelper, a.ChainCodeID, consent)
+	case "accesses4owner":
+		bytes, err = a.getAccesses4Owner(consentHelper, a.ChainCodeID, consent.AppID, consent.OwnerID)
+	case "registerownerkey":
+		bytes, err = a.registerOwnerKey(consentHelper, a.ChainCodeID, consent)
+	case "breakglass":
+		bytes, err = a.breakGlass(consentHelper, a.ChainCodeID, consent)
+	case "pendingbreakglass":
+		bytes, err = a.getPendingBreakGlass(consentHelper, a.ChainCodeID, consent.AppID)
+	case "ackbreakglass":
+		bytes, err = a.ackBreakGlass(consentHelper, a.ChainCodeID, consent.AppID, consent.EventID)
 	default:
 		log.Error("bad action request")
-		SendError(w, err)
+		SendError(w, validationError("bad action request"))
 		return
 	}
 	if err != nil {
@@ -97,22 +115,31 @@ func (a *AppContext) createConsent(consentHelper *helpers.ConsentHelper, chainCo
 	if err != nil {
 		return nil, err
 	}
-	consentID, err := consentHelper.CreateConsent(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess, consent.Dt_begin, consent.Dt_end)
+	if consent.Mode == "" {
+		consent.Mode = a.DuplicateMode
+	}
+	var options []helpers.ConsentOptions
+	if consent.OwnerSignature != "" || consent.Mode != "" || consent.ExternalRef != "" || len(consent.Conditions) > 0 {
+		options = append(options, helpers.ConsentOptions{OwnerSignature: consent.OwnerSignature, OwnerCert: consent.OwnerCert, Nonce: consent.Nonce, Mode: consent.Mode, ExternalRef: consent.ExternalRef, Conditions: consent.Conditions})
+	}
+	consentID, err := consentHelper.CreateConsent(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess, consent.Dt_begin, consent.Dt_end, options...)
 	if err != nil {
 		return nil, err
 	}
 	consent.ConsentID = consentID
+	consent.OwnerSigned = consent.OwnerSignature != ""
+	consent.OwnerCert = ""
 	return consent2Bytes(consent)
 }
 
-func (a *AppContext) listConsents(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID string) ([]byte, error) {
+func (a *AppContext) listConsents(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID string, filter helpers.ConsentFilter) ([]byte, error) {
 	message := fmt.Sprintf("listConsents(applicationID=%s) : calling method -", applicationID)
 	log.Info(message)
-	consents, err := consentHelper.GetConsents(chainCodeID, applicationID)
+	consents, err := consentHelper.GetConsents(chainCodeID, applicationID, filter)
 	if err != nil {
 		return nil, err
 	}
-	return consents2Bytes(consents)
+	return filteredConsents2Bytes(consents, filter)
 }
 
 func (a *AppContext) getConsent(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, consentID string) ([]byte, error) {
@@ -125,6 +152,19 @@ func (a *AppContext) getConsent(consentHelper *helpers.ConsentHelper, chainCodeI
 	return consent2Bytes(consent)
 }
 
+func (a *AppContext) getConsentByRef(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, externalRef string) ([]byte, error) {
+	message := fmt.Sprintf("getConsentByRef(applicationID=%s, externalRef=%s) : calling method -", applicationID, externalRef)
+	log.Info(message)
+	if externalRef == "" {
+		return nil, validationError("externalref is mandatory!")
+	}
+	consent, err := consentHelper.GetConsentByRef(chainCodeID, applicationID, externalRef)
+	if err != nil {
+		return nil, err
+	}
+	return consent2Bytes(consent)
+}
+
 func (a *AppContext) unactivateConsent(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, consentID string) ([]byte, error) {
 	message := fmt.Sprintf("unactivateConsent(applicationID=%s, consentID=%s) : calling method -", applicationID, consentID)
 	log.Info(message)
@@ -139,43 +179,181 @@ func (a *AppContext) unactivateConsent(consentHelper *helpers.ConsentHelper, cha
 	return consent2Bytes(consent)
 }
 
-func (a *AppContext) getConsents4Consumer(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, consumerID string) ([]byte, error) {
+func (a *AppContext) getConsents4Consumer(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, consumerID string, filter helpers.ConsentFilter) ([]byte, error) {
 	message := fmt.Sprintf("getConsents4Consumer(applicationID=%s, consumerID=%s) : calling method -", applicationID, consumerID)
 	log.Info(message)
-	consents, err := consentHelper.GetConsumerConsents(chainCodeID, applicationID, consumerID)
+	consents, err := consentHelper.GetConsumerConsents(chainCodeID, applicationID, consumerID, filter)
 	if err != nil {
 		return nil, err
 	}
-	return consents2Bytes(consents)
+	return filteredConsents2Bytes(consents, filter)
 }
 
-func (a *AppContext) getConsents4Owner(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, ownerID string) ([]byte, error) {
+func (a *AppContext) getConsents4Owner(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, ownerID string, filter helpers.ConsentFilter) ([]byte, error) {
 	message := fmt.Sprintf("getConsents4Owner(applicationID=%s, ownerID=%s) : calling method -", applicationID, ownerID)
 	log.Info(message)
-	consents, err := consentHelper.GetOwnerConsents(chainCodeID, applicationID, ownerID)
+	consents, err := consentHelper.GetOwnerConsents(chainCodeID, applicationID, ownerID, filter)
 	if err != nil {
 		return nil, err
 	}
-	return consents2Bytes(consents)
+	return filteredConsents2Bytes(consents, filter)
+}
+
+// filter of the list actions given in the body
+func bodyFilter(consent helpers.Consent) helpers.ConsentFilter {
+	return helpers.ConsentFilter{DataType: consent.DataType, DataAccess: consent.DataAccess, ValidAt: consent.ValidAt,
+		CreatedAfter: consent.CreatedAfter, CreatedBefore: consent.CreatedBefore, State: consent.State, Sort: consent.Sort, Fields: consent.Fields}
+}
+
+// the consents reduced to the selected fields are returned without the other attributes
+func filteredConsents2Bytes(consents []helpers.Consent, filter helpers.ConsentFilter) ([]byte, error) {
+	if len(filter.Fields) == 0 {
+		return consents2Bytes(consents)
+	}
+	selected, err := helpers.SelectFields(consents, filter.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(selected)
 }
 
 func (a *AppContext) isConsent(consentHelper *helpers.ConsentHelper, chainCodeID string, consent helpers.Consent) ([]byte, error) {
 	message := fmt.Sprintf("isConsent(consent=%s) : calling method -", consent.Print())
 	log.Info(message)
-	isconsent, err := consentHelper.IsConsentExist(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess)
+	// a missing data type is refused, All is a data type and not a wildcard
+	if consent.DataType == "" {
+		return nil, validationError("dataType is mandatory!")
+	}
+	if consent.DataAccess == "" {
+		consent.DataAccess = "A"
+	}
+	decision, err := consentHelper.CheckConsent(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess, consent.Context)
 	if err != nil {
 		return nil, err
 	}
 	response := IsConsent{}
-	if isconsent {
+	if decision.Consent == "True" && decision.BreakGlassID != "" {
+		// emergency access: no consent to attest nor to reference in the access log
 		response.Consent = "True"
+		response.BreakGlassID = decision.BreakGlassID
+	} else if decision.Consent == "True" {
+		response.Consent = "True"
+		if a.AttestationKey != nil {
+			response.Attestation, err = a.createAttestation(consentHelper, consent, decision.ConsentID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if a.LogAccessOnIsConsent {
+			_, err = consentHelper.LogAccess(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess, decision.ConsentID)
+			if err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		response.Consent = "False"
+		response.Unmet = decision.Unmet
 	}
 	content, _ := json.Marshal(response)
 	return content, nil
 }
 
+func (a *AppContext) wasConsent(consentHelper *helpers.ConsentHelper, chainCodeID string, consent helpers.Consent) ([]byte, error) {
+	message := fmt.Sprintf("wasConsent(consent=%s, at=%s) : calling method -", consent.Print(), consent.At)
+	log.Info(message)
+	if consent.At == "" {
+		return nil, validationError("at is mandatory!")
+	}
+	if consent.DataType == "" {
+		return nil, validationError("dataType is mandatory!")
+	}
+	if consent.DataAccess == "" {
+		consent.DataAccess = "A"
+	}
+	decision, err := consentHelper.WasConsent(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess, consent.At)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(decision)
+}
+
+func (a *AppContext) logAccess(consentHelper *helpers.ConsentHelper, chainCodeID string, consent helpers.Consent) ([]byte, error) {
+	message := fmt.Sprintf("logAccess(consent=%s) : calling method -", consent.Print())
+	log.Info(message)
+	if consent.ConsentID == "" {
+		return nil, validationError("consentID is mandatory!")
+	}
+	eventID, err := consentHelper.LogAccess(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.DataAccess, consent.ConsentID)
+	if err != nil {
+		return nil, err
+	}
+	event := helpers.AccessEvent{AppID: consent.AppID, EventID: eventID, OwnerID: consent.OwnerID, ConsumerID: consent.ConsumerID, DataType: consent.DataType, DataAccess: consent.DataAccess, ConsentID: consent.ConsentID}
+	return json.Marshal(event)
+}
+
+func (a *AppContext) getAccesses4Owner(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, ownerID string) ([]byte, error) {
+	message := fmt.Sprintf("getAccesses4Owner(applicationID=%s, ownerID=%s) : calling method -", applicationID, ownerID)
+	log.Info(message)
+	events, err := consentHelper.GetOwnerAccesses(chainCodeID, applicationID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(events)
+}
+
+func (a *AppContext) registerOwnerKey(consentHelper *helpers.ConsentHelper, chainCodeID string, consent helpers.Consent) ([]byte, error) {
+	message := fmt.Sprintf("registerOwnerKey(applicationID=%s, ownerID=%s) : calling method -", consent.AppID, consent.OwnerID)
+	log.Info(message)
+	if consent.OwnerID == "" || consent.OwnerCert == "" {
+		return nil, validationError("ownerID and ownercert are mandatory!")
+	}
+	// the ownersignature (of the new certificate by the registered key) rotates a registered key
+	_, err := consentHelper.RegisterOwnerKey(chainCodeID, consent.AppID, consent.OwnerID, consent.OwnerCert, consent.OwnerSignature)
+	if err != nil {
+		return nil, err
+	}
+	ownerKey := helpers.OwnerKey{AppID: consent.AppID, OwnerID: consent.OwnerID, Certificate: consent.OwnerCert}
+	return json.Marshal(ownerKey)
+}
+
+func (a *AppContext) breakGlass(consentHelper *helpers.ConsentHelper, chainCodeID string, consent helpers.Consent) ([]byte, error) {
+	message := fmt.Sprintf("breakGlass(consent=%s, reason=%s, duration=%s) : calling method -", consent.Print(), consent.Reason, consent.Duration)
+	log.Info(message)
+	if consent.Reason == "" || consent.Duration == "" {
+		return nil, validationError("reason and duration are mandatory!")
+	}
+	eventID, err := consentHelper.BreakGlass(chainCodeID, consent.AppID, consent.OwnerID, consent.ConsumerID, consent.DataType, consent.Reason, consent.Duration)
+	if err != nil {
+		return nil, err
+	}
+	event := helpers.BreakGlassEvent{AppID: consent.AppID, EventID: eventID, OwnerID: consent.OwnerID, ConsumerID: consent.ConsumerID, DataType: consent.DataType, Reason: consent.Reason}
+	return json.Marshal(event)
+}
+
+func (a *AppContext) getPendingBreakGlass(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID string) ([]byte, error) {
+	message := fmt.Sprintf("getPendingBreakGlass(applicationID=%s) : calling method -", applicationID)
+	log.Info(message)
+	events, err := consentHelper.GetPendingBreakGlass(chainCodeID, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(events)
+}
+
+func (a *AppContext) ackBreakGlass(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, eventID string) ([]byte, error) {
+	message := fmt.Sprintf("ackBreakGlass(applicationID=%s, eventID=%s) : calling method -", applicationID, eventID)
+	log.Info(message)
+	if eventID == "" {
+		return nil, validationError("eventID is mandatory!")
+	}
+	_, err := consentHelper.AckBreakGlass(chainCodeID, applicationID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	event := helpers.BreakGlassEvent{AppID: applicationID, EventID: eventID, Reviewed: true}
+	return json.Marshal(event)
+}
+
 func consents2Bytes(consents []helpers.Consent) ([]byte, error) {
 	log.Debug("consents2Bytes() : calling method -")
 	j, err := json.Marshal(consents)
@@ -197,20 +375,20 @@ func consent2Bytes(consent helpers.Consent) ([]byte, error) {
 func check_args(consent *helpers.Consent) error {
 	log.Debug("check_args() : calling method -")
 	if consent.AppID == "" {
-		return errors.New("appID is mandatory!")
+		return validationError("appID is mandatory!")
 	}
 	if consent.OwnerID == "" {
-		return errors.New("ownerID is mandatory!")
+		return validationError("ownerID is mandatory!")
 	}
 	if consent.ConsumerID == "" {
-		return errors.New("consumerID is mandatory!")
+		return validationError("consumerID is mandatory!")
+	}
+	if consent.DataType == "" {
+		return validationError("dataType is mandatory!")
 	}
 	if consent.DataAccess == "" {
 		consent.DataAccess = "A"
 	}
-	if consent.DataType == "" {
-		consent.DataType = "All"
-	}
 	if consent.Dt_begin == "" {
 		consent.Dt_begin = time.Now().Format("2006-01-02")
 	}
@@ -6,6 +6,10 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pascallimeux/ocmsV2/helpers"
 	"github.com/pascallimeux/ocmsV2/settings"
+	"github.com/pascallimeux/ocmsV2/attestation"
+	"github.com/pascallimeux/ocmsV2/auth"
+	"github.com/pascallimeux/ocmsV2/idempotency"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -17,7 +21,7 @@ var configuration settings.Settings
 var httpServerTest *httptest.Server
 const(
 	ADMINNAME          = "admin"
-	ADMINPWD           = "admpw"
+	ADMINPWD           = "adminpw"
 	APPID              = "apptest"
 	TransactionTimeout = time.Millisecond * 1500
 )
@@ -67,6 +71,44 @@ func setup() {
 		Repo:                   configuration.Repo,
 		StatStorePath:          configuration.StatstorePath,
 		ChainID:         	configuration.ChainID,
+		AttestationIssuer:      configuration.AttestationIssuer,
+		AttestationTTL:         configuration.AttestationTTL,
+		LogAccessOnIsConsent:   configuration.LogAccessOnIsConsent,
+		DuplicateMode:          configuration.DuplicateMode,
+		CommitMode:             configuration.CommitMode,
+		CommitTimeout:          configuration.CommitTimeout,
+		Transactions:           helpers.NewTransactionTracker(configuration.TransactionTTL),
+		DocumentStorePath:      configuration.DocumentStorePath,
+		DocumentMaxSize:        configuration.DocumentMaxSize,
+		BackupPath:             configuration.BackupPath,
+		BackupPageSize:         configuration.BackupPageSize,
+		Sessions:               helpers.NewSessionManager(configuration.StatstorePath, configuration.ChainID, configuration.SessionMaxSize, configuration.SessionIdleTimeout),
+		AllowBasicAuth:         configuration.AllowBasicAuth,
+		RolePolicy:             RolePolicy{
+			RoleAttribute:  configuration.RoleAttribute,
+			Groups:         configuration.RouteRoles,
+			BootstrapAdmin: configuration.BootstrapAdmin,
+			StatStorePath:  configuration.StatstorePath},
+	}
+	appContext.AttestationKey, err = attestation.LoadPrivateKey(configuration.AttestationKeyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authKey, err := auth.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext.Tokens, err = auth.NewManager(authKey, configuration.AuthIssuer, configuration.AuthTTL, configuration.AuthRefreshTTL, "")
+	if err != nil {
+		log.Fatal(err)
+	}
+	idempotencyPath, err := ioutil.TempDir("", "idempotency")
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext.Idempotency, err = idempotency.NewStore(idempotencyPath, configuration.IdempotencyTTL)
+	if err != nil {
+		log.Fatal(err)
 	}
 	router := mux.NewRouter().StrictSlash(false)
 	appContext.CreateOCMSRoutes(router)
@@ -75,7 +117,7 @@ func setup() {
 	appContext.CreateOCMSRoutes(router)
 
 	// Init http server for tests
-	httpServerTest = httptest.NewServer(router)
+	httpServerTest = httptest.NewServer(RequestID(Authenticate(appContext.Tokens, appContext.AllowBasicAuth, appContext.StatStorePath, ClientCertIdentity(Authorize(appContext.RolePolicy, BorrowSessions(appContext.Sessions, Idempotency(appContext.Idempotency, Commits(appContext.CommitMode, appContext.CommitTimeout, appContext.Transactions, ValidateRequests(router)))))))))
 
 }
 
@@ -85,7 +127,7 @@ func shutdown() {
 }
 
 func TestCreateConsentFromAPINominal(t *testing.T) {
-	consent := helpers.Consent{OwnerID: "1111", ConsumerID: "2222"}
+	consent := helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: "2222"}
 	consentID, err := createConsent(consent)
 	if err != nil {
 		t.Error(err)
@@ -96,7 +138,7 @@ func TestCreateConsentFromAPINominal(t *testing.T) {
 }
 
 func TestGetConsentDetailFromAPINominal(t *testing.T) {
-	consent := helpers.Consent{OwnerID: "OOOO", ConsumerID: "AAAA"}
+	consent := helpers.Consent{DataType: "All", OwnerID: "OOOO", ConsumerID: "AAAA"}
 	consentID, err := createConsent(consent)
 	if err != nil {
 		t.Error(err)
@@ -113,9 +155,9 @@ func TestGetConsentDetailFromAPINominal(t *testing.T) {
 }
 
 func TestGetConsentsFromAPINominal(t *testing.T) {
-	createConsent(helpers.Consent{OwnerID: "1111", ConsumerID: "2222"})
-	createConsent(helpers.Consent{OwnerID: "1111", ConsumerID: "3333"})
-	createConsent(helpers.Consent{OwnerID: "1111", ConsumerID: "4444"})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: "2222"})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: "3333"})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: "4444"})
 	consents, err := getListOfConsents("", "")
 	if err != nil {
 		t.Error(err)
@@ -127,9 +169,9 @@ func TestGetConsentsFromAPINominal(t *testing.T) {
 
 func TestGetConsents4OwnerFromAPINominal(t *testing.T) {
 	ownerid := "1111"
-	createConsent(helpers.Consent{OwnerID: "1111", ConsumerID: "2222"})
-	createConsent(helpers.Consent{OwnerID: "1111", ConsumerID: "3333"})
-	createConsent(helpers.Consent{OwnerID: "1111", ConsumerID: "4444"})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: "2222"})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: "3333"})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: "4444"})
 	consents, err := getListOfConsents(ownerid, "")
 	if err != nil {
 		t.Error(err)
@@ -141,9 +183,9 @@ func TestGetConsents4OwnerFromAPINominal(t *testing.T) {
 
 func TestGetConsents4ConsumerFromAPINominal(t *testing.T) {
 	consumerid := "3333"
-	createConsent(helpers.Consent{OwnerID: "1111", ConsumerID: consumerid})
-	createConsent(helpers.Consent{OwnerID: "2222", ConsumerID: consumerid})
-	createConsent(helpers.Consent{OwnerID: "3333", ConsumerID: consumerid})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "1111", ConsumerID: consumerid})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "2222", ConsumerID: consumerid})
+	createConsent(helpers.Consent{DataType: "All", OwnerID: "3333", ConsumerID: consumerid})
 	consents, err := getListOfConsents("", consumerid)
 	if err != nil {
 		t.Error(err)
@@ -154,6 +196,106 @@ func TestGetConsents4ConsumerFromAPINominal(t *testing.T) {
 }
 
 
+func TestLogAccessFromAPINominal(t *testing.T) {
+	consent := helpers.Consent{DataType: "All", OwnerID: "LOG1", ConsumerID: "LOG2"}
+	consentID, err := createConsent(consent)
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	consent.ConsentID = consentID
+	err = logAccess(consent)
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	events, err := getAccesses4Owner(consent.OwnerID)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(events) == 0 {
+		t.Error("access event expected for owner")
+	}
+}
+
+func TestWasConsentFromAPINominal(t *testing.T) {
+	consent := helpers.Consent{DataType: "All", OwnerID: "WAS1", ConsumerID: "WAS2"}
+	consentID, err := createConsent(consent)
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	consent.At = time.Now().UTC().Format(time.RFC3339)
+	decision, err := wasConsent(consent)
+	if err != nil {
+		t.Error(err)
+	}
+	if decision.Consent != "True" || decision.ConsentID != consentID || decision.Version == 0 {
+		t.Error("consent ", consentID, " not granted at ", consent.At)
+	}
+}
+
+func TestIsConsentWithConditionsFromAPI(t *testing.T) {
+	consent := helpers.Consent{DataType: "All", OwnerID: "CND1", ConsumerID: "CND2", Conditions: []helpers.Condition{{Attribute: "study", Operator: "eq", Value: "S1"}}}
+	_, err := createConsent(consent)
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	consent.Conditions = nil
+	consent.Action = "isconsent"
+	consent.AppID = APPID
+	consent.Context = map[string]string{"study": "S2"}
+	data, _ := json.Marshal(consent)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		t.Error(err)
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		t.Error(err)
+	}
+	response := IsConsent{}
+	json.Unmarshal(body_bytes, &response)
+	if status != http.StatusOK || response.Consent != "False" || len(response.Unmet) != 1 {
+		t.Error("unmet condition expected: ", string(body_bytes))
+	}
+}
+
+func TestDataTypeMandatory(t *testing.T) {
+	a := &AppContext{}
+	consent := helpers.Consent{AppID: APPID, OwnerID: "DT1", ConsumerID: "DT2", At: time.Now().UTC().Format(time.RFC3339)}
+	if _, err := a.isConsent(nil, "", consent); helpers.ErrorCode(err) != helpers.ERROR_VALIDATION {
+		t.Error("isconsent without data type accepted: ", err)
+	}
+	if _, err := a.wasConsent(nil, "", consent); helpers.ErrorCode(err) != helpers.ERROR_VALIDATION {
+		t.Error("wasconsent without data type accepted: ", err)
+	}
+	if err := check_args(&consent); helpers.ErrorCode(err) != helpers.ERROR_VALIDATION {
+		t.Error("consent without data type accepted: ", err)
+	}
+}
+
+func TestBreakGlassFromAPIWithoutEmergencyAttribute(t *testing.T) {
+	consent := helpers.Consent{Action: "breakglass", AppID: APPID, OwnerID: "BG1", ConsumerID: "BG2", DataType: "BP", Reason: "emergency", Duration: "1h"}
+	data, _ := json.Marshal(consent)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		t.Error(err)
+	}
+	status, _, err := executeRequest(request)
+	if err != nil {
+		t.Error(err)
+	}
+	if status == http.StatusOK {
+		t.Error("break-glass access opened without emergency attribute")
+	}
+	_, err = getPendingBreakGlass()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 
 func createConsent(consent helpers.Consent) (string, error) {
 	var responseConsent helpers.Consent
@@ -230,3 +372,78 @@ func getListOfConsents(ownerID, consumerID string) ([]helpers.Consent, error) {
 	return consents, nil
 }
 
+func wasConsent(consent helpers.Consent) (helpers.PastDecision, error) {
+	var decision helpers.PastDecision
+	consent.Action = "wasconsent"
+	consent.AppID = APPID
+	data, _ := json.Marshal(consent)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return decision, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return decision, err
+	}
+	if status != http.StatusOK {
+		return decision, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &decision)
+	return decision, err
+}
+
+func logAccess(consent helpers.Consent) error {
+	consent.Action = "logaccess"
+	consent.AppID = APPID
+	data, _ := json.Marshal(consent)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return err
+	}
+	status, _, err := executeRequest(request)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return errors.New("bad status")
+	}
+	return nil
+}
+
+func getAccesses4Owner(ownerID string) ([]helpers.AccessEvent, error) {
+	events := []helpers.AccessEvent{}
+	consent := helpers.Consent{Action: "accesses4owner", AppID: APPID, OwnerID: ownerID}
+	data, _ := json.Marshal(consent)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return events, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return events, err
+	}
+	if status != http.StatusOK {
+		return events, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &events)
+	return events, err
+}
+
+func getPendingBreakGlass() ([]helpers.BreakGlassEvent, error) {
+	events := []helpers.BreakGlassEvent{}
+	consent := helpers.Consent{Action: "pendingbreakglass", AppID: APPID}
+	data, _ := json.Marshal(consent)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return events, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return events, err
+	}
+	if status != http.StatusOK {
+		return events, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &events)
+	return events, err
+}
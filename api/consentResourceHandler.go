@@ -0,0 +1,193 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"github.com/gorilla/mux"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+// v3 of the consent API: one resource per URI, the HTTP method gives the operation
+// (the action-dispatch POST of v2 stays available)
+
+// prefix of the context attributes given as query parameters to the authorization resource (context.location=home)
+const CONTEXTPARAM = "context."
+
+//HTTP Get - /ocms/v3/api/apps/{appid}/consents?externalref=xxx
+func (a *AppContext) listConsentResources(w http.ResponseWriter, r *http.Request) {
+	appID := mux.Vars(r)["appid"]
+	log.Debug("listConsentResources(appid=" + appID + ") : calling method -")
+	a.processConsentResource(w, r, http.StatusOK, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		externalRef := r.URL.Query().Get("externalref")
+		if externalRef != "" {
+			return a.getConsentByRef(consentHelper, a.ChainCodeID, appID, externalRef)
+		}
+		return a.listConsents(consentHelper, a.ChainCodeID, appID, queryFilter(r))
+	})
+}
+
+//HTTP Post - /ocms/v3/api/apps/{appid}/consents
+func (a *AppContext) createConsentResource(w http.ResponseWriter, r *http.Request) {
+	appID := mux.Vars(r)["appid"]
+	log.Debug("createConsentResource(appid=" + appID + ") : calling method -")
+	var consent helpers.Consent
+	err := json.NewDecoder(r.Body).Decode(&consent)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if consent.AppID != "" && consent.AppID != appID {
+		SendError(w, validationError("appid of the body does not match the URI"))
+		return
+	}
+	consent.AppID = appID
+	consent.Action = ""
+	a.processConsentResource(w, r, http.StatusCreated, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		bytes, err := a.createConsent(consentHelper, a.ChainCodeID, consent)
+		if err != nil {
+			return nil, err
+		}
+		var created helpers.Consent
+		json.Unmarshal(bytes, &created)
+		w.Header().Set("Location", consentResourceURI(appID, created.ConsentID))
+		return bytes, nil
+	})
+}
+
+//HTTP Get - /ocms/v3/api/apps/{appid}/consents/{consentid}
+func (a *AppContext) getConsentResource(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	consentID := vars["consentid"]
+	log.Debug("getConsentResource(appid=" + appID + ", consentid=" + consentID + ") : calling method -")
+	a.processConsentResource(w, r, http.StatusOK, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		return a.getConsent(consentHelper, a.ChainCodeID, appID, consentID)
+	})
+}
+
+//HTTP Patch - /ocms/v3/api/apps/{appid}/consents/{consentid}
+// only the state can be changed, from active to unactive (a consent is never reactivated, create a new one)
+func (a *AppContext) patchConsentResource(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	consentID := vars["consentid"]
+	log.Debug("patchConsentResource(appid=" + appID + ", consentid=" + consentID + ") : calling method -")
+	var patch map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&patch)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	for field, value := range patch {
+		if field != "state" || value != "unactive" {
+			SendError(w, validationError("only the state can be patched, to unactive"))
+			return
+		}
+	}
+	if len(patch) == 0 {
+		SendError(w, validationError("state is mandatory!"))
+		return
+	}
+	a.processConsentResource(w, r, http.StatusOK, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		return a.unactivateConsent(consentHelper, a.ChainCodeID, appID, consentID)
+	})
+}
+
+//HTTP Delete - /ocms/v3/api/apps/{appid}/consents/{consentid}
+// the consent is revoked, the ledger keeps its history
+func (a *AppContext) deleteConsentResource(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	consentID := vars["consentid"]
+	log.Debug("deleteConsentResource(appid=" + appID + ", consentid=" + consentID + ") : calling method -")
+	a.processConsentResource(w, r, http.StatusOK, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		return a.unactivateConsent(consentHelper, a.ChainCodeID, appID, consentID)
+	})
+}
+
+//HTTP Get - /ocms/v3/api/apps/{appid}/owners/{ownerid}/consents
+func (a *AppContext) listOwnerConsentResources(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	ownerID := vars["ownerid"]
+	log.Debug("listOwnerConsentResources(appid=" + appID + ", ownerid=" + ownerID + ") : calling method -")
+	a.processConsentResource(w, r, http.StatusOK, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		return a.getConsents4Owner(consentHelper, a.ChainCodeID, appID, ownerID, queryFilter(r))
+	})
+}
+
+//HTTP Get - /ocms/v3/api/apps/{appid}/consumers/{consumerid}/consents
+func (a *AppContext) listConsumerConsentResources(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	consumerID := vars["consumerid"]
+	log.Debug("listConsumerConsentResources(appid=" + appID + ", consumerid=" + consumerID + ") : calling method -")
+	a.processConsentResource(w, r, http.StatusOK, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		return a.getConsents4Consumer(consentHelper, a.ChainCodeID, appID, consumerID, queryFilter(r))
+	})
+}
+
+//HTTP Get - /ocms/v3/api/apps/{appid}/authorizations?ownerid=xxx&consumerid=xxx&datatype=xxx&dataaccess=xxx[&at=xxx][&context.xxx=xxx]
+// without at: decision now (isconsent), with at (RFC3339): decision at this instant (wasconsent)
+func (a *AppContext) getAuthorizationResource(w http.ResponseWriter, r *http.Request) {
+	appID := mux.Vars(r)["appid"]
+	log.Debug("getAuthorizationResource(appid=" + appID + ") : calling method -")
+	query := r.URL.Query()
+	consent := helpers.Consent{AppID: appID, OwnerID: query.Get("ownerid"), ConsumerID: query.Get("consumerid"), DataType: query.Get("datatype"), DataAccess: query.Get("dataaccess"), At: query.Get("at")}
+	for param := range query {
+		if strings.HasPrefix(param, CONTEXTPARAM) {
+			if consent.Context == nil {
+				consent.Context = map[string]string{}
+			}
+			consent.Context[strings.TrimPrefix(param, CONTEXTPARAM)] = query.Get(param)
+		}
+	}
+	if consent.OwnerID == "" || consent.ConsumerID == "" {
+		SendError(w, validationError("ownerid and consumerid are mandatory!"))
+		return
+	}
+	a.processConsentResource(w, r, http.StatusOK, func(consentHelper *helpers.ConsentHelper) ([]byte, error) {
+		if consent.At != "" {
+			return a.wasConsent(consentHelper, a.ChainCodeID, consent)
+		}
+		return a.isConsent(consentHelper, a.ChainCodeID, consent)
+	})
+}
+
+// filter of the lists given in the query: datatype, dataaccess, validAt, createdAfter, createdBefore, state, sort
+// and fields (comma separated)
+func queryFilter(r *http.Request) helpers.ConsentFilter {
+	query := r.URL.Query()
+	filter := helpers.ConsentFilter{DataType: query.Get("datatype"), DataAccess: query.Get("dataaccess"), ValidAt: query.Get("validAt"),
+		CreatedAfter: query.Get("createdAfter"), CreatedBefore: query.Get("createdBefore"), State: query.Get("state"), Sort: query.Get("sort")}
+	for _, field := range strings.Split(query.Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			filter.Fields = append(filter.Fields, field)
+		}
+	}
+	return filter
+}
+
+// init the helper with the credentials of the request, process the resource and send the response
+func (a *AppContext) processConsentResource(w http.ResponseWriter, r *http.Request, status int, process func(*helpers.ConsentHelper) ([]byte, error)) {
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err := InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	bytes, err := process(consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
+
+func consentResourceURI(appID, consentID string) string {
+	return fmt.Sprintf("%s/%s/consents/%s", APPSAPI, appID, consentID)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestConsentResourceFromAPINominal(t *testing.T) {
+	status, body_bytes, err := executeResource("POST", APPSAPI+"/"+APPID+"/consents", helpers.Consent{DataType: "All", OwnerID: "V3O1", ConsumerID: "V3C1"})
+	if err != nil || status != http.StatusCreated {
+		t.Fatal("bad status: ", status, err)
+	}
+	created := helpers.Consent{}
+	json.Unmarshal(body_bytes, &created)
+	if created.ConsentID == "" || created.AppID != APPID {
+		t.Fatal("bad created consent: ", string(body_bytes))
+	}
+	time.Sleep(TransactionTimeout)
+	uri := consentResourceURI(APPID, created.ConsentID)
+	status, body_bytes, err = executeResource("GET", uri, nil)
+	consent := helpers.Consent{}
+	json.Unmarshal(body_bytes, &consent)
+	if err != nil || status != http.StatusOK || consent.ConsumerID != "V3C1" {
+		t.Fatal("bad consent: ", status, string(body_bytes), err)
+	}
+	status, _, err = executeResource("PATCH", uri, map[string]string{"ownerid": "V3O2"})
+	if err != nil || status == http.StatusOK {
+		t.Error("patch of an attribute other than the state must be rejected")
+	}
+	status, body_bytes, err = executeResource("DELETE", uri, nil)
+	if err != nil || status != http.StatusOK {
+		t.Error("bad status: ", status, string(body_bytes), err)
+	}
+}
+
+func TestConsentResourcesByOwnerAndConsumerFromAPI(t *testing.T) {
+	executeResource("POST", APPSAPI+"/"+APPID+"/consents", helpers.Consent{DataType: "All", OwnerID: "V3O3", ConsumerID: "V3C3"})
+	time.Sleep(TransactionTimeout)
+	for _, uri := range []string{APPSAPI+"/"+APPID+"/owners/V3O3/consents", APPSAPI+"/"+APPID+"/consumers/V3C3/consents"} {
+		status, body_bytes, err := executeResource("GET", uri, nil)
+		consents := []helpers.Consent{}
+		json.Unmarshal(body_bytes, &consents)
+		if err != nil || status != http.StatusOK || len(consents) == 0 {
+			t.Error("bad consents for ", uri, ": ", string(body_bytes), err)
+		}
+	}
+	uri := fmt.Sprintf("%s/%s/authorizations?ownerid=V3O3&consumerid=V3C3&datatype=All&dataaccess=A", APPSAPI, APPID)
+	status, body_bytes, err := executeResource("GET", uri, nil)
+	decision := IsConsent{}
+	json.Unmarshal(body_bytes, &decision)
+	if err != nil || status != http.StatusOK || decision.Consent != "True" {
+		t.Error("bad authorization: ", string(body_bytes), err)
+	}
+}
+
+func TestConsentResourcesFilterFromAPI(t *testing.T) {
+	executeResource("POST", APPSAPI+"/"+APPID+"/consents", helpers.Consent{DataType: "All", OwnerID: "V3O4", ConsumerID: "V3C4"})
+	time.Sleep(TransactionTimeout)
+	status, body_bytes, err := executeResource("GET", APPSAPI+"/"+APPID+"/owners/V3O4/consents?state=all&sort=-createdat&fields=datatype,createdat", nil)
+	records := []map[string]interface{}{}
+	json.Unmarshal(body_bytes, &records)
+	if err != nil || status != http.StatusOK || len(records) == 0 || len(records[0]) != 3 || records[0]["createdat"] == nil {
+		t.Error("bad selected fields: ", string(body_bytes), err)
+	}
+	status, body_bytes, err = executeResource("GET", APPSAPI+"/"+APPID+"/owners/V3O4/consents?sort=label", nil)
+	if err != nil || status != http.StatusUnprocessableEntity {
+		t.Error("bad sort accepted: ", status, string(body_bytes), err)
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	request, _ := http.NewRequest("GET", APPSAPI+"/app1/consents?datatype=BP&validAt=2017-03-09&createdAfter=2017-01-01&state=all&sort=-dtbegin&fields=datatype,+dtend,", nil)
+	filter := queryFilter(request)
+	if filter.DataType != "BP" || filter.ValidAt != "2017-03-09" || filter.CreatedAfter != "2017-01-01" || filter.State != "all" ||
+		filter.Sort != "-dtbegin" || len(filter.Fields) != 2 || filter.Fields[1] != "dtend" {
+		t.Error("bad filter: ", filter)
+	}
+	selected, err := helpers.SelectFields([]helpers.Consent{{ConsentID: "c1", DataType: "BP", OwnerID: "o1"}}, filter.Fields)
+	if err != nil || len(selected) != 1 || len(selected[0]) != 3 || selected[0]["datatype"] != "BP" || selected[0]["consentid"] != "c1" {
+		t.Error("bad selected fields: ", selected, err)
+	}
+}
+
+func executeResource(method, uri string, body interface{}) (int, []byte, error) {
+	data := ""
+	if body != nil {
+		bytes, _ := json.Marshal(body)
+		data = string(bytes)
+	}
+	request, err := buildRequestWithLoginPassword(method, httpServerTest.URL+uri, data, ADMINNAME, ADMINPWD)
+	if err != nil {
+		return 0, nil, err
+	}
+	return executeRequest(request)
+}
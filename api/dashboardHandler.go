@@ -5,18 +5,21 @@ import (
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/pascallimeux/ocmsV2/helpers"
-	"strings"
 )
 
 func SendError(w http.ResponseWriter, err error) {
 	log.Debug("sendError() : calling method -")
-	libelle := err.Error()
-	libelle = strings.Replace(libelle, "\"", "'", -1)
-	log.Error("sendError: ", libelle)
-	message := "{\"content\":\"" + libelle + "\"} "
+	requestID := w.Header().Get(REQUESTIDHEADER)
+	if requestID == "" {
+		requestID = newRequestID()
+		w.Header().Set(REQUESTIDHEADER, requestID)
+	}
+	body, status := buildErrorBody(err, requestID)
+	log.Error("sendError: ", status, " ", body.Code, " ", body.Message, " requestid:", requestID)
+	content, _ := json.Marshal(body)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
-	w.Write([]byte(message))
+	w.WriteHeader(status)
+	w.Write(content)
 }
 
 //HTTP Get - /ocms/v2/dashboard/chain
@@ -244,4 +247,79 @@ func (a *AppContext) queryByCC(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(content)
-}
\ No newline at end of file
+}
+
+//HTTP Get - /ocms/v2/dashboard/stats/{appid}
+func (a *AppContext) consentStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	message := fmt.Sprintf("consentStats(appid=%s) : calling method -", appID)
+	log.Debug(message)
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err := InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	stats, err := consentHelper.GetStats(a.ChainCodeID, appID)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	content, err := json.Marshal(stats)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+//HTTP Post - /ocms/v2/admin/stats/{appid}/compact
+// the counters are written by transaction and summed on read, the compaction keeps the reads bounded
+func (a *AppContext) compactStats(w http.ResponseWriter, r *http.Request) {
+	appID := mux.Vars(r)["appid"]
+	log.Debug("compactStats(appid=" + appID + ") : calling method -")
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err := InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	stats, err := consentHelper.CompactStats(a.ChainCodeID, appID)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	content, err := json.Marshal(stats)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+//HTTP Get - /ocms/v2/dashboard/sessions
+func (a *AppContext) sessionMetrics(w http.ResponseWriter, r *http.Request) {
+	log.Debug("sessionMetrics() : calling method -")
+	_, err := GetUserCredentials(r)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if a.Sessions == nil {
+		SendError(w, helpers.NewError(helpers.ERROR_INTERNAL, "session manager is not configured"))
+		return
+	}
+	content, err := json.Marshal(a.Sessions.Metrics())
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
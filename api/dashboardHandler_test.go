@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"encoding/json"
 	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pascallimeux/ocmsV2/helpers"
+	"time"
 )
 
 func TestBlockchainInfo(t *testing.T) {
@@ -29,3 +31,57 @@ func TestBlockchainInfo(t *testing.T) {
 		t.Error(errors.New("bad response"))
 	}
 }
+
+func TestConsentStats(t *testing.T) {
+	_, err := createConsent(helpers.Consent{DataType: "All", OwnerID: "STAT1", ConsumerID: "STAT2"})
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	var response helpers.ConsentStats
+	request, err := buildRequestWithLoginPassword("GET", httpServerTest.URL+CONSENTSTATS+"/"+APPID, "", ADMINNAME, ADMINPWD)
+	if err != nil {
+		t.Error(err)
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		t.Error(err)
+	}
+	err = json.Unmarshal(body_bytes, &response)
+	if err != nil {
+		t.Error(err)
+	}
+	if status != http.StatusOK {
+		t.Error(errors.New("bad status"))
+	}
+	if response.Active < 1 || response.ByConsumer["STAT2"].Active < 1 {
+		t.Error(errors.New("bad response"))
+	}
+}
+
+func TestSessionMetrics(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		request, _ := buildRequestWithLoginPassword("GET", httpServerTest.URL+BCINFO, "", ADMINNAME, ADMINPWD)
+		executeRequest(request)
+	}
+	var metrics helpers.SessionMetrics
+	request, err := buildRequestWithLoginPassword("GET", httpServerTest.URL+SESSIONS, "", ADMINNAME, ADMINPWD)
+	if err != nil {
+		t.Error(err)
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		t.Error(err)
+	}
+	err = json.Unmarshal(body_bytes, &metrics)
+	if err != nil {
+		t.Error(err)
+	}
+	if status != http.StatusOK {
+		t.Error(errors.New("bad status"))
+	}
+	// the session of the admin is reused, nothing is borrowed once the requests are served
+	if metrics.Sessions < 1 || metrics.Hits < 1 || metrics.InUse != 0 || !metrics.EventHubConnected {
+		t.Error(errors.New("bad response"), metrics)
+	}
+}
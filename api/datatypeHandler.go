@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"fmt"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+//HTTP Post - /ocms/v2/api/datatype
+func (a *AppContext) processDataType(w http.ResponseWriter, r *http.Request) {
+	log.Debug("processDataType() : calling method -")
+
+	var bytes []byte
+	var dataType helpers.DataType
+	err := json.NewDecoder(r.Body).Decode(&dataType)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err = InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	switch action := dataType.Action; action {
+	case "add":
+		bytes, err = a.addDataType(consentHelper, a.ChainCodeID, dataType)
+	case "list":
+		bytes, err = a.listDataTypes(consentHelper, a.ChainCodeID)
+	case "deprecate":
+		bytes, err = a.deprecateDataType(consentHelper, a.ChainCodeID, dataType)
+	default:
+		log.Error("bad action request")
+		SendError(w, validationError("bad action request"))
+		return
+	}
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bytes)
+}
+
+func (a *AppContext) addDataType(consentHelper *helpers.ConsentHelper, chainCodeID string, dataType helpers.DataType) ([]byte, error) {
+	message := fmt.Sprintf("addDataType(code=%s, label=%s, parent=%s) : calling method -", dataType.Code, dataType.Label, dataType.Parent)
+	log.Info(message)
+	if dataType.Code == "" {
+		return nil, validationError("code is mandatory!")
+	}
+	_, err := consentHelper.AddDataType(chainCodeID, dataType.Code, dataType.Label, dataType.Parent)
+	if err != nil {
+		return nil, err
+	}
+	dataType.Action = ""
+	return json.Marshal(dataType)
+}
+
+func (a *AppContext) listDataTypes(consentHelper *helpers.ConsentHelper, chainCodeID string) ([]byte, error) {
+	log.Info("listDataTypes() : calling method -")
+	dataTypes, err := consentHelper.ListDataTypes(chainCodeID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(dataTypes)
+}
+
+func (a *AppContext) deprecateDataType(consentHelper *helpers.ConsentHelper, chainCodeID string, dataType helpers.DataType) ([]byte, error) {
+	message := fmt.Sprintf("deprecateDataType(code=%s) : calling method -", dataType.Code)
+	log.Info(message)
+	if dataType.Code == "" {
+		return nil, validationError("code is mandatory!")
+	}
+	_, err := consentHelper.DeprecateDataType(chainCodeID, dataType.Code)
+	if err != nil {
+		return nil, err
+	}
+	dataType.Action = ""
+	dataType.Deprecated = true
+	return json.Marshal(dataType)
+}
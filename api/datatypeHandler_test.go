@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestListDataTypesFromAPINominal(t *testing.T) {
+	dataTypes, err := listDataTypes()
+	if err != nil {
+		t.Error(err)
+	}
+	found := false
+	for _, dataType := range dataTypes {
+		if dataType.Code == "HR" && dataType.Parent == "CAR" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("default data type HR not found in the taxonomy")
+	}
+}
+
+func listDataTypes() ([]helpers.DataType, error) {
+	dataTypes := []helpers.DataType{}
+	data, _ := json.Marshal(helpers.DataType{Action: "list"})
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+DATATYPEAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return dataTypes, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return dataTypes, err
+	}
+	if status != http.StatusOK {
+		return dataTypes, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &dataTypes)
+	return dataTypes, err
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"encoding/hex"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"github.com/gorilla/mux"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+type DocumentReceipt struct {
+	AppID           string `json:"appid"`
+	ConsentID       string `json:"consentid"`
+	DocumentHash    string `json:"documenthash"`
+	DocumentURI     string `json:"documenturi"`
+	DocumentVersion string `json:"documentversion"`
+}
+
+type DocumentVerification struct {
+	ConsentID       string `json:"consentid"`
+	DocumentHash    string `json:"documenthash"`
+	PresentedHash   string `json:"presentedhash"`
+	DocumentVersion string `json:"documentversion"`
+	Match           bool   `json:"match"`
+}
+
+var documentExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+//HTTP Post - /ocms/v2/api/document/{appid}/{consentid}?version=xxx&uri=xxx
+func (a *AppContext) uploadDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	consentID := vars["consentid"]
+	message := fmt.Sprintf("uploadDocument(appid=%s, consentid=%s) : calling method -", appID, consentID)
+	log.Debug(message)
+	if a.DocumentStorePath == "" {
+		SendError(w, helpers.NewError(helpers.ERROR_INTERNAL, "document store is not configured"))
+		return
+	}
+	extension, err := documentExtension(r)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	// the identity and the consent are checked before the document is read and staged
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err = InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	_, err = consentHelper.GetConsentRecord(a.ChainCodeID, appID, consentID)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	document, err := a.readDocument(w, r)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	documentHash := hashDocument(document)
+	path := filepath.Join(a.DocumentStorePath, documentHash+extension)
+	staged, err := a.stageDocument(document)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	// the staged document is removed when it is not kept
+	defer os.Remove(staged)
+	receipt := DocumentReceipt{AppID: appID, ConsentID: consentID, DocumentHash: documentHash}
+	receipt.DocumentVersion = r.URL.Query().Get("version")
+	if receipt.DocumentVersion == "" {
+		receipt.DocumentVersion = "1"
+	}
+	receipt.DocumentURI = r.URL.Query().Get("uri")
+	if receipt.DocumentURI == "" {
+		receipt.DocumentURI = "file://" + path
+	}
+	// the document is kept only when its anchor is committed
+	if consentHelper.Commit == nil {
+		consentHelper.SetCommit(&helpers.Commit{})
+	}
+	consentHelper.Commit.Mode = helpers.COMMIT_WAIT
+	_, err = consentHelper.AnchorDocument(a.ChainCodeID, appID, consentID, receipt.DocumentHash, receipt.DocumentURI, receipt.DocumentVersion)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	err = keepDocument(staged, path)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	content, _ := json.Marshal(receipt)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+//HTTP Post - /ocms/v2/api/document/{appid}/{consentid}/verify
+func (a *AppContext) verifyDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appID := vars["appid"]
+	consentID := vars["consentid"]
+	message := fmt.Sprintf("verifyDocument(appid=%s, consentid=%s) : calling method -", appID, consentID)
+	log.Debug(message)
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err := InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	// the document of a revoked consent can still be verified
+	consent, err := consentHelper.GetConsentRecord(a.ChainCodeID, appID, consentID)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	document, err := a.readDocument(w, r)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if consent.DocumentHash == "" {
+		SendError(w, helpers.NewError(helpers.ERROR_NOTFOUND, "no document anchored in the consent"))
+		return
+	}
+	verification := DocumentVerification{ConsentID: consentID, DocumentHash: consent.DocumentHash, DocumentVersion: consent.DocumentVersion}
+	verification.PresentedHash = hashDocument(document)
+	verification.Match = verification.PresentedHash == consent.DocumentHash
+	content, _ := json.Marshal(verification)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// readDocument reads the body, the rest of a body larger than DocumentMaxSize is not read (the connection is closed)
+func (a *AppContext) readDocument(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	log.Debug("readDocument() : calling method -")
+	reader := io.Reader(r.Body)
+	if a.DocumentMaxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, a.DocumentMaxSize+1)
+		reader = io.LimitReader(r.Body, a.DocumentMaxSize+1)
+	}
+	document, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(document) == 0 {
+		return nil, validationError("document is mandatory!")
+	}
+	if a.DocumentMaxSize > 0 && int64(len(document)) > a.DocumentMaxSize {
+		return nil, validationError("document is too large!")
+	}
+	return document, nil
+}
+
+// stageDocument writes the document in a temporary file of the store
+func (a *AppContext) stageDocument(document []byte) (string, error) {
+	log.Debug("stageDocument() : calling method -")
+	err := os.MkdirAll(a.DocumentStorePath, 0700)
+	if err != nil {
+		return "", err
+	}
+	file, err := ioutil.TempFile(a.DocumentStorePath, ".upload-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	_, err = file.Write(document)
+	return file.Name(), err
+}
+
+// keepDocument moves a staged document to its path in the store
+func keepDocument(staged, path string) error {
+	log.Debug("keepDocument(" + path + ") : calling method -")
+	if _, err := os.Stat(path); err == nil {
+		// the store is content addressed: the same document is already stored
+		return nil
+	}
+	return os.Rename(staged, path)
+}
+
+func documentExtension(r *http.Request) (string, error) {
+	contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	extension, ok := documentExtensions[contentType]
+	if !ok {
+		return "", validationError("document must be a PDF or a text!")
+	}
+	return extension, nil
+}
+
+func hashDocument(document []byte) string {
+	hash := sha256.Sum256(document)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestUploadAndVerifyDocumentFromAPINominal(t *testing.T) {
+	consentID, err := createConsent(helpers.Consent{DataType: "All", OwnerID: "DOC1", ConsumerID: "DOC2"})
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	receipt := DocumentReceipt{}
+	err = postDocument(DOCUMENTAPI+"/"+APPID+"/"+consentID+"?version=v1", "I agree to share my data", &receipt)
+	if err != nil {
+		t.Error(err)
+	}
+	if receipt.DocumentHash != hashDocument([]byte("I agree to share my data")) {
+		t.Error("bad document hash: ", receipt.DocumentHash)
+	}
+	time.Sleep(TransactionTimeout)
+	verification := DocumentVerification{}
+	err = postDocument(DOCUMENTAPI+"/"+APPID+"/"+consentID+"/verify", "I agree to share my data", &verification)
+	if err != nil {
+		t.Error(err)
+	}
+	if !verification.Match || verification.DocumentVersion != "v1" {
+		t.Error("the anchored document does not match")
+	}
+	err = postDocument(DOCUMENTAPI+"/"+APPID+"/"+consentID+"/verify", "I do not agree", &verification)
+	if err != nil {
+		t.Error(err)
+	}
+	if verification.Match {
+		t.Error("a tampered document matches")
+	}
+}
+
+func TestUploadDocumentFromAPIWithBadConsentID(t *testing.T) {
+	err := postDocument(DOCUMENTAPI+"/"+APPID+"/badconsentid?version=v1", "orphan document", &DocumentReceipt{})
+	if err == nil {
+		t.Error("error expected for an unknown consent")
+	}
+	path := filepath.Join(configuration.DocumentStorePath, hashDocument([]byte("orphan document"))+".txt")
+	if _, err := os.Stat(path); err == nil {
+		t.Error("the document of a failed upload is stored")
+	}
+}
+
+func TestStageAndKeepDocument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "documents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := &AppContext{DocumentStorePath: dir}
+	staged, err := a.stageDocument([]byte("I agree to share my data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "form.txt")
+	if err = keepDocument(staged, path); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil || string(content) != "I agree to share my data" {
+		t.Error("the kept document is not stored at its path")
+	}
+	if _, err := os.Stat(staged); err == nil {
+		t.Error("the staged document is left in the store")
+	}
+}
+
+func TestReadDocumentTooLarge(t *testing.T) {
+	a := &AppContext{DocumentMaxSize: 8}
+	request := httptest.NewRequest("POST", DOCUMENTAPI+"/"+APPID+"/1", strings.NewReader("a document larger than the maximum size"))
+	if _, err := a.readDocument(httptest.NewRecorder(), request); helpers.ErrorCode(err) != helpers.ERROR_VALIDATION {
+		t.Error("document larger than the maximum size accepted: ", err)
+	}
+	request = httptest.NewRequest("POST", DOCUMENTAPI+"/"+APPID+"/1", strings.NewReader("12345678"))
+	if document, err := a.readDocument(httptest.NewRecorder(), request); err != nil || string(document) != "12345678" {
+		t.Error("document of the maximum size refused: ", err)
+	}
+}
+
+func postDocument(uri, document string, response interface{}) error {
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+uri, document, ADMINNAME, ADMINPWD)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain")
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return errors.New("bad status")
+	}
+	return json.Unmarshal(body_bytes, response)
+}
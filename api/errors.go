@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+// header of the request ID, given by the client or generated, returned in the response and in the errors
+const REQUESTIDHEADER = "X-Request-ID"
+
+// code of the errors not typed by the helpers (malformed request...)
+const ERROR_BADREQUEST = "bad_request"
+
+type ErrorBody struct {
+	Code		string `json:"code"`
+	Message		string `json:"message"`
+	RequestID	string `json:"requestid"`
+}
+
+// status of the typed errors of the helpers
+var errorStatus = map[string]int{
+	helpers.ERROR_NOTFOUND:     http.StatusNotFound,
+	helpers.ERROR_UNAUTHORIZED: http.StatusUnauthorized,
+	helpers.ERROR_FORBIDDEN:    http.StatusForbidden,
+	helpers.ERROR_VALIDATION:   http.StatusUnprocessableEntity,
+	helpers.ERROR_CONFLICT:     http.StatusConflict,
+	helpers.ERROR_UNAVAILABLE:  http.StatusServiceUnavailable,
+	helpers.ERROR_TIMEOUT:      http.StatusGatewayTimeout,
+	helpers.ERROR_INTERNAL:     http.StatusInternalServerError,
+}
+
+func validationError(message string) error {
+	return helpers.NewError(helpers.ERROR_VALIDATION, "%s", message)
+}
+
+// error body and status of an error
+func buildErrorBody(err error, requestID string) (ErrorBody, int) {
+	code := helpers.ErrorCode(err)
+	status, ok := errorStatus[code]
+	if !ok {
+		code = ERROR_BADREQUEST
+		status = http.StatusBadRequest
+	}
+	return ErrorBody{Code: code, Message: err.Error(), RequestID: requestID}, status
+}
+
+// RequestID gives an ID to each request, the ID of the client is kept
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(REQUESTIDHEADER)
+		if requestID == "" {
+			requestID = newRequestID()
+			r.Header.Set(REQUESTIDHEADER, requestID)
+		}
+		w.Header().Set(REQUESTIDHEADER, requestID)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
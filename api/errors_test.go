@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestSendErrorStatus(t *testing.T) {
+	cases := map[error]int{
+		helpers.NewError(helpers.ERROR_NOTFOUND, "Consent does not exist:1234"): http.StatusNotFound,
+		helpers.NewError(helpers.ERROR_UNAUTHORIZED, "bad credentials"):         http.StatusUnauthorized,
+		validationError("appid is mandatory!"):                                  http.StatusUnprocessableEntity,
+		helpers.NewError(helpers.ERROR_CONFLICT, "Group already exists:g1"):     http.StatusConflict,
+		helpers.NewError(helpers.ERROR_UNAVAILABLE, "ledger unavailable"):       http.StatusServiceUnavailable,
+		helpers.NewError(helpers.ERROR_TIMEOUT, "timeout"):                      http.StatusGatewayTimeout,
+		errors.New("unexpected EOF"):                                            http.StatusBadRequest,
+	}
+	for err, status := range cases {
+		recorder := httptest.NewRecorder()
+		SendError(recorder, err)
+		body := ErrorBody{}
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		if recorder.Code != status || body.Message != err.Error() || body.RequestID == "" {
+			t.Error("bad error response for ", err, ": ", recorder.Code, " ", recorder.Body.String())
+		}
+	}
+}
+
+func TestErrorFromAPIWithRequestID(t *testing.T) {
+	request, _ := buildRequestWithLoginPassword("GET", httpServerTest.URL+APPSAPI+"/"+APPID+"/consents/unknown", "", ADMINNAME, ADMINPWD)
+	request.Header.Set(REQUESTIDHEADER, "req-1234")
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := ErrorBody{}
+	json.Unmarshal(body_bytes, &body)
+	if status != http.StatusNotFound || body.Code != helpers.ERROR_NOTFOUND || body.RequestID != "req-1234" {
+		t.Error("bad error response: ", status, " ", string(body_bytes))
+	}
+}
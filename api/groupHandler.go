@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"fmt"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+//HTTP Post - /ocms/v2/api/group
+func (a *AppContext) processGroup(w http.ResponseWriter, r *http.Request) {
+	log.Debug("processGroup() : calling method -")
+
+	var bytes []byte
+	var group helpers.ConsumerGroup
+	err := json.NewDecoder(r.Body).Decode(&group)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err = InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if group.GroupID == "" {
+		SendError(w, validationError("groupid is mandatory!"))
+		return
+	}
+	switch action := group.Action; action {
+	case "create":
+		bytes, err = a.createGroup(consentHelper, a.ChainCodeID, group)
+	case "addmember":
+		bytes, err = a.addGroupMember(consentHelper, a.ChainCodeID, group)
+	case "removemember":
+		bytes, err = a.removeGroupMember(consentHelper, a.ChainCodeID, group)
+	case "members":
+		bytes, err = a.getGroupMembers(consentHelper, a.ChainCodeID, group.AppID, group.GroupID)
+	default:
+		log.Error("bad action request")
+		SendError(w, validationError("bad action request"))
+		return
+	}
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bytes)
+}
+
+func (a *AppContext) createGroup(consentHelper *helpers.ConsentHelper, chainCodeID string, group helpers.ConsumerGroup) ([]byte, error) {
+	message := fmt.Sprintf("createGroup(applicationID=%s, groupID=%s, label=%s) : calling method -", group.AppID, group.GroupID, group.Label)
+	log.Info(message)
+	_, err := consentHelper.CreateGroup(chainCodeID, group.AppID, group.GroupID, group.Label)
+	if err != nil {
+		return nil, err
+	}
+	group.Action = ""
+	return json.Marshal(group)
+}
+
+func (a *AppContext) addGroupMember(consentHelper *helpers.ConsentHelper, chainCodeID string, group helpers.ConsumerGroup) ([]byte, error) {
+	message := fmt.Sprintf("addGroupMember(applicationID=%s, groupID=%s, consumerID=%s) : calling method -", group.AppID, group.GroupID, group.ConsumerID)
+	log.Info(message)
+	if group.ConsumerID == "" {
+		return nil, validationError("consumerid is mandatory!")
+	}
+	_, err := consentHelper.AddGroupMember(chainCodeID, group.AppID, group.GroupID, group.ConsumerID)
+	if err != nil {
+		return nil, err
+	}
+	group.Action = ""
+	return json.Marshal(group)
+}
+
+func (a *AppContext) removeGroupMember(consentHelper *helpers.ConsentHelper, chainCodeID string, group helpers.ConsumerGroup) ([]byte, error) {
+	message := fmt.Sprintf("removeGroupMember(applicationID=%s, groupID=%s, consumerID=%s) : calling method -", group.AppID, group.GroupID, group.ConsumerID)
+	log.Info(message)
+	if group.ConsumerID == "" {
+		return nil, validationError("consumerid is mandatory!")
+	}
+	_, err := consentHelper.RemoveGroupMember(chainCodeID, group.AppID, group.GroupID, group.ConsumerID)
+	if err != nil {
+		return nil, err
+	}
+	group.Action = ""
+	return json.Marshal(group)
+}
+
+func (a *AppContext) getGroupMembers(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, groupID string) ([]byte, error) {
+	message := fmt.Sprintf("getGroupMembers(applicationID=%s, groupID=%s) : calling method -", applicationID, groupID)
+	log.Info(message)
+	group, err := consentHelper.GetGroupMembers(chainCodeID, applicationID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(group)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestGroupConsentFromAPINominal(t *testing.T) {
+	groupID := "team" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	_, err := postGroup(helpers.ConsumerGroup{Action: "create", GroupID: groupID, Label: "care team"})
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	_, err = createConsent(helpers.Consent{DataType: "All", OwnerID: "GRP1", ConsumerID: helpers.GroupReference(groupID)})
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = postGroup(helpers.ConsumerGroup{Action: "addmember", GroupID: groupID, ConsumerID: "GRP2"})
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	body, err := postGroup(helpers.ConsumerGroup{Action: "members", GroupID: groupID})
+	if err != nil {
+		t.Error(err)
+	}
+	group := helpers.ConsumerGroup{}
+	json.Unmarshal(body, &group)
+	if len(group.Members) != 1 || group.Members[0] != "GRP2" {
+		t.Error("bad members: ", string(body))
+	}
+}
+
+func postGroup(group helpers.ConsumerGroup) ([]byte, error) {
+	group.AppID = APPID
+	data, _ := json.Marshal(group)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+GROUPAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return nil, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, errors.New("bad status")
+	}
+	return body_bytes, nil
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"github.com/pascallimeux/ocmsV2/helpers"
+	"github.com/pascallimeux/ocmsV2/idempotency"
+)
+
+// header of the idempotency key of a write, and of a replayed response
+const (
+	IDEMPOTENCYKEYHEADER = "Idempotency-Key"
+	REPLAYEDHEADER       = "Idempotent-Replayed"
+)
+
+const IDEMPOTENCYKEYMAXSIZE = 255
+
+// read actions of the action routes, a retried read is sent again
+var readActions = map[string][]string{
+	CONSENTAPI:  {"list", "get", "getbyref", "list4owner", "list4consumer", "isconsent", "wasconsent", "accesses4owner", "pendingbreakglass"},
+	DATATYPEAPI: {"list"},
+	TEMPLATEAPI: {"get", "list", "getgrant"},
+	GROUPAPI:    {"members"},
+}
+
+// Idempotency replays the stored response of a write retried with the same Idempotency-Key,
+// the keys are scoped by identity and the server errors are not stored (the request can be retried)
+// except a commit timeout, its transaction is sent and its status is given by its txID
+func Idempotency(store *idempotency.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IDEMPOTENCYKEYHEADER)
+		if store == nil || key == "" || !isWriteRoute(r.Method, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > IDEMPOTENCYKEYMAXSIZE {
+			SendError(w, validationError("Idempotency-Key is too long"))
+			return
+		}
+		userCredentials, err := GetUserCredentials(r)
+		if err != nil {
+			SendError(w, err)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			SendError(w, validationError("unreadable body"))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if isReadAction(r.URL.Path, body) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key = userCredentials.UserName + "\n" + key
+		requestHash := idempotency.RequestHash(r.Method, r.URL.RequestURI(), body)
+		record, err := store.Begin(key, requestHash)
+		if err != nil {
+			SendError(w, idempotencyError(err))
+			return
+		}
+		if record != nil {
+			log.Debug("Idempotency(user:" + userCredentials.UserName + ") : replay of " + r.URL.Path + " txid:" + record.TxID)
+			for name, values := range record.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(REPLAYEDHEADER, "true")
+			w.WriteHeader(record.Status)
+			w.Write(record.Body)
+			return
+		}
+		// the key is released unless the response is saved, a panic of the handler too
+		saved := false
+		defer func() {
+			if !saved {
+				store.Abort(key)
+			}
+		}()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		txID := w.Header().Get(TRANSACTIONIDHEADER)
+		timeout := recorder.status == http.StatusGatewayTimeout && txID != ""
+		if recorder.status >= http.StatusInternalServerError && !timeout {
+			return
+		}
+		header := http.Header{}
+		for name, values := range w.Header() {
+			if name != REQUESTIDHEADER {
+				header[name] = values
+			}
+		}
+		err = store.Save(key, idempotency.Record{RequestHash: requestHash,
+			Status: recorder.status, Header: header, Body: recorder.body.Bytes(), TxID: txID})
+		saved = true
+		if err != nil {
+			log.Errorf("Idempotency() : save of the response return error: %v", err)
+		}
+	})
+}
+
+// the writes are the POST, PATCH and DELETE of a route group, except the verifications
+func isWriteRoute(method, path string) bool {
+	if method != "POST" && method != "PATCH" && method != "DELETE" {
+		return false
+	}
+	if routeGroup(path) == "" || path == ATTESTATIONAPI || strings.HasSuffix(path, "/verify") {
+		return false
+	}
+	return true
+}
+
+func isReadAction(path string, body []byte) bool {
+	actions, ok := readActions[path]
+	if !ok {
+		return false
+	}
+	var request struct {
+		Action	string	`json:"action"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return false
+	}
+	for _, action := range actions {
+		if request.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+func idempotencyError(err error) error {
+	switch err {
+	case idempotency.ErrMismatch:
+		return helpers.NewError(helpers.ERROR_VALIDATION, "%s", err.Error())
+	case idempotency.ErrInProgress:
+		return helpers.NewError(helpers.ERROR_CONFLICT, "%s", err.Error())
+	}
+	return helpers.NewError(helpers.ERROR_INTERNAL, "%s", err.Error())
+}
+
+// responseRecorder keeps the status and the body written by the handler
+type responseRecorder struct {
+	http.ResponseWriter
+	status		int
+	body		bytes.Buffer
+	wroteHeader	bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.wroteHeader {
+		return
+	}
+	rr.wroteHeader = true
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(content []byte) (int, error) {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+	rr.body.Write(content)
+	return rr.ResponseWriter.Write(content)
+}
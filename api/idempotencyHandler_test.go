@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/idempotency"
+)
+
+func TestIdempotencyReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idempotency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := idempotency.NewStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := 0
+	handler := Idempotency(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) == "panic" {
+			panic("handler failure")
+		}
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if string(body) == "timeout" {
+			w.Header().Set(TRANSACTIONIDHEADER, "tx"+strconv.Itoa(sent))
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.Header().Set(TRANSACTIONIDHEADER, "tx"+strconv.Itoa(sent))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("consent" + strconv.Itoa(sent)))
+	}))
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		request.SetBasicAuth("user1", "pwd1")
+		if key != "" {
+			request.Header.Set(IDEMPOTENCYKEYHEADER, key)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	post := func(key, body string) *httptest.ResponseRecorder {
+		return send(APPSAPI+"/app1/consents", key, body)
+	}
+	first := post("key1", `{"ownerid":"o1"}`)
+	retry := post("key1", `{"ownerid":"o1"}`)
+	if sent != 1 || retry.Code != http.StatusCreated || retry.Body.String() != "consent1" || retry.Header().Get(TRANSACTIONIDHEADER) != "tx1" || retry.Header().Get(REPLAYEDHEADER) != "true" {
+		t.Error("response not replayed: ", sent, retry.Code, retry.Body.String(), retry.Header())
+	}
+	if first.Header().Get(REPLAYEDHEADER) != "" {
+		t.Error("first response replayed")
+	}
+	if response := post("key1", `{"ownerid":"o2"}`); response.Code != http.StatusUnprocessableEntity || sent != 1 {
+		t.Error("key reused with an other body: ", response.Code, sent)
+	}
+	post("", `{"ownerid":"o1"}`)
+	post("", `{"ownerid":"o1"}`)
+	if sent != 3 {
+		t.Error("request without key not sent: ", sent)
+	}
+
+	// a server error is not stored, the request can be retried
+	post("key2", "fail")
+	post("key2", "fail")
+	if sent != 5 {
+		t.Error("server error replayed: ", sent)
+	}
+
+	// a commit timeout is stored with its transaction, the retry does not send it again
+	post("key3", "timeout")
+	if response := post("key3", "timeout"); sent != 6 || response.Code != http.StatusGatewayTimeout || response.Header().Get(TRANSACTIONIDHEADER) != "tx6" {
+		t.Error("commit timeout not replayed: ", sent, response.Code, response.Header())
+	}
+
+	// the reads are sent again, the writes of an action route are replayed
+	send(CONSENTAPI, "key4", `{"action":"isconsent"}`)
+	send(CONSENTAPI, "key4", `{"action":"isconsent"}`)
+	if sent != 8 {
+		t.Error("read replayed: ", sent)
+	}
+	send(CONSENTAPI, "key5", `{"action":"create"}`)
+	send(CONSENTAPI, "key5", `{"action":"create"}`)
+	send(ATTESTATIONAPI, "key6", `{}`)
+	send(ATTESTATIONAPI, "key6", `{}`)
+	if sent != 11 {
+		t.Error("write not replayed or verification replayed: ", sent)
+	}
+
+	// a panic of the handler releases the key, the request can be retried
+	for i := 0; i < 2; i++ {
+		func() {
+			defer func() { recover() }()
+			post("key7", "panic")
+		}()
+	}
+	if sent != 13 {
+		t.Error("key not released after a panic: ", sent)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"fmt"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+type MigrateRequest struct {
+	AppID           string `json:"appid"`
+	Max             int    `json:"max,omitempty"`	// max number of consents upgraded by the call (default backup page size)
+}
+
+//HTTP Post - /ocms/v2/admin/migrate
+func (a *AppContext) migrateConsents(w http.ResponseWriter, r *http.Request) {
+	log.Debug("migrateConsents() : calling method -")
+	var request MigrateRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if request.AppID == "" {
+		SendError(w, validationError("appid is mandatory!"))
+		return
+	}
+	if request.Max <= 0 {
+		request.Max = a.backupPageSize()
+	}
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err = InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	content, err := a.migrate(consentHelper, a.ChainCodeID, request.AppID, request.Max)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+func (a *AppContext) migrate(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID string, max int) ([]byte, error) {
+	message := fmt.Sprintf("migrate(applicationID=%s, max=%d) : calling method -", applicationID, max)
+	log.Info(message)
+	result, err := consentHelper.Migrate(chainCodeID, applicationID, max)
+	if err != nil {
+		return nil, err
+	}
+	content, _ := json.Marshal(result)
+	return content, nil
+}
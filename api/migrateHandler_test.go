@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestMigrateFromAPINominal(t *testing.T) {
+	result, err := postMigrate(MigrateRequest{AppID: APPID, Max: 10})
+	if err != nil {
+		t.Error(err)
+	}
+	if result.AppID != APPID || result.SchemaVersion == 0 {
+		t.Error("bad migration result: ", result)
+	}
+}
+
+func TestMigrateFromAPIWithoutAppID(t *testing.T) {
+	_, err := postMigrate(MigrateRequest{Max: 10})
+	if err == nil {
+		t.Error("migrate without appid must be rejected")
+	}
+}
+
+func postMigrate(migrateRequest MigrateRequest) (helpers.MigrationResult, error) {
+	result := helpers.MigrationResult{}
+	data, _ := json.Marshal(migrateRequest)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+MIGRATE, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return result, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return result, err
+	}
+	if status != http.StatusOK {
+		return result, errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &result)
+	return result, err
+}
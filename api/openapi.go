@@ -0,0 +1,319 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pascallimeux/ocmsV2/attestation"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+const OPENAPIVERSION = "3.0.0"
+
+// description of an operation of the API, the OpenAPI document and the validation of the bodies are built from it
+type apiOperation struct {
+	Method    string
+	Path      string
+	Tag       string
+	Summary   string
+	Request   interface{}   // value of the type of the JSON body, nil without JSON body
+	Required  []string      // mandatory attributes of the body
+	Enums     map[string][]string // allowed values of attributes of the body (action...)
+	RawBody   []string      // content types of a raw body (document)
+	Query     []string      // query parameters
+	Responses []interface{} // values of the types of the response, one of them is returned
+	Status    int           // status of a success, 200 by default
+}
+
+// body of PATCH /ocms/v3/api/apps/{appid}/consents/{consentid}
+type ConsentPatch struct {
+	State string `json:"state"`
+}
+
+// filter of the consent lists (see queryFilter)
+var listQuery = []string{"datatype", "dataaccess", "validAt", "createdAfter", "createdBefore", "state", "sort", "fields"}
+
+// every route created by CreateOCMSRoutes must be described here (see TestOpenAPIDescribesAllRoutes)
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: OPENAPI, Tag: "api", Summary: "OpenAPI document of the API", Responses: []interface{}{map[string]interface{}{}}},
+	{Method: "GET", Path: VERSIONURI, Tag: "api", Summary: "Version of the chaincode", Responses: []interface{}{Version{}}},
+	{Method: "POST", Path: CONSENTAPI, Tag: "consent", Summary: "Consent operation selected by the action attribute",
+		Request: helpers.Consent{}, Required: []string{"action", "appid"},
+		Enums: map[string][]string{"action": {"create", "list", "get", "getbyref", "remove", "list4owner", "list4consumer", "isconsent", "wasconsent", "logaccess", "accesses4owner", "registerownerkey", "breakglass", "pendingbreakglass", "ackbreakglass"}},
+		Responses: []interface{}{helpers.Consent{}, []helpers.Consent{}, IsConsent{}, helpers.PastDecision{}, helpers.AccessEvent{}, []helpers.AccessEvent{}, helpers.OwnerKey{}, helpers.BreakGlassEvent{}, []helpers.BreakGlassEvent{}}},
+	{Method: "POST", Path: ATTESTATIONAPI, Tag: "consent", Summary: "Verify an attestation of a consent decision",
+		Request: AttestationToken{}, Required: []string{"token"}, Responses: []interface{}{attestation.Claims{}}},
+	{Method: "POST", Path: DATATYPEAPI, Tag: "datatype", Summary: "Data type operation selected by the action attribute",
+		Request: helpers.DataType{}, Required: []string{"action"}, Enums: map[string][]string{"action": {"add", "list", "deprecate"}},
+		Responses: []interface{}{helpers.DataType{}, []helpers.DataType{}}},
+	{Method: "POST", Path: TEMPLATEAPI, Tag: "template", Summary: "Template operation selected by the action attribute",
+		Request: helpers.ConsentTemplate{}, Required: []string{"action", "appid"}, Enums: map[string][]string{"action": {"put", "get", "list", "grant", "getgrant", "revokegrant"}},
+		Responses: []interface{}{helpers.ConsentTemplate{}, []helpers.ConsentTemplate{}, helpers.Grant{}, []helpers.Consent{}}},
+	{Method: "POST", Path: GROUPAPI, Tag: "group", Summary: "Consumer group operation selected by the action attribute",
+		Request: helpers.ConsumerGroup{}, Required: []string{"action", "appid", "groupid"}, Enums: map[string][]string{"action": {"create", "addmember", "removemember", "members"}},
+		Responses: []interface{}{helpers.ConsumerGroup{}}},
+	{Method: "GET", Path: TRANSACTIONAPI + "/{txid}/status", Tag: "transaction", Summary: "Status of a transaction: pending, valid or invalid with its reason",
+		Responses: []interface{}{helpers.TransactionStatus{}}},
+	{Method: "GET", Path: APPSAPI + "/{appid}/consents", Tag: "consent v3", Summary: "List the consents of an application",
+		Query: append([]string{"externalref"}, listQuery...), Responses: []interface{}{[]helpers.Consent{}, helpers.Consent{}}},
+	{Method: "POST", Path: APPSAPI + "/{appid}/consents", Tag: "consent v3", Summary: "Create a consent",
+		Request: helpers.Consent{}, Required: []string{"ownerid", "consumerid"}, Responses: []interface{}{helpers.Consent{}}, Status: http.StatusCreated},
+	{Method: "GET", Path: APPSAPI + "/{appid}/consents/{consentid}", Tag: "consent v3", Summary: "Get a consent", Responses: []interface{}{helpers.Consent{}}},
+	{Method: "PATCH", Path: APPSAPI + "/{appid}/consents/{consentid}", Tag: "consent v3", Summary: "Revoke a consent (state unactive)",
+		Request: ConsentPatch{}, Required: []string{"state"}, Enums: map[string][]string{"state": {"unactive"}}, Responses: []interface{}{helpers.Consent{}}},
+	{Method: "DELETE", Path: APPSAPI + "/{appid}/consents/{consentid}", Tag: "consent v3", Summary: "Revoke a consent", Responses: []interface{}{helpers.Consent{}}},
+	{Method: "GET", Path: APPSAPI + "/{appid}/owners/{ownerid}/consents", Tag: "consent v3", Summary: "List the consents of an owner", Query: listQuery, Responses: []interface{}{[]helpers.Consent{}}},
+	{Method: "GET", Path: APPSAPI + "/{appid}/consumers/{consumerid}/consents", Tag: "consent v3", Summary: "List the consents of a consumer", Query: listQuery, Responses: []interface{}{[]helpers.Consent{}}},
+	{Method: "GET", Path: APPSAPI + "/{appid}/authorizations", Tag: "consent v3", Summary: "Consent decision now, or at an instant",
+		Query: []string{"ownerid", "consumerid", "datatype", "dataaccess", "at", "context.{attribute}"}, Responses: []interface{}{IsConsent{}, helpers.PastDecision{}}},
+	{Method: "POST", Path: DOCUMENTAPI + "/{appid}/{consentid}", Tag: "document", Summary: "Store and anchor the consent form of a consent",
+		RawBody: []string{"application/pdf", "text/plain"}, Query: []string{"version", "uri"}, Responses: []interface{}{DocumentReceipt{}}},
+	{Method: "POST", Path: DOCUMENTAPI + "/{appid}/{consentid}/verify", Tag: "document", Summary: "Verify a document against the anchored hash",
+		RawBody: []string{"application/pdf", "text/plain"}, Responses: []interface{}{DocumentVerification{}}},
+	{Method: "GET", Path: BCINFO, Tag: "dashboard", Summary: "Blockchain information", Responses: []interface{}{common.BlockchainInfo{}}},
+	{Method: "GET", Path: GETCHANNELS, Tag: "dashboard", Summary: "Channels of the peer", Responses: []interface{}{pb.ChannelQueryResponse{}}},
+	{Method: "GET", Path: GETPEERS, Tag: "dashboard", Summary: "Peers of the chain", Responses: []interface{}{[]map[string]interface{}{}}},
+	{Method: "GET", Path: INSTALLEDCC, Tag: "dashboard", Summary: "Installed chaincodes", Responses: []interface{}{pb.ChaincodeQueryResponse{}}},
+	{Method: "GET", Path: INSTANCIATEDCC, Tag: "dashboard", Summary: "Instantiated chaincodes", Responses: []interface{}{pb.ChaincodeQueryResponse{}}},
+	{Method: "GET", Path: QUERYTRANSACTION + "/{truuid}", Tag: "dashboard", Summary: "Transaction details", Responses: []interface{}{pb.ProcessedTransaction{}}},
+	{Method: "GET", Path: BLOCKBYNB + "/{blocknb}", Tag: "dashboard", Summary: "Block by number", Responses: []interface{}{common.Block{}}},
+	{Method: "GET", Path: BLOCKBYHASH + "/{blockhash}", Tag: "dashboard", Summary: "Block by hash", Responses: []interface{}{common.Block{}}},
+	{Method: "GET", Path: QUERYBYCC + "/{ccname}", Tag: "dashboard", Summary: "Query a chaincode", Responses: []interface{}{[][]byte{}}},
+	{Method: "GET", Path: CONSENTSTATS + "/{appid}", Tag: "dashboard", Summary: "Consent statistics of an application", Responses: []interface{}{helpers.ConsentStats{}}},
+	{Method: "GET", Path: SESSIONS, Tag: "dashboard", Summary: "Metrics of the Fabric sessions", Responses: []interface{}{helpers.SessionMetrics{}}},
+	{Method: "POST", Path: LOGIN, Tag: "auth", Summary: "Check the credentials and get a bearer token", Request: helpers.UserCredentials{}, Required: []string{"username", "password"}, Responses: []interface{}{TokenResponse{}}},
+	{Method: "POST", Path: REFRESH, Tag: "auth", Summary: "Replace the bearer token of the request", Responses: []interface{}{TokenResponse{}}},
+	{Method: "POST", Path: LOGOUT, Tag: "auth", Summary: "Revoke the bearer token of the request"},
+	{Method: "POST", Path: REGISTER, Tag: "admin", Summary: "Register a user",
+		Request: helpers.UserRegistrer{}, Required: []string{"name", "type"}, Responses: []interface{}{EnrollmentSecret{}}},
+	{Method: "POST", Path: ENROLL, Tag: "admin", Summary: "Enroll a user", Request: helpers.UserCredentials{}, Required: []string{"username", "password"}},
+	{Method: "POST", Path: REVOKE, Tag: "admin", Summary: "Revoke a user", Request: helpers.UserCredentials{}, Required: []string{"username"}},
+	{Method: "POST", Path: BACKUP, Tag: "admin", Summary: "Back up the consents of an application",
+		Request: BackupRequest{}, Required: []string{"appid"}, Responses: []interface{}{BackupReceipt{}}},
+	{Method: "POST", Path: RESTORE, Tag: "admin", Summary: "Restore the consents of an application",
+		Request: BackupRequest{}, Required: []string{"appid", "file"}, Responses: []interface{}{BackupReceipt{}}},
+	{Method: "POST", Path: MIGRATE, Tag: "admin", Summary: "Upgrade the consents of an application to the current schema",
+		Request: MigrateRequest{}, Required: []string{"appid"}, Responses: []interface{}{helpers.MigrationResult{}}},
+	{Method: "POST", Path: COMPACTSTATS + "/{appid}/compact", Tag: "admin", Summary: "Fold the counters of the consent statistics of an application",
+		Responses: []interface{}{helpers.ConsentStats{}}},
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{([a-z]+)\}`)
+
+//HTTP Get - /ocms/v2/openapi.json
+func (a *AppContext) getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	log.Debug("getOpenAPI() : calling method -")
+	content, err := json.Marshal(openAPIDocument())
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// schemas of the components of the document, by name
+type schemaBuilder struct {
+	components map[string]interface{}
+	types      map[string]reflect.Type
+}
+
+var openAPIOnce sync.Once
+var openAPI map[string]interface{}
+
+// openAPIDocument returns the OpenAPI 3 document of apiOperations, built on first use
+func openAPIDocument() map[string]interface{} {
+	openAPIOnce.Do(func() {
+		openAPI = buildOpenAPIDocument()
+	})
+	return openAPI
+}
+
+func buildOpenAPIDocument() map[string]interface{} {
+	builder := &schemaBuilder{components: map[string]interface{}{}, types: map[string]reflect.Type{}}
+	paths := map[string]interface{}{}
+	for _, operation := range apiOperations {
+		item, ok := paths[operation.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[operation.Path] = item
+		}
+		item[strings.ToLower(operation.Method)] = operation.document(builder)
+	}
+	builder.schemaOf(reflect.TypeOf(ErrorBody{}))
+	return map[string]interface{}{
+		"openapi": OPENAPIVERSION,
+		"info":    map[string]interface{}{"title": "OCMS consent management API", "version": "2"},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas":         builder.components,
+			"securitySchemes": map[string]interface{}{
+				"basicAuth":  map[string]interface{}{"type": "http", "scheme": "basic"},
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}, map[string]interface{}{"basicAuth": []string{}}},
+	}
+}
+
+func (operation apiOperation) document(builder *schemaBuilder) map[string]interface{} {
+	parameters := []interface{}{}
+	for _, match := range pathParamRegexp.FindAllStringSubmatch(operation.Path, -1) {
+		parameters = append(parameters, map[string]interface{}{"name": match[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}})
+	}
+	for _, name := range operation.Query {
+		parameters = append(parameters, map[string]interface{}{"name": name, "in": "query", "schema": map[string]interface{}{"type": "string"}})
+	}
+	// the transactions of the writes are sent in the commit mode of the request (see Commits)
+	if operation.Method != "GET" {
+		parameters = append(parameters, map[string]interface{}{"name": COMMITPARAM, "in": "query", "schema": map[string]interface{}{"type": "string", "enum": helpers.CommitModes}})
+	}
+	// a retried write with the same key gets the first response (see Idempotency)
+	if isWriteRoute(operation.Method, operation.Path) {
+		parameters = append(parameters, map[string]interface{}{"name": IDEMPOTENCYKEYHEADER, "in": "header", "schema": map[string]interface{}{"type": "string", "maxLength": IDEMPOTENCYKEYMAXSIZE}})
+	}
+	document := map[string]interface{}{
+		"tags":       []string{operation.Tag},
+		"summary":    operation.Summary,
+		"parameters": parameters,
+	}
+	if operation.Request != nil {
+		document["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": operation.requestSchema(builder)}},
+		}
+	} else if len(operation.RawBody) > 0 {
+		content := map[string]interface{}{}
+		for _, contentType := range operation.RawBody {
+			content[contentType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}
+		}
+		document["requestBody"] = map[string]interface{}{"required": true, "content": content}
+	}
+	success := map[string]interface{}{"description": "success"}
+	if len(operation.Responses) == 1 {
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": builder.schemaOf(reflect.TypeOf(operation.Responses[0]))}}
+	} else if len(operation.Responses) > 1 {
+		oneOf := []interface{}{}
+		for _, response := range operation.Responses {
+			oneOf = append(oneOf, builder.schemaOf(reflect.TypeOf(response)))
+		}
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{"oneOf": oneOf}}}
+	}
+	status := operation.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	document["responses"] = map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default": map[string]interface{}{
+			"description": "error",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/ErrorBody"}}},
+		},
+	}
+	return document
+}
+
+// schema of the body of the operation: the schema of its type with the mandatory attributes and the actions
+func (operation apiOperation) requestSchema(builder *schemaBuilder) map[string]interface{} {
+	schema := builder.schemaOf(reflect.TypeOf(operation.Request))
+	constraints := map[string]interface{}{"type": "object"}
+	if len(operation.Required) > 0 {
+		constraints["required"] = operation.Required
+	}
+	if len(operation.Enums) > 0 {
+		properties := map[string]interface{}{}
+		for name, values := range operation.Enums {
+			properties[name] = map[string]interface{}{"type": "string", "enum": values}
+		}
+		constraints["properties"] = properties
+	}
+	return map[string]interface{}{"allOf": []interface{}{schema, constraints}}
+}
+
+// schemaOf returns the JSON schema of a type, the structures are added to the components and referenced
+func (builder *schemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == nil || t.Kind() == reflect.Interface:
+		return map[string]interface{}{}
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == reflect.TypeOf(json.RawMessage{}):
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return builder.schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": builder.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": builder.schemaOf(t.Elem())}
+	case reflect.Struct:
+		name := builder.componentName(t)
+		if _, ok := builder.components[name]; !ok {
+			// registered before its properties for the recursive types
+			schema := map[string]interface{}{"type": "object"}
+			builder.components[name] = schema
+			properties := map[string]interface{}{}
+			builder.addProperties(t, properties)
+			schema["properties"] = properties
+			schema["additionalProperties"] = false
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (builder *schemaBuilder) addProperties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || strings.HasPrefix(field.Name, "XXX_") {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			builder.addProperties(field.Type, properties)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = builder.schemaOf(field.Type)
+	}
+}
+
+// name of the component of a structure, prefixed by its package when the name is used by another structure
+func (builder *schemaBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		name = "Anonymous"
+	}
+	if registered, ok := builder.types[name]; ok && registered != t {
+		name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + name
+	}
+	builder.types[name] = t
+	return name
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"github.com/gorilla/mux"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestOpenAPIDescribesAllRoutes(t *testing.T) {
+	router := mux.NewRouter().StrictSlash(false)
+	appContext := AppContext{}
+	appContext.CreateOCMSRoutes(router)
+	routes := 0
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		routes++
+		return nil
+	})
+	if routes != len(apiOperations) {
+		t.Error("bad number of operations in the OpenAPI document: ", len(apiOperations), " routes: ", routes)
+	}
+	for _, operation := range apiOperations {
+		path := pathParamRegexp.ReplaceAllString(operation.Path, "x")
+		request, _ := http.NewRequest(operation.Method, path, nil)
+		var match mux.RouteMatch
+		if !router.Match(request, &match) {
+			t.Error("operation without route: ", operation.Method, " ", operation.Path)
+			continue
+		}
+		template, _ := match.Route.GetPathTemplate()
+		if template != operation.Path {
+			t.Error("operation routed to another path: ", operation.Path, " ", template)
+		}
+	}
+}
+
+func TestValidateConsentBody(t *testing.T) {
+	schema := requestBodySchema("POST", CONSENTAPI)
+	invalids := []string{
+		`{"appid":"app1"}`,
+		`{"action":"unknown","appid":"app1"}`,
+		`{"action":"create","appid":"app1","owner":"o1"}`,
+		`{"action":"create","appid":"app1","ownersigned":"true"}`,
+		`{"action":"create","appid":"app1","conditions":[{"attribute":1}]}`,
+		`[]`,
+	}
+	for _, body := range invalids {
+		err := validateBody([]byte(body), schema)
+		if helpers.ErrorCode(err) != helpers.ERROR_VALIDATION {
+			t.Error("invalid body accepted: ", body)
+		}
+	}
+	valid := `{"action":"isconsent","appid":"app1","ownerid":"o1","consumerid":"c1","context":{"location":"home"},"conditions":[{"attribute":"a","operator":"eq","value":"v"}]}`
+	err := validateBody([]byte(valid), schema)
+	if err != nil {
+		t.Error("valid body rejected: ", err)
+	}
+}
+
+func TestOpenAPIFromAPI(t *testing.T) {
+	request, _ := buildRequest("GET", httpServerTest.URL+OPENAPI, "")
+	status, body_bytes, err := executeRequest(request)
+	if err != nil || status != http.StatusOK {
+		t.Fatal("bad status: ", status, err)
+	}
+	document := map[string]interface{}{}
+	json.Unmarshal(body_bytes, &document)
+	if document["openapi"] != OPENAPIVERSION || !strings.Contains(string(body_bytes), CONSENTAPI) {
+		t.Error("bad OpenAPI document: ", string(body_bytes))
+	}
+}
+
+func TestValidationBeforeHelperInit(t *testing.T) {
+	// the body is rejected before the helper is initialized
+	request, _ := buildRequestWithLoginPassword("POST", httpServerTest.URL+CONSENTAPI, `{"action":"create"}`, ADMINNAME, ADMINPWD)
+	status, body_bytes, err := executeRequest(request)
+	if err != nil || status != http.StatusUnprocessableEntity {
+		t.Error("bad status: ", status, " ", string(body_bytes), err)
+	}
+}
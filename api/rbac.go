@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+// groups of routes, each group is allowed to some roles
+const (
+	CONSENTGROUP   = "consent"
+	DASHBOARDGROUP = "dashboard"
+	ADMINGROUP     = "admin"
+)
+
+// group of the routes by prefix, the routes out of a group (openapi, auth) are open
+var routeGroups = []struct {
+	prefix		string
+	group		string
+}{
+	{"/ocms/v2/api/", CONSENTGROUP},
+	{"/ocms/v3/api/", CONSENTGROUP},
+	{"/ocms/v2/dashboard/", DASHBOARDGROUP},
+	{"/ocms/v2/admin/", ADMINGROUP},
+}
+
+type RolePolicy struct {
+	// certificate attribute of the roles (an OU RoleAttribute=role of the certificate is read too)
+	RoleAttribute	string
+	// roles allowed by group, a group without roles is open to any authenticated user
+	Groups		map[string][]string
+	// identity given the admin role whatever its certificate: the registrar of the CA registers the first users
+	BootstrapAdmin	string
+	StatStorePath	string
+}
+
+// Authorize answers 403 to an identity without any of the roles of the route group, before the helpers are initialized
+func Authorize(policy RolePolicy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := routeGroup(r.URL.Path)
+		allowed := policy.Groups[group]
+		if len(allowed) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userCredentials, err := GetUserCredentials(r)
+		if err != nil {
+			SendError(w, err)
+			return
+		}
+		roles, err := policy.roles(userCredentials.UserName)
+		if err != nil && helpers.ErrorCode(err) != helpers.ERROR_UNAUTHORIZED {
+			SendError(w, err)
+			return
+		}
+		for _, role := range roles {
+			for _, allowedRole := range allowed {
+				if role == allowedRole {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+		log.Warning("Authorize(user:" + userCredentials.UserName + ") : roles [" + strings.Join(roles, ",") + "] refused on " + r.URL.Path)
+		SendError(w, helpers.NewError(helpers.ERROR_FORBIDDEN, "%s routes need one of the roles: %s", group, strings.Join(allowed, ", ")))
+	})
+}
+
+// the roles are given by the certificate of the identity (a role attribute or OU), the bootstrap admin is admin
+// (its identity is verified by Authenticate)
+func (policy RolePolicy) roles(userName string) ([]string, error) {
+	roles, err := helpers.GetRoles(userName, policy.StatStorePath, policy.RoleAttribute)
+	if policy.BootstrapAdmin != "" && userName == policy.BootstrapAdmin {
+		return append(roles, helpers.ROLE_ADMIN), nil
+	}
+	return roles, err
+}
+
+func routeGroup(path string) string {
+	for _, routeGroup := range routeGroups {
+		if strings.HasPrefix(path, routeGroup.prefix) {
+			return routeGroup.group
+		}
+	}
+	return ""
+}
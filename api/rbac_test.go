@@ -0,0 +1,117 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+	fabricClient "github.com/hyperledger/fabric-sdk-go/fabric-client"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+// enrolled user of the state store, its roles are the OU ocms.role=role of its certificate
+func writeEnrolledUser(t *testing.T, statStorePath, userName string, ous []string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: userName, OrganizationalUnit: ous},
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(fabricClient.UserJSON{EnrollmentCertificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})})
+	ioutil.WriteFile(path.Join(statStorePath, userName+".json"), data, 0600)
+}
+
+func TestAuthorizeByRouteGroup(t *testing.T) {
+	statStorePath, err := ioutil.TempDir("", "statstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(statStorePath)
+	writeEnrolledUser(t, statStorePath, "app1", []string{"client", "ocms.role=" + helpers.ROLE_APP})
+	writeEnrolledUser(t, statStorePath, "auditor1", []string{"ocms.role=" + helpers.ROLE_AUDITOR})
+	writeEnrolledUser(t, statStorePath, "admin1", []string{"ocms.role=" + helpers.ROLE_ADMIN})
+	writeEnrolledUser(t, statStorePath, "admin", []string{})
+	policy := RolePolicy{RoleAttribute: "ocms.role", StatStorePath: statStorePath, Groups: map[string][]string{
+		CONSENTGROUP:   {helpers.ROLE_ADMIN, helpers.ROLE_APP, helpers.ROLE_OWNER},
+		DASHBOARDGROUP: {helpers.ROLE_ADMIN, helpers.ROLE_AUDITOR},
+		ADMINGROUP:     {helpers.ROLE_ADMIN},
+	}}
+	handler := Authorize(policy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	checks := []struct {
+		user	string
+		uri	string
+		status	int
+	}{
+		{"admin1", REGISTER, http.StatusOK},
+		{"admin1", BCINFO, http.StatusOK},
+		{"admin", REGISTER, http.StatusForbidden},
+		{"app1", CONSENTAPI, http.StatusOK},
+		{"app1", APPSAPI + "/app1/consents", http.StatusOK},
+		{"app1", BCINFO, http.StatusForbidden},
+		{"app1", REVOKE, http.StatusForbidden},
+		{"auditor1", BCINFO, http.StatusOK},
+		{"auditor1", CONSENTAPI, http.StatusForbidden},
+		{"unknown", CONSENTAPI, http.StatusForbidden},
+		{"", CONSENTAPI, http.StatusUnauthorized},
+		{"", LOGIN, http.StatusOK},
+		{"", OPENAPI, http.StatusOK},
+	}
+	for _, check := range checks {
+		request := httptest.NewRequest("POST", check.uri, nil)
+		if check.user != "" {
+			request.SetBasicAuth(check.user, "pwd")
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != check.status {
+			t.Error("bad status for ", check.user, " on ", check.uri, ": ", recorder.Code, " ", recorder.Body.String())
+		}
+	}
+}
+
+func TestAuthorizeBootstrapAdmin(t *testing.T) {
+	statStorePath, err := ioutil.TempDir("", "statstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(statStorePath)
+	writeEnrolledUser(t, statStorePath, "app1", []string{"ocms.role=" + helpers.ROLE_APP})
+	policy := RolePolicy{RoleAttribute: "ocms.role", StatStorePath: statStorePath, BootstrapAdmin: "admin",
+		Groups: map[string][]string{ADMINGROUP: {helpers.ROLE_ADMIN}}}
+	handler := Authorize(policy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	checks := []struct {
+		user	string
+		status	int
+	}{
+		{"admin", http.StatusOK},
+		{"app1", http.StatusForbidden},
+	}
+	for _, check := range checks {
+		request := httptest.NewRequest("POST", REGISTER, nil)
+		request.SetBasicAuth(check.user, "pwd")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != check.status {
+			t.Error("bad status for ", check.user, ": ", recorder.Code, " ", recorder.Body.String())
+		}
+	}
+}
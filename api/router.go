@@ -1,15 +1,29 @@
 package api
 
 import (
+	"crypto/ecdsa"
 	"github.com/gorilla/mux"
 	"net/http"
+	"time"
 	"github.com/op/go-logging"
+	"github.com/pascallimeux/ocmsV2/auth"
+	"github.com/pascallimeux/ocmsV2/helpers"
+	"github.com/pascallimeux/ocmsV2/idempotency"
 )
 var log = logging.MustGetLogger("ocms.api")
 
 const (
+	OPENAPI          = "/ocms/v2/openapi.json"
 	VERSIONURI       = "/ocms/v2/api/version"
 	CONSENTAPI       = "/ocms/v2/api/consent/"
+	ATTESTATIONAPI   = "/ocms/v2/api/attestation/verify"
+	DATATYPEAPI      = "/ocms/v2/api/datatype/"
+	DOCUMENTAPI      = "/ocms/v2/api/document"
+	TEMPLATEAPI      = "/ocms/v2/api/template/"
+	GROUPAPI         = "/ocms/v2/api/group/"
+	TRANSACTIONAPI   = "/ocms/v2/api/transactions"
+
+	APPSAPI          = "/ocms/v3/api/apps"
 
 	BCINFO           = "/ocms/v2/dashboard/chain"
 	QUERYTRANSACTION = "/ocms/v2/dashboard/transaction"
@@ -20,10 +34,20 @@ const (
 	QUERYBYCC        = "/ocms/v2/dashboard/cc/query"
 	GETPEERS         = "/ocms/v2/dashboard/peers"
 	INSTANCIATEDCC   = "/ocms/v2/dashboard/cc/instanciated"
+	CONSENTSTATS     = "/ocms/v2/dashboard/stats"
+	SESSIONS         = "/ocms/v2/dashboard/sessions"
+
+	LOGIN            = "/ocms/v2/auth/login"
+	REFRESH          = "/ocms/v2/auth/refresh"
+	LOGOUT           = "/ocms/v2/auth/logout"
 
 	REGISTER         = "/ocms/v2/admin/user/register"
 	ENROLL           = "/ocms/v2/admin/user/enroll"
 	REVOKE           = "/ocms/v2/admin/user/revoke"
+	BACKUP           = "/ocms/v2/admin/backup"
+	RESTORE          = "/ocms/v2/admin/restore"
+	MIGRATE          = "/ocms/v2/admin/migrate"
+	COMPACTSTATS     = "/ocms/v2/admin/stats"
 )
 
 type AppContext struct {
@@ -32,12 +56,45 @@ type AppContext struct {
 	ChainID         string
 	Repo            string
 	StatStorePath   string
+	AttestationKey    *ecdsa.PrivateKey
+	AttestationIssuer string
+	AttestationTTL    time.Duration
+	LogAccessOnIsConsent bool
+	DuplicateMode     string
+	DocumentStorePath string
+	DocumentMaxSize   int64
+	BackupPath        string
+	BackupPageSize    int
+	Sessions          *helpers.SessionManager
+	Tokens            *auth.Manager
+	AllowBasicAuth    bool
+	RolePolicy        RolePolicy
+	CommitMode        string
+	CommitTimeout     time.Duration
+	Transactions      *helpers.TransactionTracker
+	Idempotency       *idempotency.Store
 }
 
 func (a *AppContext) CreateOCMSRoutes(router *mux.Router) {
 	log.Debug("CreateOCMSRoutes() : calling method -")
+	router.HandleFunc(OPENAPI, a.getOpenAPI).Methods("GET")
 	router.HandleFunc(VERSIONURI, a.getVersion).Methods("GET")
 	router.HandleFunc(CONSENTAPI, a.processConsent).Methods("POST")
+	router.HandleFunc(ATTESTATIONAPI, a.verifyAttestation).Methods("POST")
+	router.HandleFunc(DATATYPEAPI, a.processDataType).Methods("POST")
+	router.HandleFunc(TEMPLATEAPI, a.processTemplate).Methods("POST")
+	router.HandleFunc(GROUPAPI, a.processGroup).Methods("POST")
+	router.HandleFunc(TRANSACTIONAPI+"/{txid}/status", a.transactionStatus).Methods("GET")
+	router.HandleFunc(APPSAPI+"/{appid}/consents", a.listConsentResources).Methods("GET")
+	router.HandleFunc(APPSAPI+"/{appid}/consents", a.createConsentResource).Methods("POST")
+	router.HandleFunc(APPSAPI+"/{appid}/consents/{consentid}", a.getConsentResource).Methods("GET")
+	router.HandleFunc(APPSAPI+"/{appid}/consents/{consentid}", a.patchConsentResource).Methods("PATCH")
+	router.HandleFunc(APPSAPI+"/{appid}/consents/{consentid}", a.deleteConsentResource).Methods("DELETE")
+	router.HandleFunc(APPSAPI+"/{appid}/owners/{ownerid}/consents", a.listOwnerConsentResources).Methods("GET")
+	router.HandleFunc(APPSAPI+"/{appid}/consumers/{consumerid}/consents", a.listConsumerConsentResources).Methods("GET")
+	router.HandleFunc(APPSAPI+"/{appid}/authorizations", a.getAuthorizationResource).Methods("GET")
+	router.HandleFunc(DOCUMENTAPI+"/{appid}/{consentid}", a.uploadDocument).Methods("POST")
+	router.HandleFunc(DOCUMENTAPI+"/{appid}/{consentid}/verify", a.verifyDocument).Methods("POST")
 	router.HandleFunc(BCINFO, a.blockchainInfo).Methods("GET")
 	router.HandleFunc(GETCHANNELS, a.getChannels).Methods("GET")
 	router.HandleFunc(GETPEERS, a.getPeers).Methods("GET")
@@ -47,7 +104,16 @@ func (a *AppContext) CreateOCMSRoutes(router *mux.Router) {
 	router.HandleFunc(BLOCKBYNB+"/{blocknb}", a.blockByNumber).Methods("GET")
 	router.HandleFunc(BLOCKBYHASH+"/{blockhash}", a.blockByHash).Methods("GET")
 	router.HandleFunc(QUERYBYCC+"/{ccname}", a.queryByCC).Methods("GET")
+	router.HandleFunc(CONSENTSTATS+"/{appid}", a.consentStats).Methods("GET")
+	router.HandleFunc(SESSIONS, a.sessionMetrics).Methods("GET")
+	router.HandleFunc(LOGIN, a.login).Methods("POST")
+	router.HandleFunc(REFRESH, a.refreshToken).Methods("POST")
+	router.HandleFunc(LOGOUT, a.logout).Methods("POST")
 	router.HandleFunc(REGISTER, a.registerUser).Methods("POST")
 	router.HandleFunc(ENROLL, a.enrollUser).Methods("POST")
 	router.HandleFunc(REVOKE, a.revokeUser).Methods("POST")
+	router.HandleFunc(BACKUP, a.backupConsents).Methods("POST")
+	router.HandleFunc(RESTORE, a.restoreConsents).Methods("POST")
+	router.HandleFunc(MIGRATE, a.migrateConsents).Methods("POST")
+	router.HandleFunc(COMPACTSTATS+"/{appid}/compact", a.compactStats).Methods("POST")
 }
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"encoding/json"
+	"fmt"
+	"time"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+//HTTP Post - /ocms/v2/api/template
+func (a *AppContext) processTemplate(w http.ResponseWriter, r *http.Request) {
+	log.Debug("processTemplate() : calling method -")
+
+	var bytes []byte
+	var consentTemplate helpers.ConsentTemplate
+	err := json.NewDecoder(r.Body).Decode(&consentTemplate)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+	err = InitHelper(r, consentHelper)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	switch action := consentTemplate.Action; action {
+	case "put":
+		bytes, err = a.putTemplate(consentHelper, a.ChainCodeID, consentTemplate)
+	case "get":
+		bytes, err = a.getTemplate(consentHelper, a.ChainCodeID, consentTemplate.AppID, consentTemplate.TemplateID)
+	case "list":
+		bytes, err = a.listTemplates(consentHelper, a.ChainCodeID, consentTemplate.AppID)
+	case "grant":
+		bytes, err = a.grantTemplate(consentHelper, a.ChainCodeID, consentTemplate)
+	case "getgrant":
+		bytes, err = a.getGrant(consentHelper, a.ChainCodeID, consentTemplate.AppID, consentTemplate.GrantID)
+	case "revokegrant":
+		bytes, err = a.revokeGrant(consentHelper, a.ChainCodeID, consentTemplate.AppID, consentTemplate.GrantID)
+	default:
+		log.Error("bad action request")
+		SendError(w, validationError("bad action request"))
+		return
+	}
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bytes)
+}
+
+func (a *AppContext) putTemplate(consentHelper *helpers.ConsentHelper, chainCodeID string, consentTemplate helpers.ConsentTemplate) ([]byte, error) {
+	message := fmt.Sprintf("putTemplate(applicationID=%s, templateID=%s) : calling method -", consentTemplate.AppID, consentTemplate.TemplateID)
+	log.Info(message)
+	if consentTemplate.TemplateID == "" || len(consentTemplate.Items) == 0 {
+		return nil, validationError("templateid and items are mandatory!")
+	}
+	_, err := consentHelper.PutTemplate(chainCodeID, consentTemplate.AppID, consentTemplate.TemplateID, consentTemplate.Label, consentTemplate.Items, consentTemplate.ValidityDays)
+	if err != nil {
+		return nil, err
+	}
+	consentTemplate.Action = ""
+	return json.Marshal(consentTemplate)
+}
+
+func (a *AppContext) getTemplate(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, templateID string) ([]byte, error) {
+	message := fmt.Sprintf("getTemplate(applicationID=%s, templateID=%s) : calling method -", applicationID, templateID)
+	log.Info(message)
+	if templateID == "" {
+		return nil, validationError("templateid is mandatory!")
+	}
+	consentTemplate, err := consentHelper.GetTemplate(chainCodeID, applicationID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(consentTemplate)
+}
+
+func (a *AppContext) listTemplates(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID string) ([]byte, error) {
+	message := fmt.Sprintf("listTemplates(applicationID=%s) : calling method -", applicationID)
+	log.Info(message)
+	templates, err := consentHelper.ListTemplates(chainCodeID, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(templates)
+}
+
+func (a *AppContext) grantTemplate(consentHelper *helpers.ConsentHelper, chainCodeID string, consentTemplate helpers.ConsentTemplate) ([]byte, error) {
+	message := fmt.Sprintf("grantTemplate(applicationID=%s, templateID=%s, ownerID=%s, consumerID=%s) : calling method -", consentTemplate.AppID, consentTemplate.TemplateID, consentTemplate.OwnerID, consentTemplate.ConsumerID)
+	log.Info(message)
+	if consentTemplate.TemplateID == "" || consentTemplate.OwnerID == "" || consentTemplate.ConsumerID == "" {
+		return nil, validationError("templateid, ownerid and consumerid are mandatory!")
+	}
+	if consentTemplate.Dt_begin == "" {
+		consentTemplate.Dt_begin = time.Now().Format("2006-01-02")
+	}
+	// the consents of a grant are never merged in other consents
+	if consentTemplate.Mode == "" && a.DuplicateMode == "reject" {
+		consentTemplate.Mode = a.DuplicateMode
+	}
+	var options []helpers.GrantOptions
+	if consentTemplate.Mode != "" || len(consentTemplate.OwnerSignatures) > 0 {
+		options = append(options, helpers.GrantOptions{Mode: consentTemplate.Mode, OwnerCert: consentTemplate.OwnerCert, Nonce: consentTemplate.Nonce, OwnerSignatures: consentTemplate.OwnerSignatures})
+	}
+	grant, err := consentHelper.CreateConsentsFromTemplate(chainCodeID, consentTemplate.AppID, consentTemplate.TemplateID, consentTemplate.OwnerID, consentTemplate.ConsumerID, consentTemplate.Dt_begin, consentTemplate.Dt_end, options...)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(grant)
+}
+
+func (a *AppContext) getGrant(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, grantID string) ([]byte, error) {
+	message := fmt.Sprintf("getGrant(applicationID=%s, grantID=%s) : calling method -", applicationID, grantID)
+	log.Info(message)
+	if grantID == "" {
+		return nil, validationError("grantid is mandatory!")
+	}
+	consents, err := consentHelper.GetGrant(chainCodeID, applicationID, grantID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(consents)
+}
+
+func (a *AppContext) revokeGrant(consentHelper *helpers.ConsentHelper, chainCodeID, applicationID, grantID string) ([]byte, error) {
+	message := fmt.Sprintf("revokeGrant(applicationID=%s, grantID=%s) : calling method -", applicationID, grantID)
+	log.Info(message)
+	if grantID == "" {
+		return nil, validationError("grantid is mandatory!")
+	}
+	_, err := consentHelper.RevokeGrant(chainCodeID, applicationID, grantID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(helpers.ConsentTemplate{AppID: applicationID, GrantID: grantID})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestGrantTemplateFromAPINominal(t *testing.T) {
+	items := []helpers.TemplateItem{{DataType: "BP", DataAccess: "R"}, {DataType: "HR", DataAccess: "R"}}
+	_, err := postTemplate(helpers.ConsentTemplate{Action: "put", TemplateID: "monitoring", Label: "remote monitoring", Items: items, ValidityDays: 365})
+	if err != nil {
+		t.Error(err)
+	}
+	time.Sleep(TransactionTimeout)
+	body, err := postTemplate(helpers.ConsentTemplate{Action: "grant", TemplateID: "monitoring", OwnerID: "TPL1", ConsumerID: "TPL2"})
+	if err != nil {
+		t.Error(err)
+	}
+	grant := helpers.Grant{}
+	json.Unmarshal(body, &grant)
+	if grant.GrantID == "" || len(grant.ConsentIDs) != len(items) {
+		t.Error("bad grant: ", string(body))
+	}
+	time.Sleep(TransactionTimeout)
+	_, err = postTemplate(helpers.ConsentTemplate{Action: "revokegrant", GrantID: grant.GrantID})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func postTemplate(consentTemplate helpers.ConsentTemplate) ([]byte, error) {
+	consentTemplate.AppID = APPID
+	data, _ := json.Marshal(consentTemplate)
+	request, err := buildRequestWithLoginPassword("POST", httpServerTest.URL+TEMPLATEAPI, string(data), ADMINNAME, ADMINPWD)
+	if err != nil {
+		return nil, err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, errors.New("bad status")
+	}
+	return body_bytes, nil
+}
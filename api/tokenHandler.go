@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/pascallimeux/ocmsV2/auth"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+// identity of a request authenticated with a bearer token or checked basic credentials
+const CREDENTIALSKEY = contextKey("credentials")
+
+const BEARER = "Bearer "
+
+type TokenResponse struct {
+	Token		string	`json:"token"`
+	TokenType	string	`json:"tokentype"`
+	ExpiresAt	string	`json:"expiresat"`
+	ExpiresIn	int64	`json:"expiresin"`
+}
+
+//HTTP Post - /ocms/v2/auth/login
+// the credentials are checked once, the token is sent in place of the credentials (Authorization: Bearer xxx)
+func (a *AppContext) login(w http.ResponseWriter, r *http.Request) {
+	log.Debug("login() : calling method -")
+	var credentials helpers.UserCredentials
+	err := json.NewDecoder(r.Body).Decode(&credentials)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	if a.Tokens == nil {
+		SendError(w, helpers.NewError(helpers.ERROR_INTERNAL, "token authentication is not configured"))
+		return
+	}
+	err = helpers.CheckCredentials(credentials, a.StatStorePath)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	token, claims, err := a.Tokens.Issue(credentials.UserName)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	sendToken(w, token, claims)
+}
+
+//HTTP Post - /ocms/v2/auth/refresh
+// the token of the request is revoked and replaced, until the end of the login session
+func (a *AppContext) refreshToken(w http.ResponseWriter, r *http.Request) {
+	log.Debug("refreshToken() : calling method -")
+	if a.Tokens == nil {
+		SendError(w, helpers.NewError(helpers.ERROR_INTERNAL, "token authentication is not configured"))
+		return
+	}
+	token, claims, err := a.Tokens.Refresh(getBearerToken(r))
+	if err != nil {
+		SendError(w, tokenError(err))
+		return
+	}
+	sendToken(w, token, claims)
+}
+
+//HTTP Post - /ocms/v2/auth/logout
+func (a *AppContext) logout(w http.ResponseWriter, r *http.Request) {
+	log.Debug("logout() : calling method -")
+	if a.Tokens == nil {
+		SendError(w, helpers.NewError(helpers.ERROR_INTERNAL, "token authentication is not configured"))
+		return
+	}
+	claims, err := a.Tokens.Verify(getBearerToken(r))
+	if err != nil {
+		SendError(w, tokenError(err))
+		return
+	}
+	err = a.Tokens.Revoke(claims)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	content := []byte("")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// Authenticate checks the bearer token of the request, the identity of the token is used in place of the credentials,
+// basic authentication is refused when allowBasicAuth is false, else the basic credentials are checked before the
+// identity is used by the roles and the helpers
+func Authenticate(tokens *auth.Manager, allowBasicAuth bool, statStorePath string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := getBearerToken(r)
+		if token != "" && tokens != nil {
+			claims, err := tokens.Verify(token)
+			if err != nil {
+				SendError(w, tokenError(err))
+				return
+			}
+			credentials := helpers.UserCredentials{UserName: claims.Subject}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), CREDENTIALSKEY, credentials)))
+			return
+		}
+		if username, password, ok := r.BasicAuth(); ok {
+			if !allowBasicAuth {
+				SendError(w, helpers.NewError(helpers.ERROR_UNAUTHORIZED, "basic authentication is disabled, login to get a token"))
+				return
+			}
+			credentials := helpers.UserCredentials{UserName: username, EnrollmentSecret: password}
+			err := helpers.CheckCredentials(credentials, statStorePath)
+			if err != nil {
+				SendError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), CREDENTIALSKEY, credentials)))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func getBearerToken(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, BEARER) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authorization, BEARER))
+}
+
+func tokenError(err error) error {
+	return helpers.NewError(helpers.ERROR_UNAUTHORIZED, "%s", err.Error())
+}
+
+func sendToken(w http.ResponseWriter, token string, claims auth.Claims) {
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	response := TokenResponse{Token: token, TokenType: strings.TrimSpace(BEARER), ExpiresAt: expiresAt.UTC().Format(time.RFC3339), ExpiresIn: claims.ExpiresAt - claims.IssuedAt}
+	content, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/auth"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+func TestAuthenticateBearerToken(t *testing.T) {
+	key, _ := auth.GenerateKey()
+	tokens, err := auth.NewManager(key, "ocms", time.Minute, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Authenticate(tokens, false, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials, err := GetUserCredentials(r)
+		if err != nil {
+			SendError(w, err)
+			return
+		}
+		w.Write([]byte(credentials.UserName))
+	}))
+	token, claims, _ := tokens.Issue("user1")
+	request := httptest.NewRequest("GET", BCINFO, nil)
+	request.Header.Set("Authorization", BEARER+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "user1" {
+		t.Error("bad identity: ", recorder.Code, recorder.Body.String())
+	}
+
+	tokens.Revoke(claims)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Error("revoked token accepted: ", recorder.Code)
+	}
+
+	request = httptest.NewRequest("GET", BCINFO, nil)
+	request.SetBasicAuth("user1", "pwd1")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Error("basic authentication accepted: ", recorder.Code)
+	}
+}
+
+func TestLoginRefreshLogoutFromAPI(t *testing.T) {
+	credentials, _ := json.Marshal(helpers.UserCredentials{UserName: configuration.Adminusername, EnrollmentSecret: configuration.AdminPwd})
+	token, err := getToken(LOGIN, string(credentials), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ := buildRequestWithToken("GET", httpServerTest.URL+BCINFO, "", token)
+	status, _, err := executeRequest(request)
+	if err != nil || status != http.StatusOK {
+		t.Error("token refused: ", status, err)
+	}
+	refreshed, err := getToken(REFRESH, "", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ = buildRequestWithToken("GET", httpServerTest.URL+BCINFO, "", token)
+	status, _, _ = executeRequest(request)
+	if status != http.StatusUnauthorized {
+		t.Error("refreshed token accepted: ", status)
+	}
+	request, _ = buildRequestWithToken("POST", httpServerTest.URL+LOGOUT, "", refreshed)
+	status, _, _ = executeRequest(request)
+	if status != http.StatusOK {
+		t.Error("bad status: ", status)
+	}
+	request, _ = buildRequestWithToken("GET", httpServerTest.URL+BCINFO, "", refreshed)
+	status, _, _ = executeRequest(request)
+	if status != http.StatusUnauthorized {
+		t.Error("token accepted after logout: ", status)
+	}
+}
+
+func TestLoginWithBadCredentialsFromAPI(t *testing.T) {
+	credentials, _ := json.Marshal(helpers.UserCredentials{UserName: configuration.Adminusername, EnrollmentSecret: "badpassword"})
+	_, err := getToken(LOGIN, string(credentials), "")
+	if err == nil {
+		t.Error("login with bad credentials")
+	}
+}
+
+func getToken(uri, data, token string) (string, error) {
+	var response TokenResponse
+	request, err := buildRequestWithToken("POST", httpServerTest.URL+uri, data, token)
+	if err != nil {
+		return "", err
+	}
+	status, body_bytes, err := executeRequest(request)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", errors.New("bad status")
+	}
+	err = json.Unmarshal(body_bytes, &response)
+	if err != nil {
+		return "", err
+	}
+	if response.Token == "" || response.TokenType != "Bearer" || response.ExpiresIn <= 0 {
+		return "", errors.New("bad response")
+	}
+	return response.Token, nil
+}
+
+func buildRequestWithToken(method, uri, data, token string) (*http.Request, error) {
+	request, err := buildRequest(method, uri, data)
+	if err != nil {
+		return request, err
+	}
+	if token != "" {
+		request.Header.Set("Authorization", BEARER+token)
+	}
+	return request, nil
+}
+
+func TestAuthenticateBasicCredentials(t *testing.T) {
+	statStorePath, err := ioutil.TempDir("", "statstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(statStorePath)
+	writeEnrolledUser(t, statStorePath, "admin", []string{"ocms.role=" + helpers.ROLE_ADMIN})
+	served := false
+	handler := Authenticate(nil, true, statStorePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}))
+	// the name of an enrolled user is not enough, its secret is checked
+	request := httptest.NewRequest("GET", BCINFO, nil)
+	request.SetBasicAuth("admin", "anything")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized || served {
+		t.Error("unchecked basic credentials accepted: ", recorder.Code)
+	}
+}
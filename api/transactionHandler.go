@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/gorilla/mux"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+// commit mode of the transactions of a request
+const COMMITKEY = contextKey("commit")
+
+// query parameter of the commit mode (submit, wait or async)
+const COMMITPARAM = "commit"
+
+// headers of the transaction sent by a request and of its validation code (wait mode)
+const (
+	TRANSACTIONIDHEADER  = "X-Transaction-ID"
+	VALIDATIONCODEHEADER = "X-Validation-Code"
+)
+
+// Commits gives the commit mode of the request (query parameter commit, else defaultMode) to the helpers,
+// the transaction IDs are returned in the headers and an asynchronous transaction is answered 202 with its status URI
+func Commits(defaultMode string, timeout time.Duration, tracker *helpers.TransactionTracker, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mode := r.URL.Query().Get(COMMITPARAM)
+		if mode == "" {
+			mode = defaultMode
+		}
+		if mode == "" {
+			mode = helpers.COMMIT_SUBMIT
+		}
+		if !isCommitMode(mode) {
+			SendError(w, validationError(fmt.Sprintf("%s must be one of: %s", COMMITPARAM, strings.Join(helpers.CommitModes, ", "))))
+			return
+		}
+		commit := &helpers.Commit{Mode: mode, Timeout: timeout, Tracker: tracker}
+		writer := &commitWriter{ResponseWriter: w, commit: commit}
+		next.ServeHTTP(writer, r.WithContext(context.WithValue(r.Context(), COMMITKEY, commit)))
+	})
+}
+
+func isCommitMode(mode string) bool {
+	for _, commitMode := range helpers.CommitModes {
+		if mode == commitMode {
+			return true
+		}
+	}
+	return false
+}
+
+// commitWriter adds the transaction headers before the status is written
+type commitWriter struct {
+	http.ResponseWriter
+	commit		*helpers.Commit
+	wroteHeader	bool
+}
+
+func (cw *commitWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	transactions := cw.commit.Transactions()
+	// the transactions of a commit timeout are sent, they can still be committed (their status is given by txID)
+	if len(transactions) > 0 && status == http.StatusGatewayTimeout {
+		cw.Header().Set(TRANSACTIONIDHEADER, transactionIDs(transactions))
+	}
+	if len(transactions) > 0 && status < http.StatusMultipleChoices {
+		last := transactions[len(transactions)-1]
+		cw.Header().Set(TRANSACTIONIDHEADER, transactionIDs(transactions))
+		switch cw.commit.Mode {
+		case helpers.COMMIT_WAIT:
+			cw.Header().Set(VALIDATIONCODEHEADER, last.ValidationCode)
+		case helpers.COMMIT_ASYNC:
+			if cw.commit.Tracker != nil {
+				cw.Header().Set("Location", TRANSACTIONAPI+"/"+last.TxID+"/status")
+				status = http.StatusAccepted
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func transactionIDs(transactions []helpers.TransactionStatus) string {
+	var txIDs []string
+	for _, transaction := range transactions {
+		txIDs = append(txIDs, transaction.TxID)
+	}
+	return strings.Join(txIDs, ",")
+}
+
+func (cw *commitWriter) Write(content []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(content)
+}
+
+//HTTP Get - /ocms/v2/api/transactions/{txid}/status
+func (a *AppContext) transactionStatus(w http.ResponseWriter, r *http.Request) {
+	txID := mux.Vars(r)["txid"]
+	log.Debug("transactionStatus(txid=" + txID + ") : calling method -")
+	status, tracked := helpers.TransactionStatus{}, false
+	if a.Transactions != nil {
+		status, tracked = a.Transactions.Get(txID)
+	}
+	// an untracked transaction (expired or sent in an other mode) is read in the ledger
+	if !tracked || status.Status == helpers.TX_PENDING {
+		consentHelper := &helpers.ConsentHelper{ChainID:a.ChainID, StatStorePath:a.StatStorePath}
+		err := InitHelper(r, consentHelper)
+		if err != nil {
+			SendError(w, err)
+			return
+		}
+		ledgerStatus, err := consentHelper.TransactionStatus(txID)
+		if err == nil {
+			status = ledgerStatus
+		} else if !tracked || helpers.ErrorCode(err) != helpers.ERROR_NOTFOUND {
+			SendError(w, err)
+			return
+		}
+	}
+	content, err := json.Marshal(status)
+	if err != nil {
+		SendError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/pascallimeux/ocmsV2/helpers"
+)
+
+// handler of a write recording a transaction sent in the commit mode of the request
+func commitTestHandler(tracker *helpers.TransactionTracker) http.Handler {
+	return Commits(helpers.COMMIT_SUBMIT, time.Second, tracker, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commit := r.Context().Value(COMMITKEY).(*helpers.Commit)
+		status := helpers.TransactionStatus{TxID: "tx1", Status: helpers.TX_PENDING}
+		switch commit.Mode {
+		case helpers.COMMIT_WAIT:
+			status = helpers.TransactionStatus{TxID: "tx1", Status: helpers.TX_VALID, ValidationCode: "VALID"}
+		case helpers.COMMIT_ASYNC:
+			tracker.Track("tx1", nil)
+		}
+		commit.Record(status)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(commit.Mode))
+	}))
+}
+
+func TestCommitModes(t *testing.T) {
+	handler := commitTestHandler(helpers.NewTransactionTracker(time.Minute))
+	serve := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", APPSAPI+"/app1/consents"+query, nil))
+		return recorder
+	}
+	recorder := serve("")
+	if recorder.Code != http.StatusCreated || recorder.Body.String() != helpers.COMMIT_SUBMIT || recorder.Header().Get(TRANSACTIONIDHEADER) != "tx1" {
+		t.Error("bad submit response: ", recorder.Code, recorder.Body.String(), recorder.Header())
+	}
+	recorder = serve("?commit=wait")
+	if recorder.Code != http.StatusCreated || recorder.Header().Get(VALIDATIONCODEHEADER) != "VALID" {
+		t.Error("bad wait response: ", recorder.Code, recorder.Header())
+	}
+	recorder = serve("?commit=async")
+	if recorder.Code != http.StatusAccepted || recorder.Header().Get("Location") != TRANSACTIONAPI+"/tx1/status" {
+		t.Error("bad async response: ", recorder.Code, recorder.Header())
+	}
+	recorder = serve("?commit=later")
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Error("unknown commit mode accepted: ", recorder.Code)
+	}
+}
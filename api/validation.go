@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"github.com/gorilla/mux"
+)
+
+// ValidateRequests validates the JSON body of a request against the OpenAPI document before the route is served
+// (the helpers are only initialized for a valid body)
+func ValidateRequests(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			template, err := match.Route.GetPathTemplate()
+			if err == nil {
+				schema := requestBodySchema(r.Method, template)
+				if schema != nil {
+					body, err := ioutil.ReadAll(r.Body)
+					if err != nil {
+						SendError(w, err)
+						return
+					}
+					r.Body = ioutil.NopCloser(bytes.NewReader(body))
+					err = validateBody(body, schema)
+					if err != nil {
+						SendError(w, err)
+						return
+					}
+				}
+			}
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+// schema of the JSON body of an operation, nil if the operation has no JSON body
+func requestBodySchema(method, path string) map[string]interface{} {
+	item, ok := openAPIDocument()["paths"].(map[string]interface{})[path].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	operation, ok := item[strings.ToLower(method)].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	requestBody, ok := operation["requestBody"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	content, ok := requestBody["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return content["schema"].(map[string]interface{})
+}
+
+func validateBody(body []byte, schema map[string]interface{}) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	if err != nil {
+		return validationError("body is not a valid JSON document")
+	}
+	components := openAPIDocument()["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	return validateValue("body", value, schema, components)
+}
+
+func validateValue(path string, value interface{}, schema map[string]interface{}, components map[string]interface{}) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = components[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]interface{})
+	}
+	if value == nil {
+		return nil
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, subSchema := range allOf {
+			err := validateValue(path, value, subSchema.(map[string]interface{}), components)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if enum, ok := schema["enum"].([]string); ok && !isInEnum(value, enum) {
+		return validationError(fmt.Sprintf("%s must be one of: %s", path, strings.Join(enum, ", ")))
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return validationError(path + " must be an object")
+		}
+		return validateObject(path, object, schema, components)
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return validationError(path + " must be an array")
+		}
+		for i, item := range array {
+			err := validateValue(fmt.Sprintf("%s[%d]", path, i), item, schema["items"].(map[string]interface{}), components)
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return validationError(path + " must be a string")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return validationError(path + " must be a boolean")
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			return validationError(path + " must be an integer")
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return validationError(path + " must be a number")
+		}
+	}
+	return nil
+}
+
+func validateObject(path string, object map[string]interface{}, schema map[string]interface{}, components map[string]interface{}) error {
+	if required, ok := schema["required"].([]string); ok {
+		for _, name := range required {
+			if value, ok := object[name]; !ok || value == nil || value == "" {
+				return validationError(path + "." + name + " is mandatory!")
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, value := range object {
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			err := validateValue(path+"."+name, value, property, components)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return validationError(path + "." + name + " is not an attribute")
+			}
+		case map[string]interface{}:
+			err := validateValue(path+"."+name, value, additional, components)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isInEnum(value interface{}, enum []string) bool {
+	for _, allowed := range enum {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
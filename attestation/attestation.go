@@ -0,0 +1,233 @@
+// Package attestation issues and verifies the signed consent decisions delivered by ocms.
+// A data holder can embed this package and check a token offline with the ocms public key.
+// Tokens are compact JWS (JWT) signed with ECDSA P-256 / SHA-256 (ES256).
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	ALGORITHM = "ES256"
+	TYPE      = "JWT"
+)
+
+var (
+	ErrMalformed  = errors.New("attestation: malformed token")
+	ErrAlgorithm  = errors.New("attestation: unsupported algorithm")
+	ErrSignature  = errors.New("attestation: invalid signature")
+	ErrExpired    = errors.New("attestation: token expired")
+	ErrNoKey      = errors.New("attestation: no key found")
+	ErrBadKeyType = errors.New("attestation: key is not an ECDSA P-256 key")
+)
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+// Claims of a positive consent decision.
+type Claims struct {
+	Issuer      string `json:"iss,omitempty"`
+	IssuedAt    int64  `json:"iat"`
+	ExpiresAt   int64  `json:"exp"`
+	AppID       string `json:"app"`
+	OwnerID     string `json:"owner"`
+	ConsumerID  string `json:"consumer"`
+	DataType    string `json:"datatype"`
+	DataAccess  string `json:"access"`
+	ConsentID   string `json:"consentid"`
+	BlockHeight uint64 `json:"height"`
+}
+
+// NewClaims builds the claims of a decision valid for ttl from now.
+func NewClaims(issuer, appID, ownerID, consumerID, dataType, dataAccess, consentID string, blockHeight uint64, ttl time.Duration) Claims {
+	now := time.Now()
+	return Claims{
+		Issuer:      issuer,
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(ttl).Unix(),
+		AppID:       appID,
+		OwnerID:     ownerID,
+		ConsumerID:  consumerID,
+		DataType:    dataType,
+		DataAccess:  dataAccess,
+		ConsentID:   consentID,
+		BlockHeight: blockHeight,
+	}
+}
+
+// Sign returns the compact JWS of the claims signed with key.
+func Sign(claims Claims, key *ecdsa.PrivateKey) (string, error) {
+	if key == nil {
+		return "", ErrNoKey
+	}
+	if key.Curve != elliptic.P256() {
+		return "", ErrBadKeyType
+	}
+	headerBytes, err := json.Marshal(header{Algorithm: ALGORITHM, Type: TYPE})
+	if err != nil {
+		return "", err
+	}
+	claimsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encode(headerBytes) + "." + encode(claimsBytes)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[32-len(rBytes):32], rBytes)
+	copy(signature[64-len(sBytes):], sBytes)
+	return signingInput + "." + encode(signature), nil
+}
+
+// Verify checks the signature and the expiry of the token and returns its claims.
+func Verify(token string, key *ecdsa.PublicKey) (Claims, error) {
+	return VerifyAt(token, key, time.Now())
+}
+
+// VerifyAt checks the signature of the token and that it is not expired at the given instant.
+func VerifyAt(token string, key *ecdsa.PublicKey, now time.Time) (Claims, error) {
+	var claims Claims
+	if key == nil {
+		return claims, ErrNoKey
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrMalformed
+	}
+	headerBytes, err := decode(parts[0])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	var h header
+	if err = json.Unmarshal(headerBytes, &h); err != nil {
+		return claims, ErrMalformed
+	}
+	if h.Algorithm != ALGORITHM {
+		return claims, ErrAlgorithm
+	}
+	signature, err := decode(parts[2])
+	if err != nil || len(signature) != 64 {
+		return claims, ErrSignature
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return claims, ErrSignature
+	}
+	claimsBytes, err := decode(parts[1])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	if err = json.Unmarshal(claimsBytes, &claims); err != nil {
+		return claims, ErrMalformed
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+// LoadPrivateKey reads a PEM encoded ECDSA private key (SEC 1 or PKCS#8).
+func LoadPrivateKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+// ParsePrivateKey decodes a PEM encoded ECDSA private key (SEC 1 or PKCS#8).
+func ParsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoKey
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrBadKeyType
+	}
+	return key, nil
+}
+
+// LoadPublicKey reads a PEM encoded ECDSA public key or certificate.
+func LoadPublicKey(path string) (*ecdsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(data)
+}
+
+// ParsePublicKey decodes a PEM encoded ECDSA public key or certificate.
+func ParsePublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoKey
+	}
+	var parsed interface{}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		parsed = cert.PublicKey
+	} else {
+		var err error
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrBadKeyType
+	}
+	return key, nil
+}
+
+// EncodePublicKey returns the PEM encoding of the public key.
+func EncodePublicKey(key *ecdsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+func encode(data []byte) string {
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(data), "=")
+}
+
+func decode(data string) ([]byte, error) {
+	if m := len(data) % 4; m != 0 {
+		data += strings.Repeat("=", 4-m)
+	}
+	return base64.URLEncoding.DecodeString(data)
+}
@@ -0,0 +1,110 @@
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSignAndVerifyNominal(t *testing.T) {
+	key := newKey(t)
+	claims := NewClaims("ocms", "app1", "owner1", "consumer1", "BP", "R", "consent1", 12, time.Minute)
+	token, err := Sign(claims, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strings.Split(token, ".")) != 3 {
+		t.Error("token is not a compact JWS: ", token)
+	}
+	verified, err := Verify(token, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified != claims {
+		t.Error("claims are different after verification: ", verified, claims)
+	}
+}
+
+func TestVerifyWithAnotherKey(t *testing.T) {
+	token, err := Sign(NewClaims("ocms", "app1", "owner1", "consumer1", "BP", "R", "consent1", 12, time.Minute), newKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Verify(token, &newKey(t).PublicKey)
+	if err != ErrSignature {
+		t.Error(ErrSignature, " expected, but ", err, " received")
+	}
+}
+
+func TestVerifyTamperedClaims(t *testing.T) {
+	key := newKey(t)
+	token, err := Sign(NewClaims("ocms", "app1", "owner1", "consumer1", "BP", "R", "consent1", 12, time.Minute), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Sign(NewClaims("ocms", "app1", "owner1", "consumer2", "BP", "R", "consent1", 12, time.Minute), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	parts[1] = strings.Split(other, ".")[1]
+	_, err = Verify(strings.Join(parts, "."), &key.PublicKey)
+	if err != ErrSignature {
+		t.Error(ErrSignature, " expected, but ", err, " received")
+	}
+}
+
+func TestVerifyExpiredToken(t *testing.T) {
+	key := newKey(t)
+	token, err := Sign(NewClaims("ocms", "app1", "owner1", "consumer1", "BP", "R", "consent1", 12, time.Minute), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = VerifyAt(token, &key.PublicKey, time.Now().Add(2*time.Minute))
+	if err != ErrExpired {
+		t.Error(ErrExpired, " expected, but ", err, " received")
+	}
+}
+
+func TestVerifyMalformedToken(t *testing.T) {
+	_, err := Verify("not.a-token", &newKey(t).PublicKey)
+	if err != ErrMalformed {
+		t.Error(ErrMalformed, " expected, but ", err, " received")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	key := newKey(t)
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPem, err := EncodePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ParsePublicKey(publicPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicKey.X.Cmp(key.X) != 0 || publicKey.Y.Cmp(key.Y) != 0 {
+		t.Error("public key is different after parsing")
+	}
+}
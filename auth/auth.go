@@ -0,0 +1,267 @@
+// Package auth issues and verifies the bearer tokens of the ocms REST API.
+// A token is delivered once the credentials of a user are checked, the enrollment secret is not sent again.
+// Tokens are compact JWS (JWT) signed with HMAC SHA-256 (HS256) and can be refreshed until the end of the login session.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ALGORITHM = "HS256"
+	TYPE      = "JWT"
+	// minimal size of the signing key in bytes
+	KEYSIZE = 32
+)
+
+var (
+	ErrMalformed  = errors.New("auth: malformed token")
+	ErrAlgorithm  = errors.New("auth: unsupported algorithm")
+	ErrSignature  = errors.New("auth: invalid signature")
+	ErrExpired    = errors.New("auth: token expired")
+	ErrRevoked    = errors.New("auth: token revoked")
+	ErrNotRenewed = errors.New("auth: login session expired, login again")
+	ErrKeySize    = errors.New("auth: signing key too short")
+)
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+// Claims of a bearer token.
+type Claims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub"`
+	ID        string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	// time of the login, a token is refreshed until AuthTime + refresh ttl
+	AuthTime int64 `json:"auth_time"`
+}
+
+// revocations saved in the revocation file
+type revocations struct {
+	// token ID -> expiry of the token
+	Tokens map[string]int64 `json:"tokens"`
+	// subject -> tokens issued until this time are revoked
+	Subjects map[string]int64 `json:"subjects"`
+}
+
+// Manager issues, verifies, refreshes and revokes the tokens.
+type Manager struct {
+	Issuer     string
+	TTL        time.Duration
+	RefreshTTL time.Duration
+	key        []byte
+	path       string
+	mutex      sync.Mutex
+	revoked    revocations
+}
+
+// NewManager returns a token manager, the revocations are kept in revocationFile when it is not empty.
+func NewManager(key []byte, issuer string, ttl, refreshTTL time.Duration, revocationFile string) (*Manager, error) {
+	if len(key) < KEYSIZE {
+		return nil, ErrKeySize
+	}
+	m := &Manager{Issuer: issuer, TTL: ttl, RefreshTTL: refreshTTL, key: key, path: revocationFile,
+		revoked: revocations{Tokens: map[string]int64{}, Subjects: map[string]int64{}}}
+	if revocationFile != "" {
+		data, err := ioutil.ReadFile(revocationFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err = json.Unmarshal(data, &m.revoked); err != nil {
+				return nil, err
+			}
+			if m.revoked.Tokens == nil {
+				m.revoked.Tokens = map[string]int64{}
+			}
+			if m.revoked.Subjects == nil {
+				m.revoked.Subjects = map[string]int64{}
+			}
+		}
+	}
+	return m, nil
+}
+
+// Issue returns a new token of the subject, at the login.
+func (m *Manager) Issue(subject string) (string, Claims, error) {
+	now := time.Now()
+	return m.issue(subject, now.Unix(), now)
+}
+
+// Verify checks the signature, the expiry and the revocation of the token and returns its claims.
+func (m *Manager) Verify(token string) (Claims, error) {
+	return m.VerifyAt(token, time.Now())
+}
+
+// VerifyAt checks the token at the given instant.
+func (m *Manager) VerifyAt(token string, now time.Time) (Claims, error) {
+	var claims Claims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrMalformed
+	}
+	headerBytes, err := decode(parts[0])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	var h header
+	if err = json.Unmarshal(headerBytes, &h); err != nil {
+		return claims, ErrMalformed
+	}
+	if h.Algorithm != ALGORITHM {
+		return claims, ErrAlgorithm
+	}
+	signature, err := decode(parts[2])
+	if err != nil || !hmac.Equal(signature, m.sign(parts[0]+"."+parts[1])) {
+		return claims, ErrSignature
+	}
+	claimsBytes, err := decode(parts[1])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	if err = json.Unmarshal(claimsBytes, &claims); err != nil {
+		return claims, ErrMalformed
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return claims, ErrExpired
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.revoked.Tokens[claims.ID]; ok {
+		return claims, ErrRevoked
+	}
+	if until, ok := m.revoked.Subjects[claims.Subject]; ok && claims.IssuedAt <= until {
+		return claims, ErrRevoked
+	}
+	return claims, nil
+}
+
+// Refresh revokes a valid token and returns a new one of the same login session.
+func (m *Manager) Refresh(token string) (string, Claims, error) {
+	claims, err := m.Verify(token)
+	if err != nil {
+		return "", claims, err
+	}
+	now := time.Now()
+	if now.After(time.Unix(claims.AuthTime, 0).Add(m.RefreshTTL)) {
+		return "", claims, ErrNotRenewed
+	}
+	if err = m.Revoke(claims); err != nil {
+		return "", claims, err
+	}
+	return m.issue(claims.Subject, claims.AuthTime, now)
+}
+
+// Revoke revokes a token until its expiry (logout).
+func (m *Manager) Revoke(claims Claims) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.revoked.Tokens[claims.ID] = claims.ExpiresAt
+	return m.save()
+}
+
+// RevokeSubject revokes all the tokens issued to the subject until now (revoked user).
+func (m *Manager) RevokeSubject(subject string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.revoked.Subjects[subject] = time.Now().Unix()
+	return m.save()
+}
+
+func (m *Manager) issue(subject string, authTime int64, now time.Time) (string, Claims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", Claims{}, err
+	}
+	claims := Claims{
+		Issuer:    m.Issuer,
+		Subject:   subject,
+		ID:        hex.EncodeToString(id),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(m.TTL).Unix(),
+		AuthTime:  authTime,
+	}
+	headerBytes, err := json.Marshal(header{Algorithm: ALGORITHM, Type: TYPE})
+	if err != nil {
+		return "", claims, err
+	}
+	claimsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", claims, err
+	}
+	signingInput := encode(headerBytes) + "." + encode(claimsBytes)
+	return signingInput + "." + encode(m.sign(signingInput)), claims, nil
+}
+
+func (m *Manager) sign(signingInput string) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+// must be called with the lock, the expired token revocations are dropped
+func (m *Manager) save() error {
+	now := time.Now().Unix()
+	for id, expiresAt := range m.revoked.Tokens {
+		if expiresAt <= now {
+			delete(m.revoked.Tokens, id)
+		}
+	}
+	if m.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(m.revoked)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(m.path, data, 0600)
+}
+
+// LoadKey reads a signing key, stored in hexadecimal.
+func LoadKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < KEYSIZE {
+		return nil, ErrKeySize
+	}
+	return key, nil
+}
+
+// GenerateKey returns a random signing key, the tokens signed with it are lost at restart.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KEYSIZE)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+func encode(data []byte) string {
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(data), "=")
+}
+
+func decode(data string) ([]byte, error) {
+	if m := len(data) % 4; m != 0 {
+		data += strings.Repeat("=", 4-m)
+	}
+	return base64.URLEncoding.DecodeString(data)
+}
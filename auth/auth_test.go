@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newManager(t *testing.T, revocationFile string) *Manager {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(key, "ocms", time.Minute, time.Hour, revocationFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestIssueAndVerifyNominal(t *testing.T) {
+	m := newManager(t, "")
+	token, claims, err := m.Issue("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strings.Split(token, ".")) != 3 {
+		t.Error("token is not a compact JWS: ", token)
+	}
+	verified, err := m.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified != claims || verified.Subject != "user1" || verified.ExpiresAt-verified.IssuedAt != 60 {
+		t.Error("bad claims: ", verified, claims)
+	}
+}
+
+func TestVerifyWithAnotherKey(t *testing.T) {
+	token, _, err := newManager(t, "").Issue("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newManager(t, "").Verify(token)
+	if err != ErrSignature {
+		t.Error(ErrSignature, " expected, but ", err, " received")
+	}
+}
+
+func TestVerifyExpiredAndMalformedToken(t *testing.T) {
+	m := newManager(t, "")
+	token, _, err := m.Issue("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.VerifyAt(token, time.Now().Add(time.Hour))
+	if err != ErrExpired {
+		t.Error(ErrExpired, " expected, but ", err, " received")
+	}
+	_, err = m.Verify("abc.def")
+	if err != ErrMalformed {
+		t.Error(ErrMalformed, " expected, but ", err, " received")
+	}
+	_, err = NewManager([]byte("short"), "ocms", time.Minute, time.Hour, "")
+	if err != ErrKeySize {
+		t.Error(ErrKeySize, " expected, but ", err, " received")
+	}
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	m := newManager(t, "")
+	token, claims, err := m.Issue("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, refreshedClaims, err := m.Refresh(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshedClaims.AuthTime != claims.AuthTime || refreshedClaims.ID == claims.ID {
+		t.Error("bad refreshed claims: ", refreshedClaims)
+	}
+	_, err = m.Verify(token)
+	if err != ErrRevoked {
+		t.Error("refreshed token still valid: ", err)
+	}
+	err = m.Revoke(refreshedClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = m.Refresh(refreshed)
+	if err != ErrRevoked {
+		t.Error(ErrRevoked, " expected, but ", err, " received")
+	}
+	// the login session is over
+	m.RefreshTTL = 0
+	token, _, _ = m.Issue("user1")
+	time.Sleep(time.Second)
+	_, _, err = m.Refresh(token)
+	if err != ErrNotRenewed {
+		t.Error(ErrNotRenewed, " expected, but ", err, " received")
+	}
+}
+
+func TestRevocationsKeptInFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	revocationFile := filepath.Join(dir, "revoked.json")
+	key, _ := GenerateKey()
+	m, _ := NewManager(key, "ocms", time.Minute, time.Hour, revocationFile)
+	token1, claims1, _ := m.Issue("user1")
+	token2, _, _ := m.Issue("user2")
+	m.Revoke(claims1)
+	m.RevokeSubject("user2")
+
+	restarted, err := NewManager(key, "ocms", time.Minute, time.Hour, revocationFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{token1, token2} {
+		if _, err = restarted.Verify(token); err != ErrRevoked {
+			t.Error(ErrRevoked, " expected, but ", err, " received")
+		}
+	}
+	// a new login after the revocation of the user
+	time.Sleep(time.Second)
+	token3, _, _ := restarted.Issue("user2")
+	if _, err = restarted.Verify(token3); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "auth.key")
+	ioutil.WriteFile(keyFile, []byte(strings.Repeat("ab", KEYSIZE)+"\n"), 0600)
+	key, err := LoadKey(keyFile)
+	if err != nil || len(key) != KEYSIZE {
+		t.Error("bad key: ", key, err)
+	}
+	ioutil.WriteFile(keyFile, []byte("abcd"), 0600)
+	_, err = LoadKey(keyFile)
+	if err != ErrKeySize {
+		t.Error(ErrKeySize, " expected, but ", err, " received")
+	}
+}
@@ -0,0 +1,113 @@
+// Package backup reads and writes the backup files of the consents of an application.
+// A backup file is a versioned json document holding the consent records exported from the ledger,
+// with a SHA-256 checksum of the records to detect a corrupted or modified file before a restore.
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// VERSION is the version of the format of the backup files written by this package.
+const VERSION = 1
+
+var (
+	ErrMalformed = errors.New("backup: malformed file")
+	ErrVersion   = errors.New("backup: unsupported file version")
+	ErrChecksum  = errors.New("backup: checksum mismatch")
+)
+
+// File is the content of a backup file.
+type File struct {
+	Version     int               `json:"version"`
+	AppID       string            `json:"appid"`
+	ChainCodeID string            `json:"chaincodeid"`
+	Created     time.Time         `json:"created"`
+	Count       int               `json:"count"`
+	Checksum    string            `json:"checksum"`
+	Consents    []json.RawMessage `json:"consents"`
+}
+
+// New builds a backup of the consent records of an application.
+func New(appID, chainCodeID string, consents []json.RawMessage) (*File, error) {
+	checksum, err := Checksum(consents)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Version:     VERSION,
+		AppID:       appID,
+		ChainCodeID: chainCodeID,
+		Created:     time.Now().UTC(),
+		Count:       len(consents),
+		Checksum:    checksum,
+		Consents:    consents,
+	}, nil
+}
+
+// Checksum returns the hex SHA-256 of the compacted records, one record per line.
+func Checksum(consents []json.RawMessage) (string, error) {
+	hash := sha256.New()
+	for _, consent := range consents {
+		var compacted bytes.Buffer
+		err := json.Compact(&compacted, consent)
+		if err != nil {
+			return "", ErrMalformed
+		}
+		hash.Write(compacted.Bytes())
+		hash.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Write writes the backup file, the file is written in a temporary file then renamed to never leave a partial backup.
+func Write(path string, file *File) error {
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, content, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Read reads a backup file and verifies its version, its number of records and its checksum.
+func Read(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &File{}
+	err = json.Unmarshal(content, file)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if file.Version < 1 || file.Version > VERSION {
+		return nil, ErrVersion
+	}
+	if file.Count != len(file.Consents) {
+		return nil, ErrChecksum
+	}
+	checksum, err := Checksum(file.Consents)
+	if err != nil {
+		return nil, err
+	}
+	if checksum != file.Checksum {
+		return nil, ErrChecksum
+	}
+	return file, nil
+}
@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newFile(t *testing.T) *File {
+	consents := []json.RawMessage{
+		json.RawMessage(`{"appid":"app1","consentid":"1","state":"active"}`),
+		json.RawMessage(`{"appid":"app1","consentid":"2","state":"unactive"}`),
+	}
+	file, err := New("app1", "consentv2", consents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func tempPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "app1.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestWriteAndReadNominal(t *testing.T) {
+	path, clean := tempPath(t)
+	defer clean()
+	file := newFile(t)
+	err := Write(path, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Version != VERSION || read.AppID != "app1" || read.Count != 2 || read.Checksum != file.Checksum {
+		t.Error("backup is different after read: ", read)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file not removed")
+	}
+}
+
+func TestReadModifiedFile(t *testing.T) {
+	path, clean := tempPath(t)
+	defer clean()
+	err := Write(path, newFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(path)
+	modified := strings.Replace(string(content), `"unactive"`, `"active"`, 1)
+	ioutil.WriteFile(path, []byte(modified), 0600)
+	_, err = Read(path)
+	if err != ErrChecksum {
+		t.Error("checksum error expected, received: ", err)
+	}
+}
+
+func TestReadUnsupportedVersion(t *testing.T) {
+	path, clean := tempPath(t)
+	defer clean()
+	file := newFile(t)
+	file.Version = VERSION + 1
+	err := Write(path, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Read(path)
+	if err != ErrVersion {
+		t.Error("version error expected, received: ", err)
+	}
+}
+
+func TestChecksumIgnoresFormatting(t *testing.T) {
+	compact, _ := Checksum([]json.RawMessage{json.RawMessage(`{"a":1,"b":"x"}`)})
+	indented, _ := Checksum([]json.RawMessage{json.RawMessage("{\n  \"a\": 1,\n  \"b\": \"x\"\n}")})
+	if compact != indented {
+		t.Error("checksum depends on the formatting of the records")
+	}
+}
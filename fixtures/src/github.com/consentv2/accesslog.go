@@ -0,0 +1,155 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"time"
+	"encoding/json"
+)
+
+// =====================================================================================================================
+// Access log constantes
+// =====================================================================================================================
+const (
+	//Chainccode index
+	indexAccess = "app~owner~access"		// to get all data accesses for appID and ownerID
+
+	// Chaincode errors
+	errorLogAccess            = "Log access for consentID:"
+	errorGetAccesses4Owner    = "Get list of accesses for ownerID:"
+	errorConsentNotMatch      = "Consent does not match the access:"
+)
+
+// =====================================================================================================================
+// AppID:      string: id of the client application
+// EventID:    string: id of the access event (transaction id)
+// OwnerID:    string: id of the data owner
+// ConsumerID: string: id of the data consumer who accessed the data
+// DataType:   string: type of the accessed data
+// DataAccess: string: type of data access
+// ConsentID:  string: id of the consent used for the access
+// Timestamp:  date:   timestamp of the transaction recording the access
+// =====================================================================================================================
+type accessEvent struct {
+	AppID 		string     `json:"appid"`
+	EventID      	string     `json:"eventid"`
+	OwnerID       	string     `json:"ownerid"`
+	ConsumerID      string     `json:"consumerid"`
+	DataType      	string     `json:"datatype"`
+	DataAccess      string     `json:"dataaccess"`
+	ConsentID      	string     `json:"consentid"`
+	Timestamp      	time.Time  `json:"timestamp"`
+}
+
+// =====================================================================================================================
+// Record a data access done under a consent
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["logaccess","APPID","OWNERID","CONSUMERID","DATATYPE",
+// 							"DATAACCESS","CONSENTID"]}' -o 127.0.0.1:7050
+// return the eventID
+// =====================================================================================================================
+func (c *ConsentCC)logAccess(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 6 {
+		errStr := errorArgs+" expecting appID, ownerID, consumerID, dataType, dataAccess, consentID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("logAccess(Appid:"+ args[0]+ " Ownerid:"+ args[1]+" Consumerid:"+ args[2]+ " Datatype:"+ args[3]+
+		" Dataaccess:" + args[4]+ " ConsentID:" + args[5] +") : calling method -")
+	event := accessEvent{
+		AppID:      args[0],
+		EventID:    stub.GetTxID(),
+		OwnerID:    args[1],
+		ConsumerID: args[2],
+		DataType:   args[3],
+		DataAccess: args[4],
+		ConsentID:  args[5],
+		Timestamp:  getTxTime(stub),
+	}
+	consent, err := readConsent(stub, event.ConsentID)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	match, err := isAccessGranted(stub, consent, event)
+	if err != nil {
+		return shim.Error(buildError(errorLogAccess+ event.ConsentID))
+	}
+	if !match {
+		logger.Error("Consent does not match: " + event.ConsentID + " for AppID:" + event.AppID + " OwnerID:" + event.OwnerID +
+			" ConsumerID:" + event.ConsumerID + " DataType:" + event.DataType + " DataAccess:" + event.DataAccess)
+		return shim.Error(buildError(errorConsentNotMatch+ event.ConsentID))
+	}
+	accessKey, err := stub.CreateCompositeKey(indexAccess, []string{event.AppID, event.OwnerID, event.EventID})
+	if err != nil {
+		return shim.Error(buildError(errorLogAccess+ event.ConsentID))
+	}
+	eventAsBytes, err := json.Marshal(event)
+	if err != nil {
+		return shim.Error(buildError(errorLogAccess+ event.ConsentID))
+	}
+	err = stub.PutState(accessKey, eventAsBytes)
+	if err != nil {
+		return shim.Error(buildError(errorLogAccess+ event.ConsentID))
+	}
+	return shim.Success([]byte(event.EventID))
+}
+
+// =====================================================================================================================
+// Get the data accesses for an appID and an ownerID (who accessed my data)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getowneraccesses","APPID","OWNERID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getOwnerAccesses(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, ownerID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getOwnerAccesses(Appid:"+ args[0]+ " OwnerID:"+ args[1]+") : calling method -")
+	appID := args[0]
+	ownerID := args[1]
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexAccess, []string{appID, ownerID})
+	if err != nil {
+		return shim.Error(buildError(errorGetAccesses4Owner+ownerID+" appID:"+appID))
+	}
+	defer resultsIterator.Close()
+
+	events := make([]accessEvent, 0)
+	for resultsIterator.HasNext() {
+		_, eventAsBytes, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(buildError(errorGetAccesses4Owner+ownerID+" appID:"+appID))
+		}
+		event := accessEvent{}
+		err = json.Unmarshal(eventAsBytes, &event)
+		if err != nil {
+			logger.Error("Failed to unmarshal access event: " + err.Error())
+			return shim.Error(buildError(errorGetAccesses4Owner+ownerID+" appID:"+appID))
+		}
+		events = append(events, event)
+	}
+	valAsBytes, err := json.Marshal(events)
+	if err != nil {
+		return shim.Success([]byte("[]"))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// isAccessGranted - true if the consent is active and valid at the time of the access, and grants the access to the
+// consumer (directly or by one of its groups) on the data type (or a parent data type)
+// =====================================================================================================================
+func isAccessGranted(stub shim.ChaincodeStubInterface, consent consent, event accessEvent) (bool, error) {
+	if consent.AppID != event.AppID || consent.OwnerID != event.OwnerID || consent.DataAccess != event.DataAccess {
+		return false, nil
+	}
+	if consent.State != ACTIVE || !isValidAt(consent.Dt_begin, consent.Dt_end, event.Timestamp) {
+		return false, nil
+	}
+	consumers, err := getConsumerReferences(stub, event.AppID, event.ConsumerID)
+	if err != nil {
+		return false, err
+	}
+	if !contains(consumers, consent.ConsumerID) {
+		return false, nil
+	}
+	return contains(getDataTypeLineage(stub, event.DataType), consent.DataType), nil
+}
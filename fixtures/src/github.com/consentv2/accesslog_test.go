@@ -0,0 +1,146 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+// =====================================================================================================================
+// Log an access and get it in the owner accesses (nominal case)
+// =====================================================================================================================
+func TestConsentV2_LogAccessNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	consentID := string(res.Payload)
+	res = stub.MockInvoke("2", [][]byte{[]byte("logaccess"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(consentID)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	eventID := string(res.Payload)
+	res = stub.MockInvoke("3", [][]byte{[]byte("getowneraccesses"), []byte(APPID1), []byte(OWNERID1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	events := make([]accessEvent, 0)
+	err := json.Unmarshal(res.Payload, &events)
+	if err != nil {
+		t.Log("getowneraccesses", string(res.Payload))
+		t.FailNow()
+	}
+	if len(events) != 1 {
+		t.Error("1 expected, but ",strconv.Itoa(len(events)), "reveived")
+		t.FailNow()
+	}
+	if events[0].EventID != eventID || events[0].ConsentID != consentID || events[0].ConsumerID != CONSUMERID1 {
+		t.Log("getowneraccesses bad event:", string(res.Payload))
+		t.FailNow()
+	}
+	if events[0].Timestamp.IsZero() {
+		t.Log("getowneraccesses event without timestamp")
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Log an access with an unknown consent --> error
+// =====================================================================================================================
+func TestConsentV2_LogAccessWithBadConsentID(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("logaccess"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte("badconsentid")})
+	if res.Status != shim.ERROR{
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	if !strings.Contains(res.Message, errorConsentNotExist){
+		t.Log("Bad return message, expected:"+errorConsentNotExist+" reveived:"+string(res.Message))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Log an access with a consent of another owner --> error
+// =====================================================================================================================
+func TestConsentV2_LogAccessWithAnotherOwnerID(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	consentID := string(res.Payload)
+	res = stub.MockInvoke("2", [][]byte{[]byte("logaccess"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(consentID)})
+	if res.Status != shim.ERROR{
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	if !strings.Contains(res.Message, errorConsentNotMatch){
+		t.Log("Bad return message, expected:"+errorConsentNotMatch+" reveived:"+string(res.Message))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Log an access not granted by the consent (consumer, data type, data access or revoked consent) --> error
+// =====================================================================================================================
+func TestConsentV2_LogAccessNotGrantedByConsent(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	consentID := string(res.Payload)
+	accesses := [][]string{
+		{CONSUMERID2, DATATYPE1, DATAACCESS1},
+		{CONSUMERID1, DATATYPE2, DATAACCESS1},
+		{CONSUMERID1, DATATYPE1, DATAACCESS2},
+	}
+	for i, access := range accesses {
+		res = stub.MockInvoke(strconv.Itoa(i+2), [][]byte{[]byte("logaccess"), []byte(APPID1), []byte(OWNERID1), []byte(access[0]), []byte(access[1]), []byte(access[2]), []byte(consentID)})
+		if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentNotMatch){
+			t.Log("access ", access, " logged, expected:"+errorConsentNotMatch+" reveived:"+string(res.Message))
+			t.FailNow()
+		}
+	}
+	stub.MockInvoke("5", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte(consentID)})
+	res = stub.MockInvoke("6", [][]byte{[]byte("logaccess"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(consentID)})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentNotMatch){
+		t.Log("access logged on a revoked consent, expected:"+errorConsentNotMatch+" reveived:"+string(res.Message))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Get accesses for an owner without access --> empty list
+// =====================================================================================================================
+func TestConsentV2_GetOwnerAccessesEmptyList(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	consentID := string(res.Payload)
+	stub.MockInvoke("2", [][]byte{[]byte("logaccess"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(consentID)})
+	res = stub.MockInvoke("3", [][]byte{[]byte("getowneraccesses"), []byte(APPID1), []byte(OWNERID2)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	events := make([]accessEvent, 0)
+	err := json.Unmarshal(res.Payload, &events)
+	if err != nil {
+		t.Log("getowneraccesses", string(res.Payload))
+		t.FailNow()
+	}
+	if len(events) != 0 {
+		t.Error("empty list expected, but ",strconv.Itoa(len(events)), "reveived")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Backup constantes
+// =====================================================================================================================
+const (
+	MAX_EXPORT_PAGE_SIZE = 500		// max number of consents exported in a page
+
+	// Chaincode errors
+	errorExportConsents       = "Export consents for appID:"
+	errorImportConsents       = "Import consents for appID:"
+	errorBookmark             = "Bookmark is not valid:"
+	errorConsentExist         = "Consent already exists:"
+)
+
+// =====================================================================================================================
+// AppID:    string: id of the client application
+// Consents: list:   consent records of the page (the indexes are rebuilt from the records on import)
+// Bookmark: string: opaque position to give to get the next page
+// HasMore:  bool:   true when other consents follow this page
+// =====================================================================================================================
+type exportPage struct {
+	AppID 		string     `json:"appid"`
+	Consents	[]consent  `json:"consents"`
+	Bookmark	string     `json:"bookmark,omitempty"`
+	HasMore		bool       `json:"hasmore"`
+}
+
+// =====================================================================================================================
+// Export a page of the consents of an appID (all states)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["exportconsents","APPID","PAGESIZE","BOOKMARK"]}'
+// 	-o 127.0.0.1:7050
+// the BOOKMARK argument is empty for the first page, then the bookmark of the previous page
+// =====================================================================================================================
+func (c *ConsentCC)exportConsents(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, pageSize, bookmark!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("exportConsents(Appid:"+ args[0]+ " PageSize:"+ args[1]+ " Bookmark:"+ args[2]+") : calling method -")
+	pageSize, err := strconv.Atoi(args[1])
+	if err != nil || pageSize <= 0 || pageSize > MAX_EXPORT_PAGE_SIZE {
+		return shim.Error(buildError(errorArgs+" pageSize must be between 1 and "+ strconv.Itoa(MAX_EXPORT_PAGE_SIZE)))
+	}
+	prefix, err := stub.CreateCompositeKey(indexApp, []string{args[0]})
+	if err != nil {
+		return shim.Error(buildError(errorExportConsents+ args[0]))
+	}
+	// the keys are sorted, the next page starts at the last key exported
+	after := prefix
+	if args[2] != "" {
+		afterAsBytes, err := base64.URLEncoding.DecodeString(args[2])
+		if err != nil || !strings.HasPrefix(string(afterAsBytes), prefix) {
+			return shim.Error(buildError(errorBookmark+ args[2]))
+		}
+		after = string(afterAsBytes)
+	}
+	resultsIterator, err := stub.GetStateByRange(after, prefix+ string(utf8.MaxRune))
+	if err != nil {
+		return shim.Error(buildError(errorExportConsents+ args[0]))
+	}
+	defer resultsIterator.Close()
+
+	page := exportPage{AppID: args[0], Consents: []consent{}}
+	for resultsIterator.HasNext() {
+		indexKey, _, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(buildError(errorExportConsents+ args[0]))
+		}
+		if indexKey == after {
+			continue
+		}
+		if len(page.Consents) == pageSize {
+			page.HasMore = true
+			break
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(indexKey)
+		if err != nil {
+			return shim.Error(buildError(errorExportConsents+ args[0]))
+		}
+		record, err := readConsent(stub, compositeKeyParts[len(compositeKeyParts) - 1])
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		page.Consents = append(page.Consents, record)
+		page.Bookmark = base64.URLEncoding.EncodeToString([]byte(indexKey))
+	}
+	valAsBytes, err := json.Marshal(page)
+	if err != nil {
+		return shim.Error(buildError(errorExportConsents+ args[0]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Import consents of an appID exported by exportconsents, the consent IDs are preserved and the indexes rebuilt
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["importconsents","APPID","[CONSENTS]"]}' -o 127.0.0.1:7050
+// the consents already imported with the same record are skipped (a failed restore can be run again)
+// return the number of imported consents
+// =====================================================================================================================
+func (c *ConsentCC)importConsents(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, consents!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("importConsents(Appid:"+ args[0]+") : calling method -")
+	var records []json.RawMessage
+	err := json.Unmarshal([]byte(args[1]), &records)
+	if err != nil {
+		return shim.Error(buildError(errorImportConsents+ args[0]))
+	}
+	// the records of an older backup are upgraded to the current schema version
+	consents := make([]consent, 0, len(records))
+	for i, raw := range records {
+		record, _, err := decodeConsent("#"+ strconv.Itoa(i+1), raw)
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		consents = append(consents, record)
+	}
+	delta := newStats(args[0])
+	imported := 0
+	for _, record := range consents {
+		if record.AppID != args[0] || record.ConsentID == "" {
+			return shim.Error(buildError(errorImportConsents+ args[0]+ " bad consent:"+ record.ConsentID))
+		}
+		existing, err := stub.GetState(record.ConsentID)
+		if err != nil {
+			return shim.Error(buildError(errorImportConsents+ args[0]))
+		}
+		if existing != nil {
+			// a record already imported by a previous run of the restore is skipped, another record is a conflict
+			if !isSameRecord(record, existing) {
+				return shim.Error(buildError(errorConsentExist+ record.ConsentID))
+			}
+			continue
+		}
+		err = putConsent(stub, record)
+		if err != nil {
+			return shim.Error(buildError(errorImportConsents+ args[0]))
+		}
+		err = createIndex(stub, record)
+		if err != nil {
+			return shim.Error(buildError(errorImportConsents+ args[0]))
+		}
+		delta.countCreatedConsent(record)
+		if record.State != ACTIVE {
+			delta.countRevokedConsent(record)
+		}
+		imported++
+	}
+	if imported > 0 {
+		err = putStats(stub, delta)
+		if err != nil {
+			return shim.Error(buildError(errorUpdateStats+ args[0]))
+		}
+	}
+	return shim.Success([]byte(strconv.Itoa(imported)))
+}
+
+// =====================================================================================================================
+// isSameRecord - true if the stored consent is the imported record (both at the current schema version)
+// =====================================================================================================================
+func isSameRecord(record consent, existing []byte) bool {
+	stored, _, err := decodeConsent(record.ConsentID, existing)
+	if err != nil {
+		return false
+	}
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return false
+	}
+	storedAsBytes, err := json.Marshal(stored)
+	if err != nil {
+		return false
+	}
+	return string(recordAsBytes) == string(storedAsBytes)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+// =====================================================================================================================
+// Export the consents of an appID page by page and import them in another ledger (nominal case)
+// =====================================================================================================================
+func TestConsentV2_ExportImportNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE2), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID2), []byte(DATATYPE2), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("4", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID2), []byte(CONSUMERID2), []byte(DATATYPE2), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("5", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("2")})
+
+	exported := []consent{}
+	bookmark := ""
+	for i := 0; i < 3; i++ {
+		res := stub.MockInvoke("6", [][]byte{[]byte("exportconsents"), []byte(APPID1), []byte("2"), []byte(bookmark)})
+		if res.Status != shim.OK {
+			t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+			t.Log("response: "+ string(res.Message))
+			t.FailNow()
+		}
+		page := exportPage{}
+		json.Unmarshal(res.Payload, &page)
+		exported = append(exported, page.Consents...)
+		bookmark = page.Bookmark
+		if !page.HasMore {
+			break
+		}
+	}
+	if len(exported) != 3 {
+		t.Log("bad number of exported consents reveived: "+ strconv.Itoa(len(exported)))
+		t.FailNow()
+	}
+
+	restored := shim.NewMockStub("consentv2", new(ConsentCC))
+	restored.MockInit("0", nil)
+	consentsAsBytes, _ := json.Marshal(exported)
+	res := restored.MockInvoke("1", [][]byte{[]byte("importconsents"), []byte(APPID1), consentsAsBytes})
+	if res.Status != shim.OK || string(res.Payload) != "3" {
+		t.Log("bad import received: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+	res = restored.MockInvoke("2", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("3")})
+	if res.Status != shim.OK {
+		t.Log("consent 3 not restored with its ID: "+ res.Message)
+		t.FailNow()
+	}
+	res = restored.MockInvoke("3", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if string(res.Payload) != AUTHORIZED {
+		t.Log("indexes not rebuilt, bad consent status reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = restored.MockInvoke("4", [][]byte{[]byte("getstats"), []byte(APPID1)})
+	appStats := stats{}
+	json.Unmarshal(res.Payload, &appStats)
+	if appStats.Active != 2 || appStats.Revoked != 1 {
+		t.Log("bad counters after import reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = restored.MockInvoke("5", [][]byte{[]byte("importconsents"), []byte(APPID1), consentsAsBytes})
+	if res.Status != shim.OK || string(res.Payload) != "0" {
+		t.Log("bad second import of the same consents received: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+	res = restored.MockInvoke("6", [][]byte{[]byte("getstats"), []byte(APPID1)})
+	json.Unmarshal(res.Payload, &appStats)
+	if appStats.Active != 2 || appStats.Revoked != 1 {
+		t.Log("bad counters after a second import reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	exported[0].ConsumerID = CONSUMERID2
+	consentsAsBytes, _ = json.Marshal(exported)
+	res = restored.MockInvoke("7", [][]byte{[]byte("importconsents"), []byte(APPID1), consentsAsBytes})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentExist+ exported[0].ConsentID) {
+		t.Log("bad response for a conflicting consent reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Import consents of another appID or export with a bookmark of another appID
+// =====================================================================================================================
+func TestConsentV2_ExportImportOtherAppID(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res := stub.MockInvoke("2", [][]byte{[]byte("exportconsents"), []byte(APPID1), []byte("10"), []byte("")})
+	page := exportPage{}
+	json.Unmarshal(res.Payload, &page)
+	res = stub.MockInvoke("3", [][]byte{[]byte("exportconsents"), []byte(APPID2), []byte("10"), []byte(page.Bookmark)})
+	if res.Status == shim.OK {
+		t.Log("bad status received for a bookmark of another appID, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	page.Consents[0].ConsentID = "imported"
+	consentsAsBytes, _ := json.Marshal(page.Consents)
+	res = stub.MockInvoke("4", [][]byte{[]byte("importconsents"), []byte(APPID2), consentsAsBytes})
+	if res.Status == shim.OK {
+		t.Log("bad status received for consents of another appID, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
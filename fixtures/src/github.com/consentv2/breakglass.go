@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"github.com/consentv2/certattr"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"time"
+	"encoding/json"
+)
+
+// =====================================================================================================================
+// Break-glass constantes
+// =====================================================================================================================
+const (
+	EMERGENCY_ATTRIBUTE    = "emergency"
+	REVIEWER_ATTRIBUTE     = "reviewer"
+	MAX_BREAKGLASS_PERIOD  = 72 * time.Hour
+
+	//Chainccode index
+	indexBreakGlass        = "app~breakglass"			// to get a break-glass event
+	indexBreakGlassAccess  = "app~bg~owner~consumer~type"	// to check if a break-glass access is open
+	indexBreakGlassPending = "app~bg~pending"			// to get break-glass events pending review
+
+	// Chaincode errors
+	errorBreakGlass           = "Break-glass access!"
+	errorNotEmergency         = "Identity without emergency attribute:"
+	errorReason               = "A justification is mandatory!"
+	errorDuration             = "Duration not valid (max 72h):"
+	errorBreakGlassNotExist   = "Break-glass event does not exist:"
+	errorGetBreakGlass        = "Get break-glass events for appID:"
+	errorAckBreakGlass        = "Acknowledge break-glass event:"
+	errorNotReviewer          = "Identity without reviewer attribute:"
+	errorSelfReview           = "Break-glass event can not be acknowledged by its requester:"
+)
+
+// =====================================================================================================================
+// AppID:      string: id of the client application
+// EventID:    string: id of the break-glass event (transaction id)
+// OwnerID:    string: id of the data owner
+// ConsumerID: string: id of the data consumer granted in emergency
+// DataType:   string: type of data granted
+// Reason:     string: justification of the emergency access
+// Requester:  string: common name of the identity who opened the access
+// Dt_begin:   date:   starting time of the access
+// Dt_end:     date:   ending time of the access
+// Reviewed:   bool:   true when the event has been acknowledged
+// Reviewer:   string: common name of the identity who acknowledged the event
+// ReviewedAt: date:   time of the acknowledgment
+// =====================================================================================================================
+type breakGlass struct {
+	AppID 		string     `json:"appid"`
+	EventID      	string     `json:"eventid"`
+	OwnerID       	string     `json:"ownerid"`
+	ConsumerID      string     `json:"consumerid"`
+	DataType      	string     `json:"datatype"`
+	Reason      	string     `json:"reason"`
+	Requester      	string     `json:"requester"`
+	Dt_begin      	time.Time  `json:"dtbegin"`
+	Dt_end       	time.Time  `json:"dtend"`
+	Reviewed       	bool       `json:"reviewed"`
+	Reviewer       	string     `json:"reviewer,omitempty"`
+	ReviewedAt     	*time.Time `json:"reviewedat,omitempty"`
+}
+
+// =====================================================================================================================
+// Open a time-boxed emergency access (only for identities with the emergency attribute)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["breakglass","APPID","OWNERID","CONSUMERID","DATATYPE",
+// 							"REASON","DURATION"]}' -o 127.0.0.1:7050
+// the duration format is a go duration (ex: 4h, 90m)
+// return the eventID
+// =====================================================================================================================
+func (c *ConsentCC)breakGlass(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 6 {
+		errStr := errorArgs+" expecting appID, ownerID, consumerID, dataType, reason, duration!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("breakGlass(Appid:"+ args[0]+ " Ownerid:"+ args[1]+" Consumerid:"+ args[2]+ " Datatype:"+ args[3]+
+		" Duration:" + args[5] +") : calling method -")
+	cert, err := getCreatorCertificate(stub)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if !certattr.Has(cert, EMERGENCY_ATTRIBUTE, "true") {
+		return shim.Error(buildError(errorNotEmergency+ cert.Subject.CommonName))
+	}
+	if args[4] == "" {
+		return shim.Error(buildError(errorReason))
+	}
+	duration, err := time.ParseDuration(args[5])
+	if err != nil || duration <= 0 || duration > MAX_BREAKGLASS_PERIOD {
+		return shim.Error(buildError(errorDuration+ args[5]))
+	}
+	now := getTxTime(stub)
+	event := breakGlass{
+		AppID:      args[0],
+		EventID:    stub.GetTxID(),
+		OwnerID:    args[1],
+		ConsumerID: args[2],
+		DataType:   args[3],
+		Reason:     args[4],
+		Requester:  cert.Subject.CommonName,
+		Dt_begin:   now,
+		Dt_end:     now.Add(duration),
+	}
+	err = putBreakGlass(stub, event)
+	if err != nil {
+		return shim.Error(buildError(errorBreakGlass))
+	}
+	accessIndex, err := stub.CreateCompositeKey(indexBreakGlassAccess, []string{event.AppID, event.OwnerID,
+		event.ConsumerID, event.DataType, event.EventID})
+	if err != nil {
+		return shim.Error(buildError(errorBreakGlass))
+	}
+	stub.PutState(accessIndex, []byte{0x00})
+	pendingIndex, err := stub.CreateCompositeKey(indexBreakGlassPending, []string{event.AppID, event.EventID})
+	if err != nil {
+		return shim.Error(buildError(errorBreakGlass))
+	}
+	stub.PutState(pendingIndex, []byte{0x00})
+	return shim.Success([]byte(event.EventID))
+}
+
+// =====================================================================================================================
+// Get the break-glass events pending review for an appID
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getpendingbreakglass","APPID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getPendingBreakGlass(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		errStr := errorArgs+" Expecting appID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getPendingBreakGlass(Appid:"+ args[0]+") : calling method -")
+	appID := args[0]
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexBreakGlassPending, []string{appID})
+	if err != nil {
+		return shim.Error(buildError(errorGetBreakGlass+ appID))
+	}
+	defer resultsIterator.Close()
+
+	events := make([]breakGlass, 0)
+	for resultsIterator.HasNext() {
+		indexKey, _, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(buildError(errorGetBreakGlass+ appID))
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(indexKey)
+		if err != nil {
+			return shim.Error(buildError(errorGetBreakGlass+ appID))
+		}
+		event, err := readBreakGlass(stub, appID, compositeKeyParts[len(compositeKeyParts) - 1])
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		events = append(events, event)
+	}
+	valAsBytes, err := json.Marshal(events)
+	if err != nil {
+		return shim.Success([]byte("[]"))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Acknowledge the review of a break-glass event (only for identities with the reviewer attribute, the requester of
+// the event can not review it)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["ackbreakglass","APPID","EVENTID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)ackBreakGlass(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, eventID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("ackBreakGlass(Appid:"+ args[0]+ " EventID:"+ args[1]+") : calling method -")
+	appID := args[0]
+	eventID := args[1]
+	cert, err := getCreatorCertificate(stub)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if !certattr.Has(cert, REVIEWER_ATTRIBUTE, "true") {
+		return shim.Error(buildError(errorNotReviewer+ cert.Subject.CommonName))
+	}
+	event, err := readBreakGlass(stub, appID, eventID)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if event.Requester == cert.Subject.CommonName {
+		return shim.Error(buildError(errorSelfReview+ eventID))
+	}
+	if !event.Reviewed {
+		reviewedAt := getTxTime(stub)
+		event.Reviewed = true
+		event.Reviewer = cert.Subject.CommonName
+		event.ReviewedAt = &reviewedAt
+		err = putBreakGlass(stub, event)
+		if err != nil {
+			return shim.Error(buildError(errorAckBreakGlass+ eventID))
+		}
+		pendingIndex, err := stub.CreateCompositeKey(indexBreakGlassPending, []string{appID, eventID})
+		if err != nil {
+			return shim.Error(buildError(errorAckBreakGlass+ eventID))
+		}
+		stub.DelState(pendingIndex)
+	}
+	valAsBytes, err := json.Marshal(event)
+	if err != nil {
+		return shim.Error(buildError(errorAckBreakGlass+ eventID))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// getOpenBreakGlass - return a break-glass event open now for the parameters (nil if none)
+// =====================================================================================================================
+func getOpenBreakGlass(stub shim.ChaincodeStubInterface, appID, ownerID, consumerID, dataType string) (*breakGlass, error) {
+	logger.Debug("getOpenBreakGlass() : calling method -")
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexBreakGlassAccess, []string{appID, ownerID,
+		consumerID, dataType})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	now := getTxTime(stub)
+	for resultsIterator.HasNext() {
+		indexKey, _, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(indexKey)
+		if err != nil {
+			return nil, err
+		}
+		event, err := readBreakGlass(stub, appID, compositeKeyParts[len(compositeKeyParts) - 1])
+		if err != nil {
+			return nil, err
+		}
+		if !now.Before(event.Dt_begin) && now.Before(event.Dt_end) {
+			return &event, nil
+		}
+	}
+	return nil, nil
+}
+
+// =====================================================================================================================
+// readBreakGlass - read and decode a break-glass event
+// =====================================================================================================================
+func readBreakGlass(stub shim.ChaincodeStubInterface, appID, eventID string) (breakGlass, error) {
+	var event breakGlass
+	eventKey, err := stub.CreateCompositeKey(indexBreakGlass, []string{appID, eventID})
+	if err != nil {
+		return event, err
+	}
+	valAsBytes, err := stub.GetState(eventKey)
+	if err != nil {
+		return event, err
+	} else if valAsBytes == nil {
+		return event, errors.New(errorBreakGlassNotExist+ eventID)
+	}
+	err = json.Unmarshal(valAsBytes, &event)
+	return event, err
+}
+
+// =====================================================================================================================
+// putBreakGlass - write a break-glass event
+// =====================================================================================================================
+func putBreakGlass(stub shim.ChaincodeStubInterface, event breakGlass) error {
+	eventKey, err := stub.CreateCompositeKey(indexBreakGlass, []string{event.AppID, event.EventID})
+	if err != nil {
+		return err
+	}
+	valAsBytes, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(eventKey, valAsBytes)
+}
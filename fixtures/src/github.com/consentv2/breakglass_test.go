@@ -0,0 +1,184 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	EMERGENCYDOCTOR = "doctor1"
+	REASON = "patient unconscious"
+)
+
+var emergencyAttrs = map[string]string{EMERGENCY_ATTRIBUTE: "true"}
+var reviewerAttrs = map[string]string{REVIEWER_ATTRIBUTE: "true"}
+
+// =====================================================================================================================
+// Open a break-glass access and check that isconsent honors it (nominal case)
+// =====================================================================================================================
+func TestConsentV2_BreakGlassNominal(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	res := stub.mockInvokeAs("1", EMERGENCYDOCTOR, emergencyAttrs, [][]byte{[]byte("breakglass"), []byte(APPID1),
+		[]byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(REASON), []byte("4h")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	eventID := string(res.Payload)
+	if eventID != "1" {
+		t.Log("bad eventID received, expected: 1 received:"+eventID)
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("2", CONSUMERID1, nil, [][]byte{[]byte("checkconsent"), []byte(APPID1), []byte(OWNERID1),
+		[]byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	result := decision{}
+	json.Unmarshal(res.Payload, &result)
+	if result.Consent != AUTHORIZED || result.BreakGlassID != eventID || result.ConsentID != "" {
+		t.Log("bad decision reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("3", CONSUMERID1, nil, [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1),
+		[]byte(CONSUMERID1), []byte(DATATYPE2), []byte(DATAACCESS1)})
+	if string(res.Payload) != NOT_AUTHORIZED {
+		t.Log("bad response for another datatype, expected: "+NOT_AUTHORIZED+" reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Open a break-glass access without the emergency attribute
+// =====================================================================================================================
+func TestConsentV2_BreakGlassWithoutEmergencyAttribute(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	res := stub.mockInvokeAs("1", CONSUMERID1, map[string]string{EMERGENCY_ATTRIBUTE: "false"}, [][]byte{
+		[]byte("breakglass"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(REASON),
+		[]byte("4h")})
+	if res.Status != shim.ERROR {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	if !strings.Contains(res.Message, errorNotEmergency) {
+		t.Log("bad message reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Open a break-glass access with a bad duration
+// =====================================================================================================================
+func TestConsentV2_BreakGlassWithBadDuration(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	for _, duration := range []string{"", "-1h", "100h", "tomorrow"} {
+		res := stub.mockInvokeAs("1", EMERGENCYDOCTOR, emergencyAttrs, [][]byte{[]byte("breakglass"), []byte(APPID1),
+			[]byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(REASON), []byte(duration)})
+		if res.Status != shim.ERROR {
+			t.Log("bad status received for duration "+duration+", expected: 500 received:"+
+				strconv.FormatInt(int64(res.Status), 10))
+			t.FailNow()
+		}
+	}
+}
+
+// =====================================================================================================================
+// Open a break-glass access without reason
+// =====================================================================================================================
+func TestConsentV2_BreakGlassWithoutReason(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	res := stub.mockInvokeAs("1", EMERGENCYDOCTOR, emergencyAttrs, [][]byte{[]byte("breakglass"), []byte(APPID1),
+		[]byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(""), []byte("4h")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorReason) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// List the break-glass events pending review and acknowledge one
+// =====================================================================================================================
+func TestConsentV2_AckBreakGlassNominal(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	stub.mockInvokeAs("1", EMERGENCYDOCTOR, emergencyAttrs, [][]byte{[]byte("breakglass"), []byte(APPID1),
+		[]byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(REASON), []byte("1h")})
+	stub.mockInvokeAs("2", EMERGENCYDOCTOR, emergencyAttrs, [][]byte{[]byte("breakglass"), []byte(APPID1),
+		[]byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(REASON), []byte("1h")})
+	res := stub.mockInvokeAs("3", "auditor1", nil, [][]byte{[]byte("getpendingbreakglass"), []byte(APPID1)})
+	events := []breakGlass{}
+	json.Unmarshal(res.Payload, &events)
+	if len(events) != 2 {
+		t.Log("bad number of pending events, expected: 2 reveived: "+ strconv.Itoa(len(events)))
+		t.FailNow()
+	}
+	if events[0].Requester != EMERGENCYDOCTOR || events[0].Reason != REASON {
+		t.Log("bad pending event reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("4", "auditor1", reviewerAttrs, [][]byte{[]byte("ackbreakglass"), []byte(APPID1), []byte("1")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	event := breakGlass{}
+	json.Unmarshal(res.Payload, &event)
+	if !event.Reviewed || event.Reviewer != "auditor1" {
+		t.Log("bad acknowledged event reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("5", "auditor1", nil, [][]byte{[]byte("getpendingbreakglass"), []byte(APPID1)})
+	events = []breakGlass{}
+	json.Unmarshal(res.Payload, &events)
+	if len(events) != 1 || events[0].EventID != "2" {
+		t.Log("bad pending events after acknowledgment: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Acknowledge an unknown break-glass event
+// =====================================================================================================================
+func TestConsentV2_AckBreakGlassWithBadEventID(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	res := stub.mockInvokeAs("1", "auditor1", reviewerAttrs, [][]byte{[]byte("ackbreakglass"), []byte(APPID1), []byte("XXX")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorBreakGlassNotExist) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Acknowledge a break-glass event without the reviewer attribute or by its requester --> error
+// =====================================================================================================================
+func TestConsentV2_AckBreakGlassWithoutReviewer(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	requesterAttrs := map[string]string{EMERGENCY_ATTRIBUTE: "true", REVIEWER_ATTRIBUTE: "true"}
+	stub.mockInvokeAs("1", EMERGENCYDOCTOR, requesterAttrs, [][]byte{[]byte("breakglass"), []byte(APPID1),
+		[]byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(REASON), []byte("1h")})
+	res := stub.mockInvokeAs("2", "auditor1", map[string]string{REVIEWER_ATTRIBUTE: "false"}, [][]byte{
+		[]byte("ackbreakglass"), []byte(APPID1), []byte("1")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorNotReviewer) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("3", EMERGENCYDOCTOR, requesterAttrs, [][]byte{[]byte("ackbreakglass"), []byte(APPID1),
+		[]byte("1")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorSelfReview) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("4", "auditor1", nil, [][]byte{[]byte("getpendingbreakglass"), []byte(APPID1)})
+	events := []breakGlass{}
+	json.Unmarshal(res.Payload, &events)
+	if len(events) != 1 {
+		t.Log("bad pending events, expected: 1 reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
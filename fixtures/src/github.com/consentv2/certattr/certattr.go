@@ -0,0 +1,60 @@
+// Package certattr reads the attributes of the enrollment certificates, it is shared by the chaincode and the API
+// (vendored by the API) so that an identity has the same attributes on both sides.
+// An attribute is given by the fabric-ca attributes extension ({"attrs":{"name":"value"}}, comma separated values)
+// or by an OU name=value of the subject.
+package certattr
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"strings"
+)
+
+// OID of the attributes extension added by fabric-ca in the enrollment certificates
+var OID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Attributes is the value of the attributes extension (name --> value)
+type Attributes struct {
+	Attrs	map[string]string	`json:"attrs"`
+}
+
+// Values returns the values of the attribute name carried by the certificate
+func Values(cert *x509.Certificate, name string) []string {
+	values := []string{}
+	for _, extension := range cert.Extensions {
+		if !extension.Id.Equal(OID) {
+			continue
+		}
+		attrs := Attributes{}
+		if err := json.Unmarshal(extension.Value, &attrs); err == nil && attrs.Attrs[name] != "" {
+			values = append(values, split(attrs.Attrs[name])...)
+		}
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if strings.HasPrefix(ou, name+"=") {
+			values = append(values, split(strings.TrimPrefix(ou, name+"="))...)
+		}
+	}
+	return values
+}
+
+// Has returns true when the certificate carries the attribute name with the value
+func Has(cert *x509.Certificate, name, value string) bool {
+	for _, v := range Values(cert, name) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func split(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,50 @@
+package certattr
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newCertificate(t *testing.T, ous []string, attrs map[string]string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "user1", OrganizationalUnit: ous},
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	if attrs != nil {
+		value, _ := json.Marshal(Attributes{Attrs: attrs})
+		template.ExtraExtensions = []pkix.Extension{{Id: OID, Value: value}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestValues(t *testing.T) {
+	cert := newCertificate(t, []string{"admin", "ocms.role=app", "emergency=true"}, map[string]string{"ocms.role": "auditor, owner", "reviewer": "true"})
+	values := Values(cert, "ocms.role")
+	if len(values) != 3 || values[0] != "auditor" || values[1] != "owner" || values[2] != "app" {
+		t.Error("bad values: ", values)
+	}
+	if !Has(cert, "emergency", "true") || !Has(cert, "reviewer", "true") {
+		t.Error("attribute not found")
+	}
+	// a bare OU is not an attribute
+	if Has(cert, "ocms.role", "admin") || len(Values(cert, "admin")) != 0 {
+		t.Error("bare OU read as an attribute")
+	}
+}
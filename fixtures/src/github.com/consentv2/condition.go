@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// =====================================================================================================================
+// Condition constantes
+// =====================================================================================================================
+const (
+	// Condition operators
+	OP_EQ    = "eq"		// value equal
+	OP_NE    = "ne"		// value not equal
+	OP_IN    = "in"		// value in a comma separated list
+	OP_NOTIN = "notin"	// value not in a comma separated list
+	OP_LT    = "lt"		// lower than (numeric when both values are numbers, lexical otherwise)
+	OP_LE    = "le"		// lower or equal
+	OP_GT    = "gt"		// greater than
+	OP_GE    = "ge"		// greater or equal
+
+	// Chaincode errors
+	errorCondition            = "Condition is not valid:"
+	errorContext              = "Context is not valid (json object of strings expected)!"
+)
+
+// =====================================================================================================================
+// Attribute: string: name of the attribute of the context (country, study, hour...)
+// Operator:  string: eq, ne, in, notin, lt, le, gt, ge
+// Value:     string: value to compare with the attribute of the context (comma separated list for in and notin)
+// the context is given by the caller of isconsent, the evaluation does not depend on the peer (clock, state...)
+// =====================================================================================================================
+type condition struct {
+	Attribute	string     `json:"attribute"`
+	Operator	string     `json:"operator"`
+	Value		string     `json:"value"`
+}
+
+// =====================================================================================================================
+// checkConditions - verify the attributes and the operators of the conditions of a consent
+// =====================================================================================================================
+func checkConditions(conditions []condition) error {
+	for _, cond := range conditions {
+		if cond.Attribute == "" {
+			return errors.New(errorCondition+ " missing attribute")
+		}
+		switch cond.Operator {
+		case OP_EQ, OP_NE, OP_IN, OP_NOTIN, OP_LT, OP_LE, OP_GT, OP_GE:
+		default:
+			return errors.New(errorCondition+ " "+ cond.Attribute+ " "+ cond.Operator)
+		}
+	}
+	return nil
+}
+
+// =====================================================================================================================
+// parseContext - decode the optional json context of isconsent and checkconsent
+// =====================================================================================================================
+func parseContext(args []string, index int) (map[string]string, error) {
+	context := map[string]string{}
+	if len(args) <= index || args[index] == "" {
+		return context, nil
+	}
+	err := json.Unmarshal([]byte(args[index]), &context)
+	if err != nil {
+		return nil, errors.New(errorContext)
+	}
+	return context, nil
+}
+
+// =====================================================================================================================
+// getUnmetConditions - conditions not satisfied by the context (a missing attribute does not satisfy a condition)
+// =====================================================================================================================
+func getUnmetConditions(conditions []condition, context map[string]string) []condition {
+	unmet := make([]condition, 0)
+	for _, cond := range conditions {
+		value, ok := context[cond.Attribute]
+		if !ok || !evaluateCondition(cond, value) {
+			unmet = append(unmet, cond)
+		}
+	}
+	return unmet
+}
+
+// =====================================================================================================================
+// evaluateCondition - compare the value of the context with the value of the condition
+// =====================================================================================================================
+func evaluateCondition(cond condition, value string) bool {
+	switch cond.Operator {
+	case OP_EQ:
+		return value == cond.Value
+	case OP_NE:
+		return value != cond.Value
+	case OP_IN:
+		return isInList(value, cond.Value)
+	case OP_NOTIN:
+		return !isInList(value, cond.Value)
+	case OP_LT:
+		return compareValues(value, cond.Value) < 0
+	case OP_LE:
+		return compareValues(value, cond.Value) <= 0
+	case OP_GT:
+		return compareValues(value, cond.Value) > 0
+	case OP_GE:
+		return compareValues(value, cond.Value) >= 0
+	}
+	return false
+}
+
+func isInList(value, list string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func compareValues(value, reference string) int {
+	number, err1 := strconv.ParseFloat(value, 64)
+	referenceNumber, err2 := strconv.ParseFloat(reference, 64)
+	if err1 == nil && err2 == nil {
+		switch {
+		case number < referenceNumber:
+			return -1
+		case number > referenceNumber:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(value, reference)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+)
+
+const (
+	CONDITIONS1 = `{"conditions":[{"attribute":"country","operator":"in","value":"FR, BE"},{"attribute":"hour","operator":"ge","value":"8"},{"attribute":"hour","operator":"lt","value":"18"}]}`
+)
+
+// =====================================================================================================================
+// Evaluate conditions against contexts (evaluator only)
+// =====================================================================================================================
+func TestConsentV2_EvaluateConditions(t *testing.T) {
+	conditions := []condition{{"country", OP_IN, "FR, BE"}, {"hour", OP_GE, "8"}, {"hour", OP_LT, "18"},
+		{"study", OP_NE, "S2"}}
+	tests := []struct {
+		context map[string]string
+		unmet   int
+	}{
+		{map[string]string{"country": "FR", "hour": "9", "study": "S1"}, 0},
+		{map[string]string{"country": "BE", "hour": "17", "study": "S1"}, 0},
+		{map[string]string{"country": "US", "hour": "10", "study": "S1"}, 1},
+		{map[string]string{"country": "FR", "hour": "18", "study": "S2"}, 2},
+		{map[string]string{"country": "FR", "hour": "7"}, 2},
+		{map[string]string{}, 4},
+	}
+	for i, test := range tests {
+		unmet := getUnmetConditions(conditions, test.context)
+		if len(unmet) != test.unmet {
+			t.Log("bad number of unmet conditions for context "+ strconv.Itoa(i)+ " expected: "+
+				strconv.Itoa(test.unmet)+ " received: "+ strconv.Itoa(len(unmet)))
+			t.FailNow()
+		}
+	}
+	if evaluateCondition(condition{"version", OP_GT, "9"}, "10") != true {
+		t.Log("numeric values must be compared as numbers")
+		t.FailNow()
+	}
+	if evaluateCondition(condition{"code", OP_LT, "b"}, "a") != true {
+		t.Log("other values must be compared as strings")
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create a consent with a bad condition operator
+// =====================================================================================================================
+func TestConsentV2_CreateConsentBadCondition(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	res := stub.MockInvoke("1", postConsentArgs(args, []byte(`{"conditions":[{"attribute":"country","operator":"like","value":"F"}]}`)))
+	if res.Status == shim.OK {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Verify a conditional consent with a context (isconsent and checkconsent)
+// =====================================================================================================================
+func TestConsentV2_IsConsentWithConditions(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	res := stub.MockInvoke("1", postConsentArgs(args, []byte(CONDITIONS1)))
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("2", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(`{"country":"FR","hour":"10"}`)})
+	if string(res.Payload) != AUTHORIZED {
+		t.Log("bad consent status for a matching context reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if string(res.Payload) != NOT_AUTHORIZED {
+		t.Log("bad consent status without context reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", [][]byte{[]byte("checkconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(`{"country":"US","hour":"20"}`)})
+	result := decision{}
+	json.Unmarshal(res.Payload, &result)
+	if result.Consent != NOT_AUTHORIZED || len(result.Unmet) != 2 || result.Unmet[0].Attribute != "country" {
+		t.Log("bad decision for a context not matching reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("5", [][]byte{[]byte("checkconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(`not json`)})
+	if res.Status == shim.OK {
+		t.Log("bad status received for a bad context, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
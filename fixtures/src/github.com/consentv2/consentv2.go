@@ -50,6 +50,9 @@ const (
 	NOT_ACTIVE     = "unactive"
 	AUTHORIZED     = "True"
 	NOT_AUTHORIZED = "False"
+	MODE_ALLOW     = "allow"	// create a consent even if an overlapping consent exists
+	MODE_REJECT    = "reject"	// reject a consent overlapping an existing consent
+	MODE_MERGE     = "merge"	// merge the period of the consent into the overlapping consent
 
 	//Chainccode index
 	indexApp       = "app~id" 		// to get all consents for appID
@@ -61,8 +64,16 @@ const (
 	errorArgs                 = "Incorrect number of arguments."
 	errorBadFunctionName      = "Invalid function, expecting \"postconsent\" \"removeconsent\" " +
 				    "\"resetconsents\" \"getconsent\" \"getownerconsents\" \"getconsumerconsents\" " +
-				    "\"getconsents\" \"isconsent\" \"getversion\""
+				    "\"getconsents\" \"isconsent\" \"checkconsent\" \"logaccess\" \"getowneraccesses\" " +
+				    "\"breakglass\" \"getpendingbreakglass\" \"ackbreakglass\" \"adddatatype\" " +
+				    "\"listdatatypes\" \"deprecatedatatype\" \"anchordocument\" \"registerownerkey\" " +
+				    "\"getownerkey\" \"getconsentbyref\" \"getstats\" \"compactstats\" \"wasconsent\" \"puttemplate\" " +
+				    "\"gettemplate\" \"listtemplates\" \"postconsentfromtemplate\" \"getgrant\" " +
+				    "\"revokegrant\" \"creategroup\" \"addgroupmember\" \"removegroupmember\" " +
+				    "\"getgroupmembers\" \"exportconsents\" \"importconsents\" \"migrate\" \"getversion\""
 	errorCreateConsent        = "Create consent!"
+	errorConsentOptions       = "Consent options are not valid!"
+	errorConsentOverlap       = "An overlapping consent exists:"
 	errorGetConsent           = "Get consent:"
 	errorConsentNotExist      = "Consent does not exist:"
 	errorConsentNotActive     = "Consent is not active:"
@@ -85,14 +96,27 @@ type ConsentCC struct {
 // AppID:      string: id of the client application
 // State:      string: to define the state of the consent (active, unactive)
 // ConsentID:  string: id of the record allow to identify a consent
-// ConsumerID: string: id of the data consumer
+// ConsumerID: string: id of the data consumer or reference of a consumer group (group:GROUPID)
 // OwnerID:    string: id of the data owner
 // DataType:   string: type of data ex: ('BC'-->Body composition, 'BM'--> Body measurement, 'BP'-->Bloodpressure,
 // 					 'WS'-->Weightscale, 'CGM'-->Continue glucose monitoring, 'HR'-->Heart rate,
 // 					 'BGM -->Blood glucose monitoring, 'CAR'-->Cardio vascular and fitness)
+// 					 must be a data type of the taxonomy (see listdatatypes)
 // dataAccess: string: type of data access ex: ('C'-->Create, 'R'-->Read, 'U'-->Update, 'D'-->Delete, 'L'-->List )
 // Dt_begin:   date:   starting date of the consent  (the date format is: yyyy-mm-dd)
 // Dt_end:     date:   ending date of the consent (the date format is: yyyy-mm-dd)
+// DocumentHash:    string: SHA-256 (hex) of the consent form signed by the owner (optional)
+// DocumentURI:     string: location of the consent form (optional)
+// DocumentVersion: string: version of the consent form (optional)
+// OwnerSigned:     bool:   true when the owner signed the consent (signature verified with the owner key registry)
+// OwnerSignature:  string: signature of the owner over the canonical consent payload (optional)
+// ExternalRef:     string: id of the consent in the client application, unique per appID (optional)
+// Conditions:      list:   conditions on the context of the access, all must be met (optional)
+// GrantID:         string: id of the grant when the consent was created from a template (optional)
+// CreatedAt:       date:   time of the creation of the consent (time of the last modification for the records
+//                          written before the schema version 3)
+// UpdatedAt:       date:   time of the last modification of the consent (used to evaluate its history)
+// SchemaVersion:   int:    version of the record schema (see schema.go, records without version are version 1)
 // =====================================================================================================================
 type consent struct {
 	AppID 		string     `json:"appid"`
@@ -104,6 +128,48 @@ type consent struct {
 	DataAccess      string     `json:"dataaccess"`
 	Dt_begin      	time.Time  `json:"dtbegin"`
 	Dt_end       	time.Time  `json:"dtend"`
+	DocumentHash   	string     `json:"documenthash,omitempty"`
+	DocumentURI    	string     `json:"documenturi,omitempty"`
+	DocumentVersion	string     `json:"documentversion,omitempty"`
+	OwnerSigned	bool       `json:"ownersigned"`
+	OwnerSignature	string     `json:"ownersignature,omitempty"`
+	ExternalRef	string     `json:"externalref,omitempty"`
+	Conditions	[]condition `json:"conditions,omitempty"`
+	GrantID		string     `json:"grantid,omitempty"`
+	CreatedAt	time.Time  `json:"createdat"`
+	UpdatedAt	time.Time  `json:"updatedat"`
+	SchemaVersion	int        `json:"schemaversion"`
+}
+
+// =====================================================================================================================
+// Optional arguments of postconsent (json object)
+// OwnerSignature: string: base64 ASN.1 ECDSA signature of the owner over the canonical consent payload
+// OwnerCert:      string: PEM certificate of the owner (must match the owner key registry)
+// Nonce:          string: unique value signed with the consent by the owner (mandatory with OwnerSignature)
+// Mode:           string: behavior when an active consent overlaps the period ('allow' (default), 'reject', 'merge')
+// ExternalRef:    string: id of the consent in the client application (unique per appID)
+// Conditions:     list:   conditions on the context of the access (see condition)
+// =====================================================================================================================
+type consentOptions struct {
+	OwnerSignature	string      `json:"ownersignature,omitempty"`
+	OwnerCert	string      `json:"ownercert,omitempty"`
+	Nonce		string      `json:"nonce,omitempty"`
+	Mode		string      `json:"mode,omitempty"`
+	ExternalRef	string      `json:"externalref,omitempty"`
+	Conditions	[]condition `json:"conditions,omitempty"`
+}
+
+// =====================================================================================================================
+// Consent:    string: result of the check (True, False)
+// ConsentID:  string: id of the consent granting the access (empty if not authorized)
+// BreakGlassID: string: id of the break-glass event granting the access (empty if not in emergency)
+// Unmet:      list:   conditions of a valid consent not satisfied by the context (only when not authorized)
+// =====================================================================================================================
+type decision struct {
+	Consent		string      `json:"consent"`
+	ConsentID	string      `json:"consentid,omitempty"`
+	BreakGlassID	string      `json:"breakglassid,omitempty"`
+	Unmet		[]condition `json:"unmet,omitempty"`
 }
 
 // =====================================================================================================================
@@ -112,6 +178,10 @@ type consent struct {
 func (c *ConsentCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	logger.Debug("Init() : calling method -")
 	fmt.Println("Init() : calling method -")
+	err := initTaxonomy(stub)
+	if err != nil {
+		return shim.Error(buildError(errorAddDataType))
+	}
 	return shim.Success(nil)
 }
 
@@ -139,6 +209,64 @@ func (c *ConsentCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		return c.getConsents4AppID(stub, args)
 	case "isconsent" :
 		return c.isConsent(stub, args)
+	case "checkconsent" :
+		return c.checkConsent(stub, args)
+	case "logaccess" :
+		return c.logAccess(stub, args)
+	case "getowneraccesses" :
+		return c.getOwnerAccesses(stub, args)
+	case "breakglass" :
+		return c.breakGlass(stub, args)
+	case "getpendingbreakglass" :
+		return c.getPendingBreakGlass(stub, args)
+	case "ackbreakglass" :
+		return c.ackBreakGlass(stub, args)
+	case "adddatatype" :
+		return c.addDataType(stub, args)
+	case "listdatatypes" :
+		return c.listDataTypes(stub, args)
+	case "deprecatedatatype" :
+		return c.deprecateDataType(stub, args)
+	case "anchordocument" :
+		return c.anchorDocument(stub, args)
+	case "registerownerkey" :
+		return c.registerOwnerKey(stub, args)
+	case "getownerkey" :
+		return c.getOwnerKey(stub, args)
+	case "getconsentbyref" :
+		return c.getConsentByRef(stub, args)
+	case "getstats" :
+		return c.getStats(stub, args)
+	case "compactstats" :
+		return c.compactStats(stub, args)
+	case "wasconsent" :
+		return c.wasConsent(stub, args)
+	case "puttemplate" :
+		return c.putTemplate(stub, args)
+	case "gettemplate" :
+		return c.getTemplate(stub, args)
+	case "listtemplates" :
+		return c.listTemplates(stub, args)
+	case "postconsentfromtemplate" :
+		return c.createConsentsFromTemplate(stub, args)
+	case "getgrant" :
+		return c.getGrant(stub, args)
+	case "revokegrant" :
+		return c.revokeGrant(stub, args)
+	case "creategroup" :
+		return c.createGroup(stub, args)
+	case "addgroupmember" :
+		return c.addGroupMember(stub, args)
+	case "removegroupmember" :
+		return c.removeGroupMember(stub, args)
+	case "getgroupmembers" :
+		return c.getGroupMembers(stub, args)
+	case "exportconsents" :
+		return c.exportConsents(stub, args)
+	case "importconsents" :
+		return c.importConsents(stub, args)
+	case "migrate" :
+		return c.migrate(stub, args)
 	case "getversion" :
 		return c.getVersion(stub, args)
 	default:
@@ -166,20 +294,50 @@ func (c *ConsentCC)getVersion(stub shim.ChaincodeStubInterface, args []string) p
 // Create a Consent
 // example:
 // ./peer chaincode invoke -C mch -n consent -c '{"Args":["postconsent","APPID","OWNERID","CONSUMERID","DATATYPE",
-// 							"DATAACCESS", "DT_BEGIN", "DT_END"]}' -o 127.0.0.1:7050
+// 							"DATAACCESS", "DT_BEGIN", "DT_END", "OPTIONS"]}' -o 127.0.0.1:7050
+// the OPTIONS argument is optional, it is a json object (see consentOptions)
 // return the consentID
 // =====================================================================================================================
 func (c *ConsentCC)createConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	if len(args) != 7 {
-		errStr := errorArgs+" expecting appID, ownerID, consumerID, dataType, dataAccess, dt_begin, dt_end!"
+	if len(args) != 7 && len(args) != 8 {
+		errStr := errorArgs+" expecting appID, ownerID, consumerID, dataType, dataAccess, dt_begin, dt_end, [options]!"
 		return shim.Error(buildError(errStr))
 	}
+	options, err := parseConsentOptions(args)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
 	logger.Debug("createConsent(Ownerid:"+ args[0]+" Consumerid:"+ args[1]+ " Datatype:"+ args[2]+ " Dataaccess:" +
 		args[3]+ " Dt_begin:"+ args[4]+ " Dt_end:"+ args[5] +") : calling method -")
 	dt_begin, dt_end, err := checkDates(args[5], args[6])
 	if err != nil {
 		return shim.Error(buildError(err.Error()))
 	}
+	err = checkDataType(stub, args[3])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	err = checkConsumer(stub, args[0], args[2])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if options.ExternalRef != "" {
+		// a create retried with the same externalRef returns the consent already created
+		existingID, err := getConsentIDByRef(stub, args[0], options.ExternalRef)
+		if err != nil {
+			return shim.Error(buildError(errorCreateConsent))
+		}
+		if existingID != "" {
+			return retryConsent(stub, existingID, args, dt_begin, dt_end, options)
+		}
+	}
+	ownerSigned, overlapping, err := checkNewConsent(stub, args[:7], dt_begin, dt_end, options)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if overlapping != nil && options.Mode == MODE_MERGE {
+		return mergeConsent(stub, *overlapping, dt_begin, dt_end, ownerSigned, options.ExternalRef)
+	}
 	appID := args[0]
 	state := ACTIVE
 	consentID := stub.GetTxID()
@@ -188,12 +346,11 @@ func (c *ConsentCC)createConsent(stub shim.ChaincodeStubInterface, args []string
 	dataType := args[3]
 	dataAccess := args[4]
 
-	consent := &consent{appID, state, consentID, ownerID, consumerID, dataType, dataAccess, dt_begin, dt_end}
-	consentSONasBytes, err := json.Marshal(consent)
-	if err != nil {
-		return shim.Error(buildError(errorCreateConsent))
-	}
-	err = stub.PutState(consentID, consentSONasBytes)
+	consent := &consent{AppID: appID, State: state, ConsentID: consentID, OwnerID: ownerID, ConsumerID: consumerID,
+		DataType: dataType, DataAccess: dataAccess, Dt_begin: dt_begin, Dt_end: dt_end, OwnerSigned: ownerSigned,
+		OwnerSignature: options.OwnerSignature, ExternalRef: options.ExternalRef, Conditions: options.Conditions,
+		CreatedAt: getTxTime(stub), UpdatedAt: getTxTime(stub)}
+	err = putConsent(stub, *consent)
 	if err != nil {
 		return shim.Error(buildError(errorCreateConsent))
 	}
@@ -202,6 +359,12 @@ func (c *ConsentCC)createConsent(stub shim.ChaincodeStubInterface, args []string
 	if err != nil {
 		return shim.Error(buildError(errorCreateConsent))
 	}
+	delta := newStats(appID)
+	delta.countCreatedConsent(*consent)
+	err = putStats(stub, delta)
+	if err != nil {
+		return shim.Error(buildError(errorUpdateStats+ appID))
+	}
 	valAsBytes := []byte(consentID)
 	return shim.Success(valAsBytes)
 }
@@ -210,10 +373,16 @@ func (c *ConsentCC)createConsent(stub shim.ChaincodeStubInterface, args []string
 // Get a Consent from appID and consentID
 // example:
 // ./peer chaincode invoke -C mch -n consent -c '{"Args":["getconsent","APPID","CONSENTID"]}' -o 127.0.0.1:7050
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getconsent","APPID","CONSENTID","all"]}' -o 127.0.0.1:7050
+// the STATE argument is optional, an unactive consent is returned only with all
 // =====================================================================================================================
 func (c *ConsentCC)getConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	if len(args) != 2 {
-		errStr := errorArgs+" Expecting appID, consentID!"
+	if len(args) != 2 && len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, consentID, [state]!"
+		return shim.Error(buildError(errStr))
+	}
+	if len(args) == 3 && args[2] != STATE_ALL {
+		errStr := errorArgs+" state must be "+STATE_ALL+"!"
 		return shim.Error(buildError(errStr))
 	}
 	logger.Debug("getConsent(Appid:"+ args[0]+ "ConsentID:"+ args[1]+") : calling method -")
@@ -226,19 +395,22 @@ func (c *ConsentCC)getConsent(stub shim.ChaincodeStubInterface, args []string) p
 	} else if valAsBytes == nil {
 		return shim.Error(buildError(errorConsentNotExist+ consentID ))
 	}
-	var consent consent
-	err = json.Unmarshal(valAsBytes, &consent)
+	consent, _, err := decodeConsent(consentID, valAsBytes)
 	if err != nil {
-		logger.Error("Failed to unmarshal state for " + consentID +" "+err.Error())
-		return shim.Error(buildError(errorGetConsent+ consentID))
+		return shim.Error(buildError(err.Error()))
 	}
 	if appID != consent.AppID {
 		logger.Error("Consent does not exist: " + consentID + " for this AppID:" + appID)
 		return shim.Error(buildError(errorConsentNotExist+ consentID ))
 	}
-	if consent.State == NOT_ACTIVE {
+	if consent.State == NOT_ACTIVE && len(args) == 2 {
 		return shim.Error(buildError(errorConsentNotActive))
 	}
+	// return the record upgraded to the current schema version
+	valAsBytes, err = json.Marshal(consent)
+	if err != nil {
+		return shim.Error(buildError(errorGetConsent+ consentID))
+	}
 	return shim.Success(valAsBytes)
 }
 
@@ -264,22 +436,30 @@ func (c *ConsentCC)inactivateConsent(stub shim.ChaincodeStubInterface, args []st
 		return shim.Error(buildError(errorConsentNotExist))
 	}
 
-	consent := consent{}
-	err = json.Unmarshal(consentAsBytes, &consent)
+	consent, _, err := decodeConsent(consentID, consentAsBytes)
 	if err != nil {
-		return shim.Error(err.Error())
+		return shim.Error(buildError(err.Error()))
 	}
 	if consent.AppID != appID {
 		logger.Error("Consent does not exist: " + consentID + " for this AppID:" + appID)
 		return shim.Error(buildError(errorConsentNotExist+ consentID ))
 	}
 
+	wasActive := consent.State == ACTIVE
 	consent.State = NOT_ACTIVE
-	consentJSONasBytes, _ := json.Marshal(consent)
-	err = stub.PutState(consentID, consentJSONasBytes)
+	consent.UpdatedAt = getTxTime(stub)
+	err = putConsent(stub, consent)
 	if err != nil {
 		return shim.Error(buildError(errorInactiveConsent+ consentID ))
 	}
+	if wasActive {
+		delta := newStats(appID)
+		delta.countRevokedConsent(consent)
+		err = putStats(stub, delta)
+		if err != nil {
+			return shim.Error(buildError(errorUpdateStats+ appID))
+		}
+	}
 	return shim.Success(nil)
 }
 
@@ -310,6 +490,10 @@ func (c *ConsentCC)deleteConsents4AppID(stub shim.ChaincodeStubInterface, args [
 		return shim.Error(err.Error())
 		return shim.Error(buildError(errorRemoveConsent4App+appID))
 	}
+	err = resetStats(stub, appID)
+	if err != nil {
+		return shim.Error(buildError(errorUpdateStats+ appID))
+	}
 	return shim.Success(nil)
 }
 
@@ -318,109 +502,190 @@ func (c *ConsentCC)deleteConsents4AppID(stub shim.ChaincodeStubInterface, args [
 // example:
 // ./peer chaincode invoke -C mychanel -n consent -c '{"Args":["getconsents","APPID","ALLM"]}' -o 127.0.0.1:7050
 // ./peer chaincode invoke -C mychanel -n consent -c '{"Args":["getconsents","APPID"]}' -o 127.0.0.1:7050
+// ./peer chaincode invoke -C mychanel -n consent -c '{"Args":["getconsents","APPID","{\"datatype\":\"BP\"}"]}'
+// 						-o 127.0.0.1:7050
+// the FILTER argument is optional, it is a json object (see consentFilter), ALLM is kept for the old clients
 // =====================================================================================================================
 func (c *ConsentCC)getConsents4AppID(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 1 && len(args) != 2 {
-		errStr := errorArgs+" Expecting appID!"
+		errStr := errorArgs+" Expecting appID, [filter]!"
 		return shim.Error(buildError(errStr))
 	}
 	logger.Debug("getConsents4AppID(Appid:"+ args[0]+") : calling method -")
 	appID := args[0]
-	consents, err := getConsentsByIndex(stub, indexApp,  []string{appID, ACTIVE})
-	if err != nil {
-		return shim.Error(buildError(errorGetConsents4AppID+appID))
+	filter := ""
+	if len(args) == 2 && args[1] != "ALLM" {
+		filter = args[1]
 	}
-	valAsBytes, err := json.Marshal(consents)
-	if err != nil {
-		return shim.Success([]byte("[]"))
-	}
-	return shim.Success(valAsBytes)
+	return listConsents(stub, indexApp, []string{appID}, filter, errorGetConsents4AppID+appID)
 }
 
 
 // =====================================================================================================================
 // Get a Consents for an appID and a OwnerID
 // example:
-// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getownerconsents","APPID","OWNERID"]}' -o 127.0.0.1:7050
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getownerconsents","APPID","OWNERID","FILTER"]}'
+// 						-o 127.0.0.1:7050
+// the FILTER argument is optional, it is a json object (see consentFilter)
 // =====================================================================================================================
 func (c *ConsentCC)getOwnerConsents(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	if len(args) != 2 {
-		errStr := errorArgs+" Expecting appID, ownerID!"
+	if len(args) != 2 && len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, ownerID, [filter]!"
 		return shim.Error(buildError(errStr))
 	}
 	logger.Debug("getOwnerConsents(Appid:"+ args[0]+ "OwnerID:"+ args[1]+") : calling method -")
 	appID := args[0]
 	ownerID := args[1]
-
-	consents, err := getConsentsByIndex(stub, indexOwner,  []string{appID, ownerID, ACTIVE})
-	if err != nil {
-		return shim.Error(buildError(errorGetConsents4Owner+ownerID+" appID:"+appID))
+	filter := ""
+	if len(args) == 3 {
+		filter = args[2]
 	}
-	valAsBytes, err := json.Marshal(consents)
-	if err != nil {
-		return shim.Success([]byte("[]"))
-	}
-	return shim.Success(valAsBytes)
+	return listConsents(stub, indexOwner, []string{appID, ownerID}, filter, errorGetConsents4Owner+ownerID+" appID:"+appID)
 }
 
 // =====================================================================================================================
 // Get a Consent for an appID and a consumerID
 // example:
-// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getconsumerconsents","APPID","CONSUMERID"]}'
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getconsumerconsents","APPID","CONSUMERID","FILTER"]}'
 // 						-o 127.0.0.1:7050
+// the FILTER argument is optional, it is a json object (see consentFilter)
 // =====================================================================================================================
 func (c *ConsentCC)getConsumerConsents(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	if len(args) != 2 {
-		errStr := errorArgs+" Expecting appID, ownerID!"
+	if len(args) != 2 && len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, consumerID, [filter]!"
 		return shim.Error(buildError(errStr))
 	}
 	logger.Debug("getConsumerConsents(Appid:"+ args[0]+ "ConsumerID:"+ args[1]+") : calling method -")
 	appID := args[0]
 	consumerID := args[1]
-
-	consents, err := getConsentsByIndex(stub, indexConsumer,  []string{appID, consumerID, ACTIVE})
-	if err != nil {
-		return shim.Error(buildError(errorGetConsents4Consumer+consumerID+" appID:"+appID))
+	filter := ""
+	if len(args) == 3 {
+		filter = args[2]
 	}
-	valAsBytes, err := json.Marshal(consents)
-	if err != nil {
-		return shim.Success([]byte("[]"))
-	}
-	return shim.Success(valAsBytes)
+	return listConsents(stub, indexConsumer, []string{appID, consumerID}, filter, errorGetConsents4Consumer+consumerID+" appID:"+appID)
 }
 
 // =====================================================================================================================
 // Verify if a consent exist
 // example:
 // ./peer chaincode invoke -C mch -n consent -c '{"Args":["isconsent","APPID", "OWNERID", "CONSUMERID", "DATATYPE",
-// 							"ACCESSTYPE"]}' -o 127.0.0.1:7050
+// 							"ACCESSTYPE", "{\"country\":\"FR\"}"]}' -o 127.0.0.1:7050
+// the CONTEXT argument is optional, it is a json object matched against the conditions of the consents
 // =====================================================================================================================
 func (c *ConsentCC)isConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	if len(args) != 5 {
-		errStr := errorArgs+" Expecting AppID, OwnerID, CounsumerID, Datatype, Dataaccess!"
+	if len(args) != 5 && len(args) != 6 {
+		errStr := errorArgs+" Expecting AppID, OwnerID, CounsumerID, Datatype, Dataaccess, [Context]!"
 		return shim.Error(buildError(errStr))
 	}
 	logger.Debug("isConsent(Appid:"+ args[0]+ "Ownerid:"+ args[1]+" Consumerid:"+ args[2]+ " Datatype:"+ args[3]+
 		" Dataaccess:" + args[4] +") : calling method -")
+	context, err := parseContext(args, 5)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	granting, _, err := getGrantingConsent(stub, args[0], args[1], args[2], args[3], args[4], context)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if granting != nil {
+		return shim.Success([]byte(AUTHORIZED))
+	}
+	emergency, err := getOpenBreakGlass(stub, args[0], args[1], args[2], args[3])
+	if err != nil {
+		return shim.Error(buildError(errorGetBreakGlass+ args[0]))
+	}
+	if emergency != nil {
+		return shim.Success([]byte(AUTHORIZED))
+	}
+	return shim.Success([]byte(NOT_AUTHORIZED))
+}
 
-	appID := args[0]
-	ownerID := args[1]
-	consumerID := args[2]
-	dataType := args[3]
-	dataAccess := args[4]
-	consents, err := getConsentsByIndex(stub, indexIsConsent, []string{appID, ownerID, consumerID, ACTIVE,
-		dataType, dataAccess})
+// =====================================================================================================================
+// Verify if a consent exist and return the decision with the id of the granting consent
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["checkconsent","APPID", "OWNERID", "CONSUMERID", "DATATYPE",
+// 							"ACCESSTYPE", "{\"country\":\"FR\"}"]}' -o 127.0.0.1:7050
+// the CONTEXT argument is optional, the conditions not met by the context are returned in the decision
+// =====================================================================================================================
+func (c *ConsentCC)checkConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 5 && len(args) != 6 {
+		errStr := errorArgs+" Expecting AppID, OwnerID, CounsumerID, Datatype, Dataaccess, [Context]!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("checkConsent(Appid:"+ args[0]+ "Ownerid:"+ args[1]+" Consumerid:"+ args[2]+ " Datatype:"+ args[3]+
+		" Dataaccess:" + args[4] +") : calling method -")
+	context, err := parseContext(args, 5)
 	if err != nil {
-		return shim.Error(buildError(errorGetConsent4Params+"appID:"+appID+" OwnerID:"+ownerID+" ConsumerID:"+
-		consumerID+" dataType:"+dataType+" DataAccess:"+dataAccess))
+		return shim.Error(buildError(err.Error()))
 	}
-	for i := 0; i < len(consents); i++ {
-		isValid := isValidToday(consents[i].Dt_begin, consents[i].Dt_end)
-		if isValid {
-			return shim.Success([]byte(AUTHORIZED))
+	granting, unmet, err := getGrantingConsent(stub, args[0], args[1], args[2], args[3], args[4], context)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	result := decision{Consent: NOT_AUTHORIZED}
+	if granting != nil {
+		result.Consent = AUTHORIZED
+		result.ConsentID = granting.ConsentID
+	} else {
+		emergency, err := getOpenBreakGlass(stub, args[0], args[1], args[2], args[3])
+		if err != nil {
+			return shim.Error(buildError(errorGetBreakGlass+ args[0]))
+		}
+		if emergency != nil {
+			result.Consent = AUTHORIZED
+			result.BreakGlassID = emergency.EventID
+		} else {
+			result.Unmet = unmet
 		}
 	}
-	return shim.Success([]byte(NOT_AUTHORIZED))
+	valAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(buildError(errorGetConsent4Params+"appID:"+args[0]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// getGrantingConsent - return the first active consent valid today for the parameters or a parent data type whose
+// conditions are met by the context (nil if none), with the unmet conditions of the first valid consent otherwise
+// =====================================================================================================================
+func getGrantingConsent(stub shim.ChaincodeStubInterface, appID, ownerID, consumerID, dataType, dataAccess string,
+	context map[string]string) (*consent, []condition, error) {
+	// a consent given to a group of the consumer grants the consumer
+	consumers, err := getConsumerReferences(stub, appID, consumerID)
+	if err != nil {
+		return nil, nil, errors.New(errorGetConsent4Params+"appID:"+appID+" OwnerID:"+ownerID+" ConsumerID:"+consumerID)
+	}
+	var unmet []condition
+	for _, consumer := range consumers {
+		// a consent granted on a parent data type includes its children
+		for _, grantedType := range getDataTypeLineage(stub, dataType) {
+			consents, err := getConsentsByIndex(stub, indexIsConsent, []string{appID, ownerID, consumer, ACTIVE,
+				grantedType, dataAccess})
+			if err != nil {
+				return nil, nil, errors.New(errorGetConsent4Params+"appID:"+appID+" OwnerID:"+ownerID+" ConsumerID:"+
+				consumerID+" dataType:"+dataType+" DataAccess:"+dataAccess)
+			}
+			for i := 0; i < len(consents); i++ {
+				// the index keeps the state at the creation, a revoked consent is read as unactive
+				if consents[i].State != ACTIVE {
+					continue
+				}
+				isValid := isValidToday(consents[i].Dt_begin, consents[i].Dt_end)
+				if !isValid {
+					continue
+				}
+				consentUnmet := getUnmetConditions(consents[i].Conditions, context)
+				if len(consentUnmet) == 0 {
+					return &consents[i], nil, nil
+				}
+				if unmet == nil {
+					unmet = consentUnmet
+				}
+			}
+		}
+	}
+	return nil, unmet, nil
 }
 
 // =====================================================================================================================
@@ -458,6 +723,24 @@ func createIndex(stub shim.ChaincodeStubInterface, consent consent) error {
 		return err
 	}
 	stub.PutState(IsConsentIndex, []byte{0x00})
+
+	if consent.ExternalRef != "" {
+		err = putExternalRef(stub, consent.AppID, consent.ExternalRef, consent.ConsentID)
+		if err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+	}
+
+	if consent.GrantID != "" {
+		GrantIndex, err := stub.CreateCompositeKey(indexGrant, []string{consent.AppID, consent.GrantID,
+			consent.ConsentID})
+		if err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+		stub.PutState(GrantIndex, []byte{0x00})
+	}
 	return nil
 }
 
@@ -496,6 +779,25 @@ func deleteIndex(stub shim.ChaincodeStubInterface, consent consent) error {
 		return err
 	}
 	stub.DelState(IsConsentIndex)
+
+	if consent.ExternalRef != "" {
+		RefIndex, err := stub.CreateCompositeKey(indexExternalRef, []string{consent.AppID, consent.ExternalRef})
+		if err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+		stub.DelState(RefIndex)
+	}
+
+	if consent.GrantID != "" {
+		GrantIndex, err := stub.CreateCompositeKey(indexGrant, []string{consent.AppID, consent.GrantID,
+			consent.ConsentID})
+		if err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+		stub.DelState(GrantIndex)
+	}
 	return nil
 }
 
@@ -514,12 +816,9 @@ func deleteConsent(stub shim.ChaincodeStubInterface, consentID string) error {
 		logger.Error(errStr)
 		return errors.New(errStr)
 	}
-	var consent consent
-	err = json.Unmarshal([]byte(valAsBytes), &consent)
+	consent, _, err := decodeConsent(consentID, valAsBytes)
 	if err != nil {
-		errStr := ("Failed to decode JSON for consent:" + consentID )
-		logger.Error(errStr)
-		return errors.New(errStr)
+		return err
 	}
 
 	err = stub.DelState(consentID)
@@ -537,6 +836,23 @@ func deleteConsent(stub shim.ChaincodeStubInterface, consentID string) error {
 }
 
 
+// =====================================================================================================================
+// readConsent - read and decode a consent from its consentID
+// =====================================================================================================================
+func readConsent(stub shim.ChaincodeStubInterface, consentID string) (consent, error) {
+	logger.Debug("readConsent(ConsentID:"+ consentID+ ") : calling method -")
+	var consent consent
+	valAsBytes, err := stub.GetState(consentID)
+	if err != nil {
+		logger.Error("Failed to get consent: " + consentID +" "+err.Error())
+		return consent, errors.New(errorGetConsent+ consentID)
+	} else if valAsBytes == nil {
+		return consent, errors.New(errorConsentNotExist+ consentID)
+	}
+	consent, _, err = decodeConsent(consentID, valAsBytes)
+	return consent, err
+}
+
 // =====================================================================================================================
 // use index to retrieve a list of consents
 // =====================================================================================================================
@@ -568,11 +884,15 @@ func getConsentsByIndex(stub shim.ChaincodeStubInterface, index string, keys []s
 			logger.Error(err)
 			return nil, err
 		}
-		consent := consent{}
-		err = json.Unmarshal(consentAsBytes, &consent)
-		if err == nil {
-			consents = append (consents, consent)
+		if consentAsBytes == nil {
+			// index of a deleted consent
+			continue
 		}
+		consent, _, err := decodeConsent(consentID, consentAsBytes)
+		if err != nil {
+			return nil, err
+		}
+		consents = append (consents, consent)
 	}
 	return consents, nil
 }
@@ -591,12 +911,41 @@ func buildError(errorStr string) (jsonResp string){
 // =====================================================================================================================
 func isValidToday(dt_start, dt_end time.Time) bool {
 	logger.Debug("isValidToday(dt_start:"+dt_start.String()+", dt_end:"+dt_end.String()+") : calling method -")
-	now := time.Now()
+	return isValidAt(dt_start, dt_end, time.Now())
+}
+
+// =====================================================================================================================
+// Check if the period is valid at an instant
+// =====================================================================================================================
+func isValidAt(dt_start, dt_end, instant time.Time) bool {
 	dt_end = dt_end.Add(24 * time.Hour)
-	isValid := now.After(dt_start) && now.Before(dt_end)
+	isValid := instant.After(dt_start) && instant.Before(dt_end)
 	return isValid
 }
 
+// =====================================================================================================================
+// Check if a value is in a list
+// =====================================================================================================================
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// =====================================================================================================================
+// Get the timestamp of the transaction (peer time if the timestamp is not provided)
+// =====================================================================================================================
+func getTxTime(stub shim.ChaincodeStubInterface) time.Time {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil || txTimestamp == nil {
+		return time.Now().UTC()
+	}
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC()
+}
+
 // =====================================================================================================================
 // Convert date string to date: format (DD-MM-YYYY)
 // =====================================================================================================================
@@ -610,6 +959,108 @@ func dateString2Date(datestr string) (time.Time, error) {
 	return date, err
 }
 
+// =====================================================================================================================
+// parseConsentOptions - decode the optional json argument of postconsent
+// =====================================================================================================================
+func parseConsentOptions(args []string) (consentOptions, error) {
+	options := consentOptions{}
+	if len(args) < 8 || args[7] == "" {
+		return options, nil
+	}
+	err := json.Unmarshal([]byte(args[7]), &options)
+	if err != nil {
+		return options, errors.New(errorConsentOptions)
+	}
+	if options.Mode != "" && options.Mode != MODE_ALLOW && options.Mode != MODE_REJECT && options.Mode != MODE_MERGE {
+		return options, errors.New(errorConsentOptions)
+	}
+	err = checkConditions(options.Conditions)
+	if err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// =====================================================================================================================
+// checkNewConsent - verify the owner signature (if any) and the overlapping consents of a new consent (arguments of
+// postconsent), return if the consent is signed by the owner and the active consent overlapping its period (nil if
+// none, an error in reject mode)
+// =====================================================================================================================
+func checkNewConsent(stub shim.ChaincodeStubInterface, args []string, dt_begin, dt_end time.Time,
+	options consentOptions) (bool, *consent, error) {
+	ownerSigned := false
+	if options.OwnerSignature != "" {
+		err := verifyOwnerSignature(stub, args[0], args[1], args, options)
+		if err != nil {
+			return false, nil, err
+		}
+		ownerSigned = true
+	}
+	overlapping, err := getOverlappingConsent(stub, args[0], args[1], args[2], args[3], args[4], dt_begin, dt_end)
+	if err != nil {
+		return false, nil, errors.New(errorCreateConsent)
+	}
+	if overlapping != nil && options.Mode == MODE_REJECT {
+		return false, nil, errors.New(errorConsentOverlap+ overlapping.ConsentID)
+	}
+	return ownerSigned, overlapping, nil
+}
+
+// =====================================================================================================================
+// getOverlappingConsent - return the first active consent for the parameters overlapping the period (nil if none)
+// =====================================================================================================================
+func getOverlappingConsent(stub shim.ChaincodeStubInterface, appID, ownerID, consumerID, dataType, dataAccess string,
+	dt_begin, dt_end time.Time) (*consent, error) {
+	consents, err := getConsentsByIndex(stub, indexIsConsent, []string{appID, ownerID, consumerID, ACTIVE,
+		dataType, dataAccess})
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(consents); i++ {
+		// the index keeps the state at the creation, a revoked consent never overlaps (nor is merged)
+		if consents[i].State != ACTIVE {
+			continue
+		}
+		if consents[i].Dt_begin.Before(dt_end) && dt_begin.Before(consents[i].Dt_end) {
+			return &consents[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// =====================================================================================================================
+// mergeConsent - extend the period of an existing consent, return the existing consentID
+// =====================================================================================================================
+func mergeConsent(stub shim.ChaincodeStubInterface, existing consent, dt_begin, dt_end time.Time, ownerSigned bool,
+	externalRef string) pb.Response {
+	logger.Debug("mergeConsent() in consentID:"+ existing.ConsentID+" : calling method -")
+	if dt_begin.Before(existing.Dt_begin) {
+		existing.Dt_begin = dt_begin
+	}
+	if dt_end.After(existing.Dt_end) {
+		existing.Dt_end = dt_end
+	}
+	// the merged period is signed by the owner only if both parts are
+	if !ownerSigned {
+		existing.OwnerSigned = false
+	}
+	existing.UpdatedAt = getTxTime(stub)
+	if externalRef != "" {
+		err := putExternalRef(stub, existing.AppID, externalRef, existing.ConsentID)
+		if err != nil {
+			return shim.Error(buildError(errorCreateConsent))
+		}
+		if existing.ExternalRef == "" {
+			existing.ExternalRef = externalRef
+		}
+	}
+	err := putConsent(stub, existing)
+	if err != nil {
+		return shim.Error(buildError(errorCreateConsent))
+	}
+	return shim.Success([]byte(existing.ConsentID))
+}
+
 // =====================================================================================================================
 // Check if the period is valid (begin anterior to end)
 // =====================================================================================================================
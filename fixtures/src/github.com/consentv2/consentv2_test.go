@@ -15,8 +15,8 @@ const (
 	OWNERID2 = "owner2"
 	CONSUMERID1 = "consumer1"
 	CONSUMERID2 = "consumer2"
-	DATATYPE1 = "type1"
-	DATATYPE2 = "type2"
+	DATATYPE1 = "BP"
+	DATATYPE2 = "WS"
 	DATAACCESS1 = "access1"
 	DATAACCESS2 = "access2"
 )
@@ -27,6 +27,7 @@ const (
 func TestConsentV2_GetVersionNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("getversion")})
 	if res.Status != shim.OK {
 		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -50,6 +51,7 @@ func TestConsentV2_GetVersionNominal(t *testing.T) {
 func TestConsentV2_CreateConsentNominal(t *testing.T){
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	if res.Status != shim.OK {
 		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -68,6 +70,7 @@ func TestConsentV2_CreateConsentNominal(t *testing.T){
 func TestConsentV2_GetConsentNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	res = stub.MockInvoke("2", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte(consentID)})
@@ -98,6 +101,7 @@ func TestConsentV2_GetConsentNominal(t *testing.T) {
 func TestConsentV2_InactivateConsentNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	res = stub.MockInvoke("2", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte(consentID)})
@@ -123,6 +127,7 @@ func TestConsentV2_InactivateConsentNominal(t *testing.T) {
 func TestConsentV2_GetAllConsents4AppIDNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -152,6 +157,7 @@ func TestConsentV2_GetAllConsents4AppIDNominal(t *testing.T) {
 func TestConsentV2_DeleteConsents4AppIDNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -203,6 +209,7 @@ func TestConsentV2_DeleteConsents4AppIDNominal(t *testing.T) {
 func TestConsentV2_GetOwnerConsentsNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -231,6 +238,7 @@ func TestConsentV2_GetOwnerConsentsNominal(t *testing.T) {
 func TestConsentV2_GetConsumerConsentsNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -260,6 +268,7 @@ func TestConsentV2_GetConsumerConsentsNominal(t *testing.T) {
 func TestConsentV2_IsConsentNominal(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 
 	res := stub.MockInvoke("1", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
@@ -275,12 +284,93 @@ func TestConsentV2_IsConsentNominal(t *testing.T) {
 	}
 }
 
+// =====================================================================================================================
+// Check consent and get the granting consent (nominal case)
+// =====================================================================================================================
+func TestConsentV2_CheckConsentNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	consentID := string(res.Payload)
+	res = stub.MockInvoke("2", [][]byte{[]byte("checkconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	var result decision
+	err := json.Unmarshal(res.Payload, &result)
+	if err != nil {
+		t.Log("checkconsent", string(res.Payload))
+		t.FailNow()
+	}
+	if result.Consent != AUTHORIZED {
+		t.Log(AUTHORIZED, "expected, but ", result.Consent, "reveived")
+		t.FailNow()
+	}
+	if result.ConsentID != consentID {
+		t.Log("checkconsent bad consentID, expected:"+consentID+" received:"+result.ConsentID)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Check consent without consent --> not authorized and no consentID
+// =====================================================================================================================
+func TestConsentV2_CheckConsentNotAuthorized(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res := stub.MockInvoke("2", [][]byte{[]byte("checkconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	var result decision
+	err := json.Unmarshal(res.Payload, &result)
+	if err != nil {
+		t.Log("checkconsent", string(res.Payload))
+		t.FailNow()
+	}
+	if result.Consent != NOT_AUTHORIZED || result.ConsentID != "" {
+		t.Log(NOT_AUTHORIZED, "expected without consentID, but ", string(res.Payload), "reveived")
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Is consent exist for a revoked consent --> not authorized
+// =====================================================================================================================
+func TestConsentV2_IsConsentRevoked(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	consentID := string(res.Payload)
+	stub.MockInvoke("2", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte(consentID)})
+	res = stub.MockInvoke("3", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	response := string(res.Payload)
+	if response != NOT_AUTHORIZED{
+		t.Log(NOT_AUTHORIZED, "expected, but ",response, "reveived")
+		t.FailNow()
+	}
+}
+
 // =====================================================================================================================
 // Get bad function name --> error
 // =====================================================================================================================
 func TestConsentV2_GetBadFunction(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("badFunction")})
 	if res.Status != shim.ERROR{
 		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -298,6 +388,7 @@ func TestConsentV2_GetBadFunction(t *testing.T) {
 func TestConsentV2_GetVersionWithParam(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("getversion"), []byte(APPID1), []byte("bad param")})
 	if res.Status != shim.ERROR{
 		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -315,6 +406,7 @@ func TestConsentV2_GetVersionWithParam(t *testing.T) {
 func TestConsentV2_CreateConsentWithAMissingParam(t *testing.T){
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	if res.Status != shim.ERROR || !strings.Contains(res.Message, "Incorrect number of arguments"){
 		t.Log("postconsent", string(res.Message))
@@ -336,6 +428,7 @@ func TestConsentV2_CreateConsentWithAMissingParam(t *testing.T){
 func TestConsentV2_CreateConsentWithBadStartingDateFormat(t *testing.T){
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1),  []byte(DATAACCESS1), []byte("2017"), []byte(getStringDateNow(7))})
 	if res.Status != shim.ERROR{
 		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -353,6 +446,7 @@ func TestConsentV2_CreateConsentWithBadStartingDateFormat(t *testing.T){
 func TestConsentV2_CreateConsentWithBadEndingDateFormat(t *testing.T){
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1),  []byte(DATAACCESS1), []byte(getStringDateNow(7)), []byte("2017")})
 	if res.Status != shim.ERROR{
 		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -370,6 +464,7 @@ func TestConsentV2_CreateConsentWithBadEndingDateFormat(t *testing.T){
 func TestConsentV2_CreateConsentWithBadPeriod(t *testing.T){
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1),  []byte(DATAACCESS1), []byte(getStringDateNow(7)),[]byte(getStringDateNow(0))})
 	if res.Status != shim.ERROR{
 		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -387,6 +482,7 @@ func TestConsentV2_CreateConsentWithBadPeriod(t *testing.T){
 func TestConsentV2_InactivateConsentWithMissingParameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	res = stub.MockInvoke("2", [][]byte{[]byte("removeconsent"), []byte(consentID)})
@@ -407,6 +503,7 @@ func TestConsentV2_InactivateConsentWithMissingParameter(t *testing.T) {
 func TestConsentV2_InactivateConsentWithBadConsentID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	res = stub.MockInvoke("2", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("badconsentid")})
 	if res.Status != shim.ERROR{
@@ -425,6 +522,7 @@ func TestConsentV2_InactivateConsentWithBadConsentID(t *testing.T) {
 func TestConsentV2_InactivateConsentWithBadApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	res = stub.MockInvoke("2", [][]byte{[]byte("removeconsent"), []byte("badappid"), []byte(consentID)})
@@ -444,6 +542,7 @@ func TestConsentV2_InactivateConsentWithBadApplicationID(t *testing.T) {
 func TestConsentV2_InactivateConsentWithAnotherApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -464,6 +563,7 @@ func TestConsentV2_InactivateConsentWithAnotherApplicationID(t *testing.T) {
 func TestConsentV2_GetConsentWithMissingParameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	res = stub.MockInvoke("2", [][]byte{[]byte("getconsent"), []byte(consentID)})
@@ -483,6 +583,7 @@ func TestConsentV2_GetConsentWithMissingParameter(t *testing.T) {
 func TestConsentV2_GetConsentWithBadApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	res = stub.MockInvoke("2", [][]byte{[]byte("getconsent"), []byte("badappid"), []byte(consentID)})
@@ -502,6 +603,7 @@ func TestConsentV2_GetConsentWithBadApplicationID(t *testing.T) {
 func TestConsentV2_GetConsentWithAnotherApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -521,6 +623,7 @@ func TestConsentV2_GetConsentWithAnotherApplicationID(t *testing.T) {
 func TestConsentV2_GetInactivateConsent(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	consentID := string(res.Payload)
 	res = stub.MockInvoke("2", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte(consentID)})
@@ -550,6 +653,7 @@ func TestConsentV2_GetInactivateConsent(t *testing.T) {
 func TestConsentV2_GetAllConsentsEmptyList(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("getconsents"), []byte(APPID1)})
 	if res.Status != shim.OK{
 		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -574,6 +678,7 @@ func TestConsentV2_GetAllConsentsEmptyList(t *testing.T) {
 func TestConsentV2_GetAllConsents4AppIDWithMissingParameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -595,6 +700,7 @@ func TestConsentV2_GetAllConsents4AppIDWithMissingParameter(t *testing.T) {
 func TestConsentV2_GetAllConsents4AppIDWithBadAppID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -623,6 +729,7 @@ func TestConsentV2_GetAllConsents4AppIDWithBadAppID(t *testing.T) {
 func TestConsentV2_GetOwnerConsentsEmptyList(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("getownerconsents"), []byte(APPID1), []byte(OWNERID1)})
 	if res.Status != shim.OK {
 		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -647,6 +754,7 @@ func TestConsentV2_GetOwnerConsentsEmptyList(t *testing.T) {
 func TestConsentV2_GetOwnerConsentsWithMissingParameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	res := stub.MockInvoke("3", [][]byte{[]byte("getownerconsents"), []byte(APPID1)})
@@ -666,6 +774,7 @@ func TestConsentV2_GetOwnerConsentsWithMissingParameter(t *testing.T) {
 func TestConsentV2_GetOwnerConsentsWithBadApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	res := stub.MockInvoke("3", [][]byte{[]byte("getownerconsents"), []byte("badappid"), []byte(OWNERID1)})
@@ -692,6 +801,7 @@ func TestConsentV2_GetOwnerConsentsWithBadApplicationID(t *testing.T) {
 func TestConsentV2_GetOwnerConsentsWithOtherApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -719,6 +829,7 @@ func TestConsentV2_GetOwnerConsentsWithOtherApplicationID(t *testing.T) {
 func TestConsentV2_GetOwnerConsentsWithBadOwnerID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -747,6 +858,7 @@ func TestConsentV2_GetOwnerConsentsWithBadOwnerID(t *testing.T) {
 func TestConsentV2_GetConsumerEmptyList(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	res := stub.MockInvoke("1", [][]byte{[]byte("getconsumerconsents"), []byte(APPID1), []byte(CONSUMERID1)})
 	if res.Status != shim.OK {
 		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
@@ -772,6 +884,7 @@ func TestConsentV2_GetConsumerEmptyList(t *testing.T) {
 func TestConsentV2_GetConsumerConsentsWithMissingPärameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -794,6 +907,7 @@ func TestConsentV2_GetConsumerConsentsWithMissingPärameter(t *testing.T) {
 func TestConsentV2_GetConsumerConsentsWithBadApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -823,6 +937,7 @@ func TestConsentV2_GetConsumerConsentsWithBadApplicationID(t *testing.T) {
 func TestConsentV2_GetConsumerConsentsWithOtherApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -852,6 +967,7 @@ func TestConsentV2_GetConsumerConsentsWithOtherApplicationID(t *testing.T) {
 func TestConsentV2_GetConsumerConsentsWithBadOwnerID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -882,6 +998,7 @@ func TestConsentV2_GetConsumerConsentsWithBadOwnerID(t *testing.T) {
 func TestConsentV2_DeleteConsents4AppIDWithMissingParameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
@@ -906,6 +1023,7 @@ func TestConsentV2_DeleteConsents4AppIDWithMissingParameter(t *testing.T) {
 func TestConsentV2_IsConsentWithMissingParameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 	res := stub.MockInvoke("1", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
 
@@ -926,6 +1044,7 @@ func TestConsentV2_IsConsentWithMissingParameter(t *testing.T) {
 func TestConsentV2_IsConsentWithanotherApplicationID(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 
 	res := stub.MockInvoke("1", [][]byte{[]byte("isconsent"), []byte(APPID2), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
@@ -947,6 +1066,7 @@ func TestConsentV2_IsConsentWithanotherApplicationID(t *testing.T) {
 func TestConsentV2_IsConsentWithDifferentDataTypeParameter(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 
 	res := stub.MockInvoke("1", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE2), []byte(DATAACCESS1)})
@@ -968,6 +1088,7 @@ func TestConsentV2_IsConsentWithDifferentDataTypeParameter(t *testing.T) {
 func TestConsentV2_IsConsentWithOneDifferentDataAccess(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
 
 	res := stub.MockInvoke("1", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS2)})
@@ -989,6 +1110,7 @@ func TestConsentV2_IsConsentWithOneDifferentDataAccess(t *testing.T) {
 func TestConsentV2_IsConsentWithOldPeriod(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(-5)), []byte(getStringDateNow(-2))})
 
 	res := stub.MockInvoke("1", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
@@ -1010,6 +1132,7 @@ func TestConsentV2_IsConsentWithOldPeriod(t *testing.T) {
 func TestConsentV2_IsConsentWithFuturePeriod(t *testing.T) {
 	scc := new(ConsentCC)
 	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
 	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(1)), []byte(getStringDateNow(7))})
 
 	res := stub.MockInvoke("1", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/hex"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Document constantes
+// =====================================================================================================================
+const (
+	// Chaincode errors
+	errorAnchorDocument       = "Anchor document!"
+	errorDocumentHash         = "Document hash is not a SHA-256 hex string:"
+	errorDocumentAnchored     = "Document version already anchored:"
+)
+
+// =====================================================================================================================
+// Anchor the hash of the consent form signed by the owner in an active consent
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["anchordocument","APPID","CONSENTID","SHA256","URI",
+// 							"VERSION"]}' -o 127.0.0.1:7050
+// return the consent
+// =====================================================================================================================
+func (c *ConsentCC)anchorDocument(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 5 {
+		errStr := errorArgs+" expecting appID, consentID, documentHash, documentURI, documentVersion!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("anchorDocument(Appid:"+ args[0]+ " ConsentID:"+ args[1]+" Hash:"+ args[2]+ " URI:"+ args[3]+
+		" Version:"+ args[4]+") : calling method -")
+	appID := args[0]
+	consentID := args[1]
+	documentHash := args[2]
+	hash, err := hex.DecodeString(documentHash)
+	if err != nil || len(hash) != 32 {
+		return shim.Error(buildError(errorDocumentHash+ documentHash))
+	}
+	documentHash = hex.EncodeToString(hash)
+	consent, err := readConsent(stub, consentID)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if consent.AppID != appID {
+		return shim.Error(buildError(errorConsentNotExist+ consentID))
+	}
+	if consent.State != ACTIVE {
+		return shim.Error(buildError(errorConsentNotActive+ consentID))
+	}
+	if consent.DocumentHash != "" && consent.DocumentVersion == args[4] {
+		return shim.Error(buildError(errorDocumentAnchored+ args[4]))
+	}
+	consent.DocumentHash = documentHash
+	consent.DocumentURI = args[3]
+	consent.DocumentVersion = args[4]
+	consent.UpdatedAt = getTxTime(stub)
+	valAsBytes, err := encodeConsent(consent)
+	if err != nil {
+		return shim.Error(buildError(errorAnchorDocument))
+	}
+	err = stub.PutState(consentID, valAsBytes)
+	if err != nil {
+		return shim.Error(buildError(errorAnchorDocument))
+	}
+	return shim.Success(valAsBytes)
+}
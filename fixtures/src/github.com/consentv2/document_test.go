@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	DOCUMENTHASH = "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08"
+	DOCUMENTURI = "file:///var/ocms/documents/form.pdf"
+)
+
+// =====================================================================================================================
+// Anchor a consent form in a consent (nominal case)
+// =====================================================================================================================
+func TestConsentV2_AnchorDocumentNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res := stub.MockInvoke("2", [][]byte{[]byte("anchordocument"), []byte(APPID1), []byte("1"), []byte(DOCUMENTHASH), []byte(DOCUMENTURI), []byte("v1")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("1")})
+	consent := consent{}
+	json.Unmarshal(res.Payload, &consent)
+	if consent.DocumentHash != strings.ToLower(DOCUMENTHASH) || consent.DocumentURI != DOCUMENTURI || consent.DocumentVersion != "v1" {
+		t.Log("bad anchored document reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", [][]byte{[]byte("anchordocument"), []byte(APPID1), []byte("1"), []byte(DOCUMENTHASH), []byte(DOCUMENTURI), []byte("v1")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorDocumentAnchored) {
+		t.Log("bad response for the same version reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Anchor a document with a bad hash
+// =====================================================================================================================
+func TestConsentV2_AnchorDocumentWithBadHash(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res := stub.MockInvoke("2", [][]byte{[]byte("anchordocument"), []byte(APPID1), []byte("1"), []byte("abcd"), []byte(DOCUMENTURI), []byte("v1")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorDocumentHash) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Anchor a document in a consent of another application
+// =====================================================================================================================
+func TestConsentV2_AnchorDocumentWithAnotherAppID(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res := stub.MockInvoke("2", [][]byte{[]byte("anchordocument"), []byte(APPID2), []byte("1"), []byte(DOCUMENTHASH), []byte(DOCUMENTURI), []byte("v1")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentNotExist) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Read the anchored document of a revoked consent (state all)
+// =====================================================================================================================
+func TestConsentV2_GetRevokedConsentDocument(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("anchordocument"), []byte(APPID1), []byte("1"), []byte(DOCUMENTHASH), []byte(DOCUMENTURI), []byte("v1")})
+	stub.MockInvoke("3", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("1")})
+	res := stub.MockInvoke("4", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("1"), []byte(STATE_ALL)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	consent := consent{}
+	json.Unmarshal(res.Payload, &consent)
+	if consent.State != NOT_ACTIVE || consent.DocumentHash != strings.ToLower(DOCUMENTHASH) {
+		t.Log("bad revoked consent reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("5", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("1"), []byte(ACTIVE)})
+	if res.Status != shim.ERROR {
+		t.Log("bad status received for a bad state, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
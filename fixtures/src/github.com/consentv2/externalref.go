@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// External reference constantes
+// =====================================================================================================================
+const (
+	//Chainccode index
+	indexExternalRef = "app~ref"		// to get a consent from the reference of the client application
+
+	// Chaincode errors
+	errorExternalRefUsed      = "External reference already used by consent:"
+	errorExternalRefNotExist  = "External reference does not exist:"
+)
+
+// =====================================================================================================================
+// Get a Consent from appID and the external reference given at creation
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getconsentbyref","APPID","EXTERNALREF"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getConsentByRef(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, externalRef!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getConsentByRef(Appid:"+ args[0]+ " ExternalRef:"+ args[1]+") : calling method -")
+	consentID, err := getConsentIDByRef(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(buildError(errorExternalRefNotExist+ args[1]))
+	}
+	if consentID == "" {
+		return shim.Error(buildError(errorExternalRefNotExist+ args[1]))
+	}
+	consent, err := readConsent(stub, consentID)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	valAsBytes, err := json.Marshal(consent)
+	if err != nil {
+		return shim.Error(buildError(errorGetConsent+ consentID))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// retryConsent - return the consent already created with the external reference if it is the same consent: same
+// arguments, period, conditions and owner signature (the period of a consent merged in another one is included in
+// the period of this consent, the other options are the ones of this consent)
+// =====================================================================================================================
+func retryConsent(stub shim.ChaincodeStubInterface, consentID string, args []string, dt_begin, dt_end time.Time,
+	options consentOptions) pb.Response {
+	logger.Debug("retryConsent(ConsentID:"+ consentID+ " ExternalRef:"+ options.ExternalRef+") : calling method -")
+	existing, err := readConsent(stub, consentID)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if existing.OwnerID != args[1] || existing.ConsumerID != args[2] || existing.DataType != args[3] ||
+		existing.DataAccess != args[4] {
+		return shim.Error(buildError(errorExternalRefUsed+ consentID))
+	}
+	if existing.ExternalRef != options.ExternalRef {
+		if dt_begin.Before(existing.Dt_begin) || dt_end.After(existing.Dt_end) {
+			return shim.Error(buildError(errorExternalRefUsed+ consentID))
+		}
+		return shim.Success([]byte(consentID))
+	}
+	if !existing.Dt_begin.Equal(dt_begin) || !existing.Dt_end.Equal(dt_end) ||
+		existing.OwnerSignature != options.OwnerSignature || !sameConditions(existing.Conditions, options.Conditions) {
+		return shim.Error(buildError(errorExternalRefUsed+ consentID))
+	}
+	return shim.Success([]byte(consentID))
+}
+
+// =====================================================================================================================
+// sameConditions - true when the lists of conditions are equal (a missing list is an empty one)
+// =====================================================================================================================
+func sameConditions(a, b []condition) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// =====================================================================================================================
+// getConsentIDByRef - return the consentID of an external reference (empty if none)
+// =====================================================================================================================
+func getConsentIDByRef(stub shim.ChaincodeStubInterface, appID, externalRef string) (string, error) {
+	key, err := stub.CreateCompositeKey(indexExternalRef, []string{appID, externalRef})
+	if err != nil {
+		return "", err
+	}
+	valAsBytes, err := stub.GetState(key)
+	if err != nil {
+		return "", errors.New(errorExternalRefNotExist+ externalRef)
+	}
+	return string(valAsBytes), nil
+}
+
+// =====================================================================================================================
+// putExternalRef - index a consent by its external reference
+// =====================================================================================================================
+func putExternalRef(stub shim.ChaincodeStubInterface, appID, externalRef, consentID string) error {
+	key, err := stub.CreateCompositeKey(indexExternalRef, []string{appID, externalRef})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, []byte(consentID))
+}
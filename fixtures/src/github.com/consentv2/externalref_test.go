@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+const EXTERNALREF = "REF-0001"
+
+// =====================================================================================================================
+// Create a consent with an external reference and get it by reference (nominal case)
+// =====================================================================================================================
+func TestConsentV2_GetConsentByRefNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	stub.MockInvoke("1", postConsentArgs(args, []byte("{\"externalref\":\""+EXTERNALREF+"\"}")))
+	res := stub.MockInvoke("2", [][]byte{[]byte("getconsentbyref"), []byte(APPID1), []byte(EXTERNALREF)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	consent := consent{}
+	json.Unmarshal(res.Payload, &consent)
+	if consent.ConsentID != "1" || consent.ExternalRef != EXTERNALREF {
+		t.Log("bad consent reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("getconsentbyref"), []byte(APPID2), []byte(EXTERNALREF)})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorExternalRefNotExist) {
+		t.Log("bad response for another appID reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Retry a create with the same external reference
+// =====================================================================================================================
+func TestConsentV2_CreateConsentRetryWithRef(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	options := []byte("{\"externalref\":\""+EXTERNALREF+"\",\"mode\":\"reject\"}")
+	stub.MockInvoke("1", postConsentArgs(args, options))
+	res := stub.MockInvoke("2", postConsentArgs(args, options))
+	if res.Status != shim.OK || string(res.Payload) != "1" {
+		t.Log("bad response for a retry reveived: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+	args[2] = CONSUMERID2
+	res = stub.MockInvoke("3", postConsentArgs(args, options))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorExternalRefUsed+"1") {
+		t.Log("bad response for a reference used by another consent reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Retry a create with the same external reference and another period or other conditions
+// =====================================================================================================================
+func TestConsentV2_CreateConsentRetryWithRefAndOtherOptions(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	options := []byte("{\"externalref\":\""+EXTERNALREF+"\"}")
+	stub.MockInvoke("1", postConsentArgs(args, options))
+	args[6] = getStringDateNow(8)
+	res := stub.MockInvoke("2", postConsentArgs(args, options))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorExternalRefUsed+"1") {
+		t.Log("bad response for a retry with another period reveived: "+ res.Message)
+		t.FailNow()
+	}
+	args[6] = getStringDateNow(7)
+	conditions := []byte("{\"externalref\":\""+EXTERNALREF+"\",\"conditions\":[{\"attribute\":\"location\",\"operator\":\"eq\",\"value\":\"home\"}]}")
+	res = stub.MockInvoke("3", postConsentArgs(args, conditions))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorExternalRefUsed+"1") {
+		t.Log("bad response for a retry with other conditions reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", postConsentArgs(args, []byte("{\"externalref\":\""+EXTERNALREF+"\",\"conditions\":[]}")))
+	if res.Status != shim.OK || string(res.Payload) != "1" {
+		t.Log("bad response for a retry reveived: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Reset the consents of an application removes the external references
+// =====================================================================================================================
+func TestConsentV2_ResetConsentsWithRef(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	stub.MockInvoke("1", postConsentArgs(args, []byte("{\"externalref\":\""+EXTERNALREF+"\"}")))
+	stub.MockInvoke("2", [][]byte{[]byte("resetconsents"), []byte(APPID1)})
+	res := stub.MockInvoke("3", [][]byte{[]byte("getconsentbyref"), []byte(APPID1), []byte(EXTERNALREF)})
+	if res.Status != shim.ERROR {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
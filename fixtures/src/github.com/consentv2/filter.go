@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Consent filter constantes
+// =====================================================================================================================
+const (
+	STATE_ALL      = "all"		// consents of any state
+
+	// Chaincode errors
+	errorConsentFilter        = "Consent filter is not valid:"
+)
+
+// =====================================================================================================================
+// Optional argument of getconsents, getownerconsents and getconsumerconsents (json object)
+// DataType:      string: data type of the consents, exact match: the consents on a parent type are not listed
+//                        (isconsent follows the taxonomy to check if a data type is granted)
+// DataAccess:    string: data access of the consents
+// ValidAt:       string: the period of the consents includes this day (yyyy-mm-dd) or instant (RFC3339)
+// CreatedAfter:  string: the consents are created after this day (yyyy-mm-dd) or instant (RFC3339)
+// CreatedBefore: string: the consents are created before this day (yyyy-mm-dd) or instant (RFC3339)
+// State:         string: state of the consents: active (default), unactive or all
+// Sort:          string: attribute of the order of the list, descending order with a '-' prefix (ex: -createdat)
+// Fields:        list:   attributes of the returned consents (consentid is always returned), all if empty
+// =====================================================================================================================
+type consentFilter struct {
+	DataType	string     `json:"datatype,omitempty"`
+	DataAccess	string     `json:"dataaccess,omitempty"`
+	ValidAt		string     `json:"validat,omitempty"`
+	CreatedAfter	string     `json:"createdafter,omitempty"`
+	CreatedBefore	string     `json:"createdbefore,omitempty"`
+	State		string     `json:"state,omitempty"`
+	Sort		string     `json:"sort,omitempty"`
+	Fields		[]string   `json:"fields,omitempty"`
+	validAt		time.Time
+	createdAfter	time.Time
+	createdBefore	time.Time
+}
+
+// =====================================================================================================================
+// sortAttributes - attributes allowed to sort the consents
+// =====================================================================================================================
+var sortAttributes = map[string]func(a, b consent) bool{
+	"consentid":  func(a, b consent) bool { return a.ConsentID < b.ConsentID },
+	"ownerid":    func(a, b consent) bool { return a.OwnerID < b.OwnerID },
+	"consumerid": func(a, b consent) bool { return a.ConsumerID < b.ConsumerID },
+	"datatype":   func(a, b consent) bool { return a.DataType < b.DataType },
+	"dataaccess": func(a, b consent) bool { return a.DataAccess < b.DataAccess },
+	"state":      func(a, b consent) bool { return a.State < b.State },
+	"dtbegin":    func(a, b consent) bool { return a.Dt_begin.Before(b.Dt_begin) },
+	"dtend":      func(a, b consent) bool { return a.Dt_end.Before(b.Dt_end) },
+	"createdat":  func(a, b consent) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	"updatedat":  func(a, b consent) bool { return a.UpdatedAt.Before(b.UpdatedAt) },
+}
+
+// =====================================================================================================================
+// listConsents - list the consents of an index, filtered, sorted and reduced to the fields of the filter
+// the criteria are checked on each consent: the state key of the index is the state at the creation, a revoked
+// consent keeps its index
+// =====================================================================================================================
+func listConsents(stub shim.ChaincodeStubInterface, index string, keys []string, filterArg string, errorStr string) pb.Response {
+	filter, err := parseConsentFilter(filterArg)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	consents, err := getConsentsByIndex(stub, index, keys)
+	if err != nil {
+		return shim.Error(buildError(errorStr+" "+err.Error()))
+	}
+	filtered := make([]consent, 0)
+	for _, consent := range consents {
+		if filter.match(consent) {
+			filtered = append(filtered, consent)
+		}
+	}
+	filter.sort(filtered)
+	valAsBytes, err := filter.marshal(filtered)
+	if err != nil {
+		return shim.Error(buildError(errorStr+" "+err.Error()))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// parseConsentFilter - decode and check the optional filter argument (active consents without filter)
+// =====================================================================================================================
+func parseConsentFilter(filterArg string) (consentFilter, error) {
+	filter := consentFilter{}
+	if filterArg != "" {
+		err := json.Unmarshal([]byte(filterArg), &filter)
+		if err != nil {
+			return filter, errors.New(errorConsentFilter+" json object expected")
+		}
+	}
+	if filter.State == "" {
+		filter.State = ACTIVE
+	}
+	if filter.State != ACTIVE && filter.State != NOT_ACTIVE && filter.State != STATE_ALL {
+		return filter, errors.New(errorConsentFilter+" state must be "+ACTIVE+", "+NOT_ACTIVE+" or "+STATE_ALL)
+	}
+	var err error
+	if filter.validAt, err = parseFilterInstant(filter.ValidAt, false); err != nil {
+		return filter, errors.New(errorConsentFilter+" validat "+err.Error())
+	}
+	if filter.createdAfter, err = parseFilterInstant(filter.CreatedAfter, true); err != nil {
+		return filter, errors.New(errorConsentFilter+" createdafter "+err.Error())
+	}
+	if filter.createdBefore, err = parseFilterInstant(filter.CreatedBefore, false); err != nil {
+		return filter, errors.New(errorConsentFilter+" createdbefore "+err.Error())
+	}
+	if _, ok := sortAttributes[strings.TrimPrefix(filter.Sort, "-")]; filter.Sort != "" && !ok {
+		return filter, errors.New(errorConsentFilter+" unknown sort attribute "+filter.Sort)
+	}
+	attributes := consentAttributes()
+	for _, field := range filter.Fields {
+		if !attributes[field] {
+			return filter, errors.New(errorConsentFilter+" unknown field "+field)
+		}
+	}
+	return filter, nil
+}
+
+// =====================================================================================================================
+// parseFilterInstant - instant of a RFC3339 time or of a day (yyyy-mm-dd), the end of the day with endOfDay (a
+// consent created after a day is created after its end)
+// =====================================================================================================================
+func parseFilterInstant(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	instant, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return instant, nil
+	}
+	instant, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return instant, errors.New("must be a day (yyyy-mm-dd) or an instant (RFC3339)")
+	}
+	if endOfDay {
+		instant = instant.Add(24*time.Hour - time.Nanosecond)
+	}
+	return instant, nil
+}
+
+// =====================================================================================================================
+// match - true when the consent meets all the criteria of the filter
+// =====================================================================================================================
+func (f consentFilter) match(c consent) bool {
+	if f.State != STATE_ALL && c.State != f.State {
+		return false
+	}
+	if f.DataType != "" && c.DataType != f.DataType {
+		return false
+	}
+	if f.DataAccess != "" && c.DataAccess != f.DataAccess {
+		return false
+	}
+	// the period includes its first and last days
+	if !f.validAt.IsZero() && (f.validAt.Before(c.Dt_begin) || !f.validAt.Before(c.Dt_end.Add(24 * time.Hour))) {
+		return false
+	}
+	if !f.createdAfter.IsZero() && !c.CreatedAt.After(f.createdAfter) {
+		return false
+	}
+	if !f.createdBefore.IsZero() && !c.CreatedAt.Before(f.createdBefore) {
+		return false
+	}
+	return true
+}
+
+// =====================================================================================================================
+// consentSorter - stable sort of the consents on an attribute (the order of the index for the equal values)
+// =====================================================================================================================
+type consentSorter struct {
+	consents	[]consent
+	less		func(a, b consent) bool
+}
+
+func (s consentSorter) Len() int           { return len(s.consents) }
+func (s consentSorter) Swap(i, j int)      { s.consents[i], s.consents[j] = s.consents[j], s.consents[i] }
+func (s consentSorter) Less(i, j int) bool { return s.less(s.consents[i], s.consents[j]) }
+
+func (f consentFilter) sort(consents []consent) {
+	if f.Sort == "" {
+		return
+	}
+	less := sortAttributes[strings.TrimPrefix(f.Sort, "-")]
+	if strings.HasPrefix(f.Sort, "-") {
+		ascending := less
+		less = func(a, b consent) bool { return ascending(b, a) }
+	}
+	sort.Stable(consentSorter{consents: consents, less: less})
+}
+
+// =====================================================================================================================
+// marshal - json of the consents, reduced to the fields of the filter
+// =====================================================================================================================
+func (f consentFilter) marshal(consents []consent) ([]byte, error) {
+	if len(f.Fields) == 0 {
+		return json.Marshal(consents)
+	}
+	selected := make([]map[string]interface{}, 0, len(consents))
+	for _, consent := range consents {
+		valAsBytes, err := json.Marshal(consent)
+		if err != nil {
+			return nil, err
+		}
+		record := map[string]interface{}{}
+		json.Unmarshal(valAsBytes, &record)
+		fields := map[string]interface{}{"consentid": consent.ConsentID}
+		for _, field := range f.Fields {
+			if value, ok := record[field]; ok {
+				fields[field] = value
+			}
+		}
+		selected = append(selected, fields)
+	}
+	return json.Marshal(selected)
+}
+
+// =====================================================================================================================
+// consentAttributes - json attributes of a consent
+// =====================================================================================================================
+func consentAttributes() map[string]bool {
+	attributes := map[string]bool{}
+	consentType := reflect.TypeOf(consent{})
+	for i := 0; i < consentType.NumField(); i++ {
+		name := strings.Split(consentType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			attributes[name] = true
+		}
+	}
+	return attributes
+}
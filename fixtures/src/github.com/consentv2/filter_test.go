@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strings"
+	"encoding/json"
+)
+
+// =====================================================================================================================
+// newFilterStub - consents of OWNERID1: 1 (BP, active today), 2 (WS, from day 10 to 20), 3 (BP, revoked)
+// and of OWNERID2: 4 (BP, active today)
+// =====================================================================================================================
+func newFilterStub() *shim.MockStub {
+	stub := shim.NewMockStub("consentv2", new(ConsentCC))
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE2), []byte(DATAACCESS2), []byte(getStringDateNow(10)), []byte(getStringDateNow(20))})
+	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS2), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("4", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("3")})
+	stub.MockInvoke("5", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS2), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	return stub
+}
+
+// =====================================================================================================================
+// listConsentIDs - ids of the consents listed by a function with a filter
+// =====================================================================================================================
+func listConsentIDs(t *testing.T, stub *shim.MockStub, args ...string) []string {
+	bargs := [][]byte{}
+	for _, arg := range args {
+		bargs = append(bargs, []byte(arg))
+	}
+	res := stub.MockInvoke("list", bargs)
+	if res.Status != shim.OK {
+		t.Log("bad status for "+ strings.Join(args, " ")+ ": "+ res.Message)
+		t.FailNow()
+	}
+	consents := []consent{}
+	json.Unmarshal(res.Payload, &consents)
+	consentIDs := []string{}
+	for _, consent := range consents {
+		consentIDs = append(consentIDs, consent.ConsentID)
+	}
+	return consentIDs
+}
+
+// =====================================================================================================================
+// Filter the consents of an appID, an owner and a consumer
+// =====================================================================================================================
+func TestConsentV2_ListConsentsFilter(t *testing.T) {
+	stub := newFilterStub()
+	cases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"getconsents", APPID1}, "1,2,5"},
+		{[]string{"getconsents", APPID1, "ALLM"}, "1,2,5"},
+		{[]string{"getconsents", APPID1, "{\"datatype\":\""+ DATATYPE1+ "\"}"}, "1,5"},
+		{[]string{"getconsents", APPID1, "{\"dataaccess\":\""+ DATAACCESS2+ "\",\"state\":\"all\"}"}, "2,3,5"},
+		{[]string{"getconsents", APPID1, "{\"state\":\"unactive\"}"}, "3"},
+		{[]string{"getconsents", APPID1, "{\"validat\":\""+ getStringDateNow(15)+ "\"}"}, "2"},
+		{[]string{"getconsents", APPID1, "{\"validat\":\""+ getStringDateNow(0)+ "\"}"}, "1,5"},
+		{[]string{"getconsents", APPID1, "{\"createdafter\":\"2000-01-01\",\"createdbefore\":\"2100-01-01T00:00:00Z\"}"}, "1,2,5"},
+		{[]string{"getconsents", APPID1, "{\"createdbefore\":\"2000-01-01\"}"}, ""},
+		{[]string{"getconsents", APPID1, "{\"sort\":\"-dtbegin\"}"}, "2,1,5"},
+		{[]string{"getownerconsents", APPID1, OWNERID1, "{\"state\":\"all\",\"sort\":\"consumerid\"}"}, "1,2,3"},
+		{[]string{"getconsumerconsents", APPID1, CONSUMERID1, "{\"dataaccess\":\""+ DATAACCESS2+ "\"}"}, "5"},
+	}
+	for _, c := range cases {
+		consentIDs := strings.Join(listConsentIDs(t, stub, c.args...), ",")
+		if consentIDs != c.expected {
+			t.Log("bad consents for "+ strings.Join(c.args, " ")+ ": "+ consentIDs+ " expected: "+ c.expected)
+			t.FailNow()
+		}
+	}
+}
+
+// =====================================================================================================================
+// The data type of the filter is not followed in the taxonomy: a consent on CAR grants HR but is not listed for HR
+// =====================================================================================================================
+func TestConsentV2_ListConsentsFilterExactDataType(t *testing.T) {
+	stub := shim.NewMockStub("consentv2", new(ConsentCC))
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("CAR"), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	if consentIDs := strings.Join(listConsentIDs(t, stub, "getconsents", APPID1, "{\"datatype\":\"HR\"}"), ","); consentIDs != "" {
+		t.Error("consent on the parent data type listed: " + consentIDs)
+	}
+	if consentIDs := strings.Join(listConsentIDs(t, stub, "getconsents", APPID1, "{\"datatype\":\"CAR\"}"), ","); consentIDs != "1" {
+		t.Error("consent on the data type not listed: " + consentIDs)
+	}
+}
+
+// =====================================================================================================================
+// Select the fields of the consents, reject a bad filter
+// =====================================================================================================================
+func TestConsentV2_ListConsentsFields(t *testing.T) {
+	stub := newFilterStub()
+	res := stub.MockInvoke("6", [][]byte{[]byte("getownerconsents"), []byte(APPID1), []byte(OWNERID2), []byte("{\"fields\":[\"datatype\",\"createdat\"]}")})
+	records := []map[string]interface{}{}
+	json.Unmarshal(res.Payload, &records)
+	if res.Status != shim.OK || len(records) != 1 || len(records[0]) != 3 || records[0]["consentid"] != "5" ||
+		records[0]["datatype"] != DATATYPE1 || records[0]["createdat"] == nil {
+		t.Log("bad fields received: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+	for _, filter := range []string{"{\"sort\":\"label\"}", "{\"fields\":[\"label\"]}", "{\"state\":\"pending\"}",
+		"{\"validat\":\"tomorrow\"}", "datatype"} {
+		res = stub.MockInvoke("7", [][]byte{[]byte("getconsents"), []byte(APPID1), []byte(filter)})
+		if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentFilter) {
+			t.Log("bad filter accepted: "+ filter+ " "+ res.Message)
+			t.FailNow()
+		}
+	}
+}
+
+// =====================================================================================================================
+// The creation time of a consent written before the schema version 3 is its last modification time
+// =====================================================================================================================
+func TestConsentV2_SchemaCreatedAtMigration(t *testing.T) {
+	stub := shim.NewMockStub("consentv2", new(ConsentCC))
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	record := map[string]interface{}{}
+	json.Unmarshal(stub.State["1"], &record)
+	delete(record, "createdat")
+	record["updatedat"] = "2017-03-09T10:00:00Z"
+	record["schemaversion"] = 2
+	valAsBytes, _ := json.Marshal(record)
+	stub.MockTransactionStart("v2")
+	stub.PutState("1", valAsBytes)
+	stub.MockTransactionEnd("v2")
+
+	res := stub.MockInvoke("2", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("1")})
+	upgraded := consent{}
+	json.Unmarshal(res.Payload, &upgraded)
+	if upgraded.SchemaVersion != CONSENT_SCHEMA_VERSION || upgraded.CreatedAt.Format("2006-01-02") != "2017-03-09" {
+		t.Log("bad upgraded consent reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Consumer group constantes
+// =====================================================================================================================
+const (
+	// a consent given to a group has a consumerID "group:GROUPID"
+	GROUP_PREFIX = "group:"
+
+	//Chainccode index
+	indexGroup        = "app~group"			// to get a consumer group of an appID
+	indexGroupMember  = "app~group~member"		// to get the members of a group
+	indexMemberGroup  = "app~member~group"		// to get the groups of a consumer
+
+	// Chaincode errors
+	errorCreateGroup          = "Create group:"
+	errorGroupExist           = "Group already exists:"
+	errorGroupNotExist        = "Group does not exist:"
+	errorUpdateGroup          = "Update members of group:"
+	errorGetGroup             = "Get group:"
+)
+
+// =====================================================================================================================
+// AppID:   string: id of the client application
+// GroupID: string: id of the group, unique per appID
+// Label:   string: label of the group (care team, department...)
+// Members: list:   consumerIDs of the members (only filled when the group is read)
+// =====================================================================================================================
+type group struct {
+	AppID 		string     `json:"appid"`
+	GroupID		string     `json:"groupid"`
+	Label		string     `json:"label"`
+	Members		[]string   `json:"members,omitempty"`
+}
+
+// =====================================================================================================================
+// Create a consumer group
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["creategroup","APPID","GROUPID","LABEL"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)createGroup(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, groupID, label!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("createGroup(Appid:"+ args[0]+ " GroupID:"+ args[1]+ " Label:"+ args[2]+") : calling method -")
+	if args[1] == "" {
+		return shim.Error(buildError(errorCreateGroup+ args[1]))
+	}
+	existing, err := readGroup(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(buildError(errorCreateGroup+ args[1]))
+	}
+	if existing != nil {
+		return shim.Error(buildError(errorGroupExist+ args[1]))
+	}
+	consumerGroup := group{AppID: args[0], GroupID: args[1], Label: args[2]}
+	valAsBytes, err := json.Marshal(consumerGroup)
+	if err != nil {
+		return shim.Error(buildError(errorCreateGroup+ args[1]))
+	}
+	groupKey, err := stub.CreateCompositeKey(indexGroup, []string{args[0], args[1]})
+	if err != nil {
+		return shim.Error(buildError(errorCreateGroup+ args[1]))
+	}
+	err = stub.PutState(groupKey, valAsBytes)
+	if err != nil {
+		return shim.Error(buildError(errorCreateGroup+ args[1]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Add a member to a consumer group (the consents given to the group apply to the member)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["addgroupmember","APPID","GROUPID","CONSUMERID"]}'
+// 	-o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)addGroupMember(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, groupID, consumerID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("addGroupMember(Appid:"+ args[0]+ " GroupID:"+ args[1]+ " ConsumerID:"+ args[2]+") : calling method -")
+	err := checkGroup(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	if args[2] == "" || isGroupReference(args[2]) {
+		return shim.Error(buildError(errorUpdateGroup+ args[1]))
+	}
+	err = putMembership(stub, args[0], args[1], args[2], []byte{0x00})
+	if err != nil {
+		return shim.Error(buildError(errorUpdateGroup+ args[1]))
+	}
+	return shim.Success(nil)
+}
+
+// =====================================================================================================================
+// Remove a member from a consumer group
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["removegroupmember","APPID","GROUPID","CONSUMERID"]}'
+// 	-o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)removeGroupMember(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, groupID, consumerID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("removeGroupMember(Appid:"+ args[0]+ " GroupID:"+ args[1]+ " ConsumerID:"+ args[2]+") : calling method -")
+	err := checkGroup(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	err = putMembership(stub, args[0], args[1], args[2], nil)
+	if err != nil {
+		return shim.Error(buildError(errorUpdateGroup+ args[1]))
+	}
+	return shim.Success(nil)
+}
+
+// =====================================================================================================================
+// Get a consumer group with its members
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getgroupmembers","APPID","GROUPID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getGroupMembers(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, groupID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getGroupMembers(Appid:"+ args[0]+ " GroupID:"+ args[1]+") : calling method -")
+	consumerGroup, err := readGroup(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(buildError(errorGetGroup+ args[1]))
+	}
+	if consumerGroup == nil {
+		return shim.Error(buildError(errorGroupNotExist+ args[1]))
+	}
+	consumerGroup.Members, err = getLastKeyParts(stub, indexGroupMember, []string{args[0], args[1]})
+	if err != nil {
+		return shim.Error(buildError(errorGetGroup+ args[1]))
+	}
+	valAsBytes, err := json.Marshal(consumerGroup)
+	if err != nil {
+		return shim.Error(buildError(errorGetGroup+ args[1]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// getConsumerReferences - the consumerID and the references of the groups of the consumer, membership is resolved
+// when the consent is evaluated
+// =====================================================================================================================
+func getConsumerReferences(stub shim.ChaincodeStubInterface, appID, consumerID string) ([]string, error) {
+	groupIDs, err := getLastKeyParts(stub, indexMemberGroup, []string{appID, consumerID})
+	if err != nil {
+		return nil, err
+	}
+	references := []string{consumerID}
+	for _, groupID := range groupIDs {
+		references = append(references, GROUP_PREFIX+ groupID)
+	}
+	return references, nil
+}
+
+// =====================================================================================================================
+// isGroupReference - true if the consumerID of a consent references a group
+// =====================================================================================================================
+func isGroupReference(consumerID string) bool {
+	return strings.HasPrefix(consumerID, GROUP_PREFIX)
+}
+
+// =====================================================================================================================
+// checkConsumer - verify that the group referenced by the consumerID of a consent exists
+// =====================================================================================================================
+func checkConsumer(stub shim.ChaincodeStubInterface, appID, consumerID string) error {
+	if !isGroupReference(consumerID) {
+		return nil
+	}
+	return checkGroup(stub, appID, strings.TrimPrefix(consumerID, GROUP_PREFIX))
+}
+
+// =====================================================================================================================
+// checkGroup - verify that a group exists
+// =====================================================================================================================
+func checkGroup(stub shim.ChaincodeStubInterface, appID, groupID string) error {
+	consumerGroup, err := readGroup(stub, appID, groupID)
+	if err != nil {
+		return errors.New(errorGetGroup+ groupID)
+	}
+	if consumerGroup == nil {
+		return errors.New(errorGroupNotExist+ groupID)
+	}
+	return nil
+}
+
+// =====================================================================================================================
+// putMembership - write (value not nil) or delete (value nil) the membership indexes of a consumer
+// =====================================================================================================================
+func putMembership(stub shim.ChaincodeStubInterface, appID, groupID, consumerID string, value []byte) error {
+	memberKey, err := stub.CreateCompositeKey(indexGroupMember, []string{appID, groupID, consumerID})
+	if err != nil {
+		return err
+	}
+	groupKey, err := stub.CreateCompositeKey(indexMemberGroup, []string{appID, consumerID, groupID})
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		err = stub.DelState(memberKey)
+		if err != nil {
+			return err
+		}
+		return stub.DelState(groupKey)
+	}
+	err = stub.PutState(memberKey, value)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(groupKey, value)
+}
+
+// =====================================================================================================================
+// getLastKeyParts - last attribute of all composite keys of an index matching the partial key
+// =====================================================================================================================
+func getLastKeyParts(stub shim.ChaincodeStubInterface, index string, keys []string) ([]string, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(index, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	parts := make([]string, 0)
+	for resultsIterator.HasNext() {
+		indexKey, _, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(indexKey)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, compositeKeyParts[len(compositeKeyParts) - 1])
+	}
+	return parts, nil
+}
+
+// =====================================================================================================================
+// readGroup - read a group from the ledger, nil if the group does not exist
+// =====================================================================================================================
+func readGroup(stub shim.ChaincodeStubInterface, appID, groupID string) (*group, error) {
+	groupKey, err := stub.CreateCompositeKey(indexGroup, []string{appID, groupID})
+	if err != nil {
+		return nil, err
+	}
+	valAsBytes, err := stub.GetState(groupKey)
+	if err != nil {
+		return nil, err
+	}
+	if valAsBytes == nil {
+		return nil, nil
+	}
+	consumerGroup := group{}
+	err = json.Unmarshal(valAsBytes, &consumerGroup)
+	if err != nil {
+		return nil, err
+	}
+	return &consumerGroup, nil
+}
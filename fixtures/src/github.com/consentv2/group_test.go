@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+)
+
+const (
+	GROUPID1 = "careteam"
+)
+
+// =====================================================================================================================
+// Create a group, add and remove members, list members (nominal case)
+// =====================================================================================================================
+func TestConsentV2_GroupMembersNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("creategroup"), []byte(APPID1), []byte(GROUPID1), []byte("care team")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	stub.MockInvoke("2", [][]byte{[]byte("addgroupmember"), []byte(APPID1), []byte(GROUPID1), []byte(CONSUMERID1)})
+	stub.MockInvoke("3", [][]byte{[]byte("addgroupmember"), []byte(APPID1), []byte(GROUPID1), []byte(CONSUMERID2)})
+	stub.MockInvoke("4", [][]byte{[]byte("removegroupmember"), []byte(APPID1), []byte(GROUPID1), []byte(CONSUMERID1)})
+	res = stub.MockInvoke("5", [][]byte{[]byte("getgroupmembers"), []byte(APPID1), []byte(GROUPID1)})
+	consumerGroup := group{}
+	json.Unmarshal(res.Payload, &consumerGroup)
+	if len(consumerGroup.Members) != 1 || consumerGroup.Members[0] != CONSUMERID2 {
+		t.Log("bad members reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("6", [][]byte{[]byte("creategroup"), []byte(APPID1), []byte(GROUPID1), []byte("care team")})
+	if res.Status == shim.OK {
+		t.Log("bad status received for a duplicated group, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Add a member to a group that does not exist
+// =====================================================================================================================
+func TestConsentV2_AddMemberGroupNotExist(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("addgroupmember"), []byte(APPID1), []byte(GROUPID1), []byte(CONSUMERID1)})
+	if res.Status == shim.OK {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(GROUP_PREFIX+ GROUPID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	if res.Status == shim.OK {
+		t.Log("bad status received for a consent to an unknown group, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Verify a consent given to a group follows the membership of the group without re-consenting
+// =====================================================================================================================
+func TestConsentV2_IsConsentForGroupMember(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("creategroup"), []byte(APPID1), []byte(GROUPID1), []byte("care team")})
+	res := stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(GROUP_PREFIX+ GROUPID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if string(res.Payload) != NOT_AUTHORIZED {
+		t.Log("bad consent status before membership reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	stub.MockInvoke("4", [][]byte{[]byte("addgroupmember"), []byte(APPID1), []byte(GROUPID1), []byte(CONSUMERID1)})
+	res = stub.MockInvoke("5", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if string(res.Payload) != AUTHORIZED {
+		t.Log("bad consent status for a member reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	stub.MockInvoke("6", [][]byte{[]byte("removegroupmember"), []byte(APPID1), []byte(GROUPID1), []byte(CONSUMERID1)})
+	res = stub.MockInvoke("7", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1)})
+	if string(res.Payload) != NOT_AUTHORIZED {
+		t.Log("bad consent status after membership reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
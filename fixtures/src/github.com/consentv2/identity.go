@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/msp"
+)
+
+// =====================================================================================================================
+// Identity constantes
+// =====================================================================================================================
+const (
+	UNKNOWN_IDENTITY = "unknown"
+
+	// Chaincode errors
+	errorCreator  = "Get creator identity!"
+)
+
+// =====================================================================================================================
+// getCreatorCertificate - decode the certificate of the identity submitting the transaction
+// =====================================================================================================================
+func getCreatorCertificate(stub shim.ChaincodeStubInterface) (*x509.Certificate, error) {
+	logger.Debug("getCreatorCertificate() : calling method -")
+	creator, err := stub.GetCreator()
+	if err != nil || creator == nil {
+		return nil, errors.New(errorCreator)
+	}
+	identity := &msp.SerializedIdentity{}
+	err = proto.Unmarshal(creator, identity)
+	if err != nil {
+		logger.Error("Failed to unmarshal creator: " + err.Error())
+		return nil, errors.New(errorCreator)
+	}
+	return parseCertificate(identity.IdBytes)
+}
+
+// =====================================================================================================================
+// parseCertificate - decode a PEM x509 certificate
+// =====================================================================================================================
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New(errorCreator)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		logger.Error("Failed to parse certificate: " + err.Error())
+		return nil, errors.New(errorCreator)
+	}
+	return cert, nil
+}
+
+// =====================================================================================================================
+// getCreatorName - return the common name of the identity submitting the transaction
+// =====================================================================================================================
+func getCreatorName(stub shim.ChaincodeStubInterface) string {
+	cert, err := getCreatorCertificate(stub)
+	if err != nil {
+		return UNKNOWN_IDENTITY
+	}
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"encoding/json"
+	"math/big"
+	"time"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/consentv2/certattr"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// identityStub - MockStub with the identity of the creator of the transaction, the transaction time and the history
+// of the keys (not supported by the MockStub)
+// =====================================================================================================================
+type identityStub struct {
+	*shim.MockStub
+	creator []byte
+	args    [][]byte
+	txTime  *timestamp.Timestamp
+	history map[string][]historyEntry
+}
+
+type historyEntry struct {
+	txID  string
+	value []byte
+}
+
+func newIdentityStub(name string) *identityStub {
+	stub := &identityStub{MockStub: shim.NewMockStub(name, new(ConsentCC)), history: map[string][]historyEntry{}}
+	stub.MockInit("0", nil)
+	return stub
+}
+
+func (stub *identityStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return stub.txTime, nil
+}
+
+func (stub *identityStub) PutState(key string, value []byte) error {
+	err := stub.MockStub.PutState(key, value)
+	if err == nil {
+		stub.history[key] = append(stub.history[key], historyEntry{txID: stub.TxID, value: value})
+	}
+	return err
+}
+
+func (stub *identityStub) DelState(key string) error {
+	err := stub.MockStub.DelState(key)
+	if err == nil {
+		stub.history[key] = append(stub.history[key], historyEntry{txID: stub.TxID})
+	}
+	return err
+}
+
+func (stub *identityStub) GetHistoryForKey(key string) (shim.StateQueryIteratorInterface, error) {
+	return &historyIterator{entries: stub.history[key]}, nil
+}
+
+// =====================================================================================================================
+// historyIterator - iterator over the values written for a key (key of each entry is the txID)
+// =====================================================================================================================
+type historyIterator struct {
+	entries []historyEntry
+	current int
+}
+
+func (iter *historyIterator) HasNext() bool {
+	return iter.current < len(iter.entries)
+}
+
+func (iter *historyIterator) Next() (string, []byte, error) {
+	entry := iter.entries[iter.current]
+	iter.current++
+	return entry.txID, entry.value, nil
+}
+
+func (iter *historyIterator) Close() error {
+	return nil
+}
+
+// =====================================================================================================================
+// mockInvokeAt - invoke the chaincode with a transaction time
+// =====================================================================================================================
+func (stub *identityStub) mockInvokeAt(uuid string, txTime time.Time, args [][]byte) pb.Response {
+	stub.txTime = &timestamp.Timestamp{Seconds: txTime.Unix(), Nanos: int32(txTime.Nanosecond())}
+	res := stub.mockInvokeAs(uuid, "user1", nil, args)
+	stub.txTime = nil
+	return res
+}
+
+func (stub *identityStub) GetCreator() ([]byte, error) {
+	return stub.creator, nil
+}
+
+func (stub *identityStub) GetArgs() [][]byte {
+	return stub.args
+}
+
+func (stub *identityStub) GetStringArgs() []string {
+	strargs := make([]string, 0, len(stub.args))
+	for _, barg := range stub.args {
+		strargs = append(strargs, string(barg))
+	}
+	return strargs
+}
+
+func (stub *identityStub) GetFunctionAndParameters() (string, []string) {
+	allargs := stub.GetStringArgs()
+	if len(allargs) == 0 {
+		return "", []string{}
+	}
+	return allargs[0], allargs[1:]
+}
+
+// =====================================================================================================================
+// mockInvokeAs - invoke the chaincode with the identity (common name, attributes) as creator
+// =====================================================================================================================
+func (stub *identityStub) mockInvokeAs(uuid, name string, attrs map[string]string, args [][]byte) pb.Response {
+	stub.creator = buildCreator(name, attrs)
+	stub.args = args
+	stub.MockTransactionStart(uuid)
+	res := new(ConsentCC).Invoke(stub)
+	stub.MockTransactionEnd(uuid)
+	return res
+}
+
+// =====================================================================================================================
+// buildCreator - build a serialized identity with a self signed certificate carrying fabric-ca attributes
+// =====================================================================================================================
+func buildCreator(name string, attrs map[string]string) []byte {
+	_, certPEM := buildCertificate(name, attrs)
+	identity := &msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: certPEM}
+	creator, _ := proto.Marshal(identity)
+	return creator
+}
+
+// =====================================================================================================================
+// buildCertificate - build a key and a self signed PEM certificate carrying fabric-ca attributes
+// =====================================================================================================================
+func buildCertificate(name string, attrs map[string]string) (*ecdsa.PrivateKey, []byte) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if attrs != nil {
+		value, _ := json.Marshal(certattr.Attributes{Attrs: attrs})
+		template.ExtraExtensions = []pkix.Extension{{Id: certattr.OID, Value: value}}
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+func postConsentWithMode(stub *shim.MockStub, uuid, dt_begin, dt_end, mode string) pb.Response {
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, dt_begin, dt_end}
+	return stub.MockInvoke(uuid, postConsentArgs(args, []byte("{\"mode\":\""+mode+"\"}")))
+}
+
+// =====================================================================================================================
+// Create an overlapping consent in reject mode
+// =====================================================================================================================
+func TestConsentV2_CreateOverlappingConsentRejected(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	postConsentWithMode(stub, "1", getStringDateNow(0), getStringDateNow(7), MODE_REJECT)
+	res := postConsentWithMode(stub, "2", getStringDateNow(5), getStringDateNow(10), MODE_REJECT)
+	if res.Status != shim.ERROR {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	if !strings.Contains(res.Message, errorConsentOverlap+"1") {
+		t.Log("Bad return message, expected:"+errorConsentOverlap+"1 reveived:"+string(res.Message))
+		t.FailNow()
+	}
+	res = postConsentWithMode(stub, "3", getStringDateNow(9), getStringDateNow(10), MODE_REJECT)
+	if res.Status != shim.OK {
+		t.Log("bad status received for a disjoint period, expected: 200 received:"+
+			strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create an overlapping consent in merge mode
+// =====================================================================================================================
+func TestConsentV2_CreateOverlappingConsentMerged(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	postConsentWithMode(stub, "1", getStringDateNow(0), getStringDateNow(7), MODE_MERGE)
+	res := postConsentWithMode(stub, "2", getStringDateNow(5), getStringDateNow(10), MODE_MERGE)
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	if string(res.Payload) != "1" {
+		t.Log("bad consentID received, expected: 1 received:"+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("getconsents"), []byte(APPID1)})
+	consents := []consent{}
+	json.Unmarshal(res.Payload, &consents)
+	if len(consents) != 1 {
+		t.Log("bad number of consents, expected: 1 received:"+ strconv.Itoa(len(consents)))
+		t.FailNow()
+	}
+	_, dt_end, _ := checkDates(getStringDateNow(0), getStringDateNow(10))
+	if !consents[0].Dt_end.Equal(dt_end) {
+		t.Log("bad merged period received: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create an overlapping consent in allow mode and with a bad mode
+// =====================================================================================================================
+func TestConsentV2_CreateOverlappingConsentAllowed(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	postConsentWithMode(stub, "1", getStringDateNow(0), getStringDateNow(7), MODE_ALLOW)
+	res := postConsentWithMode(stub, "2", getStringDateNow(0), getStringDateNow(7), MODE_ALLOW)
+	if res.Status != shim.OK || string(res.Payload) != "2" {
+		t.Log("bad response received: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+	res = postConsentWithMode(stub, "3", getStringDateNow(0), getStringDateNow(7), "replace")
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentOptions) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Recreate a revoked consent in reject and merge modes: the revoked consent does not overlap and is not merged
+// =====================================================================================================================
+func TestConsentV2_RecreateRevokedConsent(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	postConsentWithMode(stub, "1", getStringDateNow(0), getStringDateNow(7), MODE_REJECT)
+	res := stub.MockInvoke("2", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("1")})
+	if res.Status != shim.OK {
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = postConsentWithMode(stub, "3", getStringDateNow(0), getStringDateNow(7), MODE_REJECT)
+	if res.Status != shim.OK || string(res.Payload) != "3" {
+		t.Log("bad response received: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+	stub.MockInvoke("4", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("3")})
+	res = postConsentWithMode(stub, "5", getStringDateNow(5), getStringDateNow(10), MODE_MERGE)
+	if res.Status != shim.OK || string(res.Payload) != "5" {
+		t.Log("bad response received: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("6", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("1"), []byte(STATE_ALL)})
+	revoked := consent{}
+	json.Unmarshal(res.Payload, &revoked)
+	if revoked.State != NOT_ACTIVE {
+		t.Log("the revoked consent is reactivated: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Owner key constantes
+// =====================================================================================================================
+const (
+	//Chainccode index
+	indexOwnerKey = "app~ownerkey"		// to get the certificate of an owner
+	indexOwnerNonce = "app~ownernonce"	// nonces of the signed consents already used
+
+	// Chaincode errors
+	errorRegisterOwnerKey     = "Register owner key!"
+	errorOwnerCert            = "Owner certificate is not a valid ECDSA certificate!"
+	errorOwnerKeyNotExist     = "Owner key is not registered:"
+	errorOwnerCertNotMatch    = "Owner certificate does not match the registered key:"
+	errorOwnerSignature       = "Owner signature is not valid:"
+	errorOwnerKeyExist        = "Owner key already exists, rotate it with a signature of the registered key:"
+	errorOwnerKeyRegistrant   = "Owner key can only be rotated by its registrant:"
+	errorOwnerCertSubject     = "Owner certificate is not issued to the owner:"
+	errorOwnerNonce           = "Owner nonce is mandatory:"
+	errorOwnerNonceUsed       = "Owner nonce is already used:"
+)
+
+// =====================================================================================================================
+// AppID:       string: id of the client application
+// OwnerID:     string: id of the data owner
+// Certificate: string: PEM certificate of the owner used to verify the signed consents
+// Registrant:  string: common name of the identity which registered the key (the only one allowed to rotate it)
+// =====================================================================================================================
+type ownerKey struct {
+	AppID 		string     `json:"appid"`
+	OwnerID       	string     `json:"ownerid"`
+	Certificate	string     `json:"certificate"`
+	Registrant	string     `json:"registrant"`
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// =====================================================================================================================
+// Register the certificate of an owner, the certificate is issued to the owner and the key is bound to the identity
+// submitting the transaction. A registered key is never overwritten: it is rotated by its registrant with the
+// signature (base64 ASN.1 ECDSA) of the new certificate by the registered key
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["registerownerkey","APPID","OWNERID","CERTPEM"]}'
+// 							-o 127.0.0.1:7050
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["registerownerkey","APPID","OWNERID","CERTPEM","SIGNATURE"]}'
+// 							-o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)registerOwnerKey(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 && len(args) != 4 {
+		errStr := errorArgs+" expecting appID, ownerID, certificate, [signature]!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("registerOwnerKey(Appid:"+ args[0]+ " Ownerid:"+ args[1]+") : calling method -")
+	cert, err := parseCertificate([]byte(args[2]))
+	if err != nil {
+		return shim.Error(buildError(errorOwnerCert))
+	}
+	if _, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok {
+		return shim.Error(buildError(errorOwnerCert))
+	}
+	if cert.Subject.CommonName != args[1] {
+		return shim.Error(buildError(errorOwnerCertSubject+ args[1]))
+	}
+	creator, err := getCreatorCertificate(stub)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	registered, err := readOwnerKey(stub, args[0], args[1])
+	if err == nil {
+		if registered.Registrant != creator.Subject.CommonName {
+			return shim.Error(buildError(errorOwnerKeyRegistrant+ args[1]))
+		}
+		if len(args) != 4 {
+			return shim.Error(buildError(errorOwnerKeyExist+ args[1]))
+		}
+		current, err := parseCertificate([]byte(registered.Certificate))
+		if err != nil {
+			return shim.Error(buildError(errorOwnerCert))
+		}
+		if !verifySignature(current, args[3], []byte(args[2])) {
+			return shim.Error(buildError(errorOwnerSignature+ args[1]))
+		}
+	} else if !strings.HasPrefix(err.Error(), errorOwnerKeyNotExist) {
+		return shim.Error(buildError(errorRegisterOwnerKey))
+	}
+	key, err := stub.CreateCompositeKey(indexOwnerKey, []string{args[0], args[1]})
+	if err != nil {
+		return shim.Error(buildError(errorRegisterOwnerKey))
+	}
+	valAsBytes, err := json.Marshal(ownerKey{AppID: args[0], OwnerID: args[1], Certificate: args[2], Registrant: creator.Subject.CommonName})
+	if err != nil {
+		return shim.Error(buildError(errorRegisterOwnerKey))
+	}
+	err = stub.PutState(key, valAsBytes)
+	if err != nil {
+		return shim.Error(buildError(errorRegisterOwnerKey))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Get the registered certificate of an owner
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getownerkey","APPID","OWNERID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getOwnerKey(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" expecting appID, ownerID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getOwnerKey(Appid:"+ args[0]+ " Ownerid:"+ args[1]+") : calling method -")
+	registered, err := readOwnerKey(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	valAsBytes, err := json.Marshal(registered)
+	if err != nil {
+		return shim.Error(buildError(errorOwnerKeyNotExist+ args[1]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// canonicalConsentPayload - payload signed by the owner, the json array of the arguments of postconsent and of the
+// nonce: ["appID","ownerID","consumerID","dataType","dataAccess","dt_begin","dt_end","nonce"]
+// =====================================================================================================================
+func canonicalConsentPayload(args []string, nonce string) []byte {
+	payload, _ := json.Marshal(append(append([]string{}, args...), nonce))
+	return payload
+}
+
+// =====================================================================================================================
+// verifyOwnerSignature - verify the signature of the payload with the registered key of the owner and use the nonce
+// (a signed payload is accepted once)
+// =====================================================================================================================
+func verifyOwnerSignature(stub shim.ChaincodeStubInterface, appID, ownerID string, args []string, options consentOptions) error {
+	logger.Debug("verifyOwnerSignature(Appid:"+ appID+ " Ownerid:"+ ownerID+") : calling method -")
+	if options.Nonce == "" {
+		return errors.New(errorOwnerNonce+ ownerID)
+	}
+	registered, err := readOwnerKey(stub, appID, ownerID)
+	if err != nil {
+		return err
+	}
+	cert, err := parseCertificate([]byte(registered.Certificate))
+	if err != nil {
+		return errors.New(errorOwnerCert)
+	}
+	if options.OwnerCert != "" {
+		presented, err := parseCertificate([]byte(options.OwnerCert))
+		if err != nil || !bytes.Equal(presented.Raw, cert.Raw) {
+			return errors.New(errorOwnerCertNotMatch+ ownerID)
+		}
+	}
+	if !verifySignature(cert, options.OwnerSignature, canonicalConsentPayload(args, options.Nonce)) {
+		return errors.New(errorOwnerSignature+ ownerID)
+	}
+	nonceKey, err := stub.CreateCompositeKey(indexOwnerNonce, []string{appID, ownerID, options.Nonce})
+	if err != nil {
+		return errors.New(errorOwnerNonce+ ownerID)
+	}
+	used, err := stub.GetState(nonceKey)
+	if err != nil || used != nil {
+		return errors.New(errorOwnerNonceUsed+ ownerID)
+	}
+	return stub.PutState(nonceKey, []byte(stub.GetTxID()))
+}
+
+// =====================================================================================================================
+// verifySignature - verify the base64 ASN.1 ECDSA signature of the payload with the key of the certificate
+// =====================================================================================================================
+func verifySignature(cert *x509.Certificate, signatureB64 string, payload []byte) bool {
+	publicKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+	der, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		return false
+	}
+	signature := ecdsaSignature{}
+	_, err = asn1.Unmarshal(der, &signature)
+	if err != nil || signature.R == nil || signature.S == nil {
+		return false
+	}
+	digest := sha256.Sum256(payload)
+	return ecdsa.Verify(publicKey, digest[:], signature.R, signature.S)
+}
+
+// =====================================================================================================================
+// readOwnerKey - read and decode the registered key of an owner
+// =====================================================================================================================
+func readOwnerKey(stub shim.ChaincodeStubInterface, appID, ownerID string) (ownerKey, error) {
+	var registered ownerKey
+	key, err := stub.CreateCompositeKey(indexOwnerKey, []string{appID, ownerID})
+	if err != nil {
+		return registered, err
+	}
+	valAsBytes, err := stub.GetState(key)
+	if err != nil {
+		return registered, err
+	} else if valAsBytes == nil {
+		return registered, errors.New(errorOwnerKeyNotExist+ ownerID)
+	}
+	err = json.Unmarshal(valAsBytes, &registered)
+	return registered, err
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+// =====================================================================================================================
+// signConsent - sign the canonical payload of the postconsent arguments and return the options argument
+// =====================================================================================================================
+func signConsent(key *ecdsa.PrivateKey, certPEM []byte, args []string) []byte {
+	return signConsentWithNonce(key, certPEM, args, strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+func signConsentWithNonce(key *ecdsa.PrivateKey, certPEM []byte, args []string, nonce string) []byte {
+	options, _ := json.Marshal(consentOptions{OwnerSignature: sign(key, canonicalConsentPayload(args, nonce)),
+		OwnerCert: string(certPEM), Nonce: nonce})
+	return options
+}
+
+func sign(key *ecdsa.PrivateKey, payload []byte) string {
+	digest := sha256.Sum256(payload)
+	der, _ := key.Sign(rand.Reader, digest[:], nil)
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func registerOwnerKeyArgs(ownerID string, certPEM []byte, signature ...string) [][]byte {
+	bargs := [][]byte{[]byte("registerownerkey"), []byte(APPID1), []byte(ownerID), certPEM}
+	for _, arg := range signature {
+		bargs = append(bargs, []byte(arg))
+	}
+	return bargs
+}
+
+func postConsentArgs(args []string, options []byte) [][]byte {
+	bargs := [][]byte{[]byte("postconsent")}
+	for _, arg := range args {
+		bargs = append(bargs, []byte(arg))
+	}
+	return append(bargs, options)
+}
+
+// =====================================================================================================================
+// Create a consent signed by the owner (nominal case)
+// =====================================================================================================================
+func TestConsentV2_CreateOwnerSignedConsentNominal(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	key, certPEM := buildCertificate(OWNERID1, nil)
+	res := stub.mockInvokeAs("1", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, certPEM))
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	res = stub.MockInvoke("2", postConsentArgs(args, signConsent(key, certPEM, args)))
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("2")})
+	consent := consent{}
+	json.Unmarshal(res.Payload, &consent)
+	if !consent.OwnerSigned || consent.OwnerSignature == "" {
+		t.Log("consent is not marked as signed by the owner: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create a consent with a signature over other arguments
+// =====================================================================================================================
+func TestConsentV2_CreateOwnerSignedConsentWithBadSignature(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	key, certPEM := buildCertificate(OWNERID1, nil)
+	stub.mockInvokeAs("1", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, certPEM))
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	options := signConsent(key, certPEM, args)
+	args[2] = CONSUMERID2
+	res := stub.MockInvoke("2", postConsentArgs(args, options))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerSignature) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create a consent signed with a certificate which is not registered for the owner
+// =====================================================================================================================
+func TestConsentV2_CreateOwnerSignedConsentWithAnotherCertificate(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	_, certPEM := buildCertificate(OWNERID1, nil)
+	stub.mockInvokeAs("1", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, certPEM))
+	otherKey, otherCertPEM := buildCertificate(OWNERID1, nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	res := stub.MockInvoke("2", postConsentArgs(args, signConsent(otherKey, otherCertPEM, args)))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerCertNotMatch) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create a consent signed by an owner without registered key
+// =====================================================================================================================
+func TestConsentV2_CreateOwnerSignedConsentWithoutRegisteredKey(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	key, certPEM := buildCertificate(OWNERID1, nil)
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	res := stub.MockInvoke("1", postConsentArgs(args, signConsent(key, certPEM, args)))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerKeyNotExist) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Replay a signed consent: the nonce is accepted once
+// =====================================================================================================================
+func TestConsentV2_CreateOwnerSignedConsentReplayed(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	key, certPEM := buildCertificate(OWNERID1, nil)
+	stub.mockInvokeAs("1", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, certPEM))
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	options := signConsentWithNonce(key, certPEM, args, "nonce1")
+	res := stub.MockInvoke("2", postConsentArgs(args, options))
+	if res.Status != shim.OK {
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", postConsentArgs(args, options))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerNonceUsed) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", postConsentArgs(args, signConsentWithNonce(key, certPEM, args, "")))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerNonce) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// The fields of the payload are encoded, a separator in a field does not give the payload of other arguments
+// =====================================================================================================================
+func TestConsentV2_CanonicalConsentPayloadEncoding(t *testing.T) {
+	first := canonicalConsentPayload([]string{"app|owner", "consumer"}, "nonce")
+	second := canonicalConsentPayload([]string{"app", "owner|consumer"}, "nonce")
+	if string(first) == string(second) {
+		t.Log("same payload for different arguments: "+ string(first))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// A registered key is not overwritten, it is rotated by its registrant with a signature of the registered key
+// =====================================================================================================================
+func TestConsentV2_RotateOwnerKey(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	key, certPEM := buildCertificate(OWNERID1, nil)
+	stub.mockInvokeAs("1", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, certPEM))
+	newKey, newCertPEM := buildCertificate(OWNERID1, nil)
+	res := stub.mockInvokeAs("2", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, newCertPEM))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerKeyExist) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("3", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, newCertPEM, sign(newKey, newCertPEM)))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerSignature) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("4", OWNERID2, nil, registerOwnerKeyArgs(OWNERID1, newCertPEM, sign(key, newCertPEM)))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerKeyRegistrant) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.mockInvokeAs("5", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, newCertPEM, sign(key, newCertPEM)))
+	if res.Status != shim.OK {
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	args := []string{APPID1, OWNERID1, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7)}
+	res = stub.MockInvoke("6", postConsentArgs(args, signConsent(newKey, newCertPEM, args)))
+	if res.Status != shim.OK {
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Register a certificate which is not issued to the owner
+// =====================================================================================================================
+func TestConsentV2_RegisterOwnerKeyOfAnotherOwner(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	_, certPEM := buildCertificate(OWNERID2, nil)
+	res := stub.mockInvokeAs("1", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, certPEM))
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerCertSubject) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
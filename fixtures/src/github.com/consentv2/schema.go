@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Schema constantes
+// =====================================================================================================================
+const (
+	// version of the consent records written by this chaincode (records without version are version 1)
+	CONSENT_SCHEMA_VERSION = 3
+
+	// Chaincode errors
+	errorDecodeConsent        = "Failed to decode consent:"
+	errorSchemaVersion        = "Consent written with a newer schema version:"
+	errorMigrateConsents      = "Migrate consents for appID:"
+)
+
+// =====================================================================================================================
+// migration - upgrade of a raw consent record from the version From to the version From+1
+// =====================================================================================================================
+type migration struct {
+	From		int
+	Migrate		func(record map[string]interface{}) error
+}
+
+// =====================================================================================================================
+// migrations - ordered list of the migrations, add a migration for each new version of the schema
+// =====================================================================================================================
+var migrations = []migration{
+	// version 1 -> 2: records written before the schema version, the optional attributes added since (document,
+	// signature, external reference, conditions, grant, updatedat) decode to their zero value
+	{From: 1, Migrate: func(record map[string]interface{}) error {
+		return nil
+	}},
+	// version 2 -> 3: the creation time was not recorded, the time of the last modification is the best known value
+	{From: 2, Migrate: func(record map[string]interface{}) error {
+		_, created := record["createdat"]
+		if updatedAt, ok := record["updatedat"]; ok && !created {
+			record["createdat"] = updatedAt
+		}
+		return nil
+	}},
+}
+
+// =====================================================================================================================
+// AppID:         string: id of the client application
+// Migrated:      int:    number of consents upgraded by the call
+// Remaining:     int:    number of consents still to upgrade (call migrate again)
+// SchemaVersion: int:    version of the upgraded consents
+// =====================================================================================================================
+type migrationResult struct {
+	AppID 		string     `json:"appid"`
+	Migrated	int        `json:"migrated"`
+	Remaining	int        `json:"remaining"`
+	SchemaVersion	int        `json:"schemaversion"`
+}
+
+// =====================================================================================================================
+// Upgrade eagerly the consents of an appID to the current schema version (the consents are also upgraded lazily
+// when they are read and written)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["migrate","APPID","MAXCONSENTS"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)migrate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, maxConsents!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("migrate(Appid:"+ args[0]+ " MaxConsents:"+ args[1]+") : calling method -")
+	maxConsents, err := strconv.Atoi(args[1])
+	if err != nil || maxConsents <= 0 {
+		return shim.Error(buildError(errorArgs+" maxConsents must be a positive number"))
+	}
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexApp, []string{args[0]})
+	if err != nil {
+		return shim.Error(buildError(errorMigrateConsents+ args[0]))
+	}
+	defer resultsIterator.Close()
+
+	result := migrationResult{AppID: args[0], SchemaVersion: CONSENT_SCHEMA_VERSION}
+	for resultsIterator.HasNext() {
+		indexKey, _, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(buildError(errorMigrateConsents+ args[0]))
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(indexKey)
+		if err != nil {
+			return shim.Error(buildError(errorMigrateConsents+ args[0]))
+		}
+		consentID := compositeKeyParts[len(compositeKeyParts) - 1]
+		valAsBytes, err := stub.GetState(consentID)
+		if err != nil {
+			return shim.Error(buildError(errorMigrateConsents+ args[0]))
+		}
+		if valAsBytes == nil {
+			// index of a deleted consent
+			continue
+		}
+		record, migrated, err := decodeConsent(consentID, valAsBytes)
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		if !migrated {
+			continue
+		}
+		if result.Migrated == maxConsents {
+			result.Remaining++
+			continue
+		}
+		err = putConsent(stub, record)
+		if err != nil {
+			return shim.Error(buildError(errorMigrateConsents+ args[0]))
+		}
+		result.Migrated++
+	}
+	valAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(buildError(errorMigrateConsents+ args[0]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// decodeConsent - decode a consent record and upgrade it to the current schema version, migrated is true when the
+// record stored is older than the current version
+// =====================================================================================================================
+func decodeConsent(consentID string, valAsBytes []byte) (consent, bool, error) {
+	var decoded consent
+	record := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(valAsBytes))
+	decoder.UseNumber()
+	err := decoder.Decode(&record)
+	if err != nil {
+		logger.Error("Failed to unmarshal state for " + consentID +" "+err.Error())
+		return decoded, false, errors.New(errorDecodeConsent+ consentID)
+	}
+	version := 1
+	if value, ok := record["schemaversion"]; ok {
+		number, ok := value.(json.Number)
+		if !ok {
+			return decoded, false, errors.New(errorDecodeConsent+ consentID)
+		}
+		version, err = strconv.Atoi(number.String())
+		if err != nil {
+			return decoded, false, errors.New(errorDecodeConsent+ consentID)
+		}
+	}
+	if version > CONSENT_SCHEMA_VERSION {
+		return decoded, false, errors.New(errorSchemaVersion+ consentID)
+	}
+	migrated := version < CONSENT_SCHEMA_VERSION
+	for _, step := range migrations {
+		if step.From < version {
+			continue
+		}
+		err = step.Migrate(record)
+		if err != nil {
+			logger.Error("Failed to migrate consent " + consentID +" "+err.Error())
+			return decoded, false, errors.New(errorDecodeConsent+ consentID)
+		}
+		version = step.From + 1
+	}
+	record["schemaversion"] = version
+	migratedAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return decoded, false, errors.New(errorDecodeConsent+ consentID)
+	}
+	err = json.Unmarshal(migratedAsBytes, &decoded)
+	if err != nil {
+		logger.Error("Failed to unmarshal state for " + consentID +" "+err.Error())
+		return decoded, false, errors.New(errorDecodeConsent+ consentID)
+	}
+	return decoded, migrated, nil
+}
+
+// =====================================================================================================================
+// encodeConsent - encode a consent record with the current schema version
+// =====================================================================================================================
+func encodeConsent(record consent) ([]byte, error) {
+	record.SchemaVersion = CONSENT_SCHEMA_VERSION
+	return json.Marshal(record)
+}
+
+// =====================================================================================================================
+// putConsent - write a consent record with the current schema version
+// =====================================================================================================================
+func putConsent(stub shim.ChaincodeStubInterface, record consent) error {
+	valAsBytes, err := encodeConsent(record)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(record.ConsentID, valAsBytes)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"strings"
+	"encoding/json"
+)
+
+// =====================================================================================================================
+// putLegacyConsent - rewrite a consent as a record written before the schema version
+// =====================================================================================================================
+func putLegacyConsent(t *testing.T, stub *shim.MockStub, consentID string) {
+	record := map[string]interface{}{}
+	json.Unmarshal(stub.State[consentID], &record)
+	delete(record, "schemaversion")
+	valAsBytes, _ := json.Marshal(record)
+	stub.MockTransactionStart("legacy")
+	err := stub.PutState(consentID, valAsBytes)
+	stub.MockTransactionEnd("legacy")
+	if err != nil {
+		t.Log("failed to write legacy consent: "+ err.Error())
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// A consent without schema version is upgraded when it is read and written
+// =====================================================================================================================
+func TestConsentV2_SchemaLazyMigration(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	putLegacyConsent(t, stub, "1")
+
+	res := stub.MockInvoke("2", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("1")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	record := consent{}
+	json.Unmarshal(res.Payload, &record)
+	if record.SchemaVersion != CONSENT_SCHEMA_VERSION || record.OwnerID != OWNERID1 {
+		t.Log("bad upgraded consent reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	if strings.Contains(string(stub.State["1"]), "schemaversion") {
+		t.Log("consent written by a read: "+ string(stub.State["1"]))
+		t.FailNow()
+	}
+
+	stub.MockInvoke("3", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("1")})
+	stored := consent{}
+	json.Unmarshal(stub.State["1"], &stored)
+	if stored.SchemaVersion != CONSENT_SCHEMA_VERSION || stored.State != NOT_ACTIVE {
+		t.Log("consent not upgraded by a write: "+ string(stub.State["1"]))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Upgrade eagerly the consents of an appID by batches
+// =====================================================================================================================
+func TestConsentV2_SchemaBatchMigration(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	putLegacyConsent(t, stub, "1")
+	putLegacyConsent(t, stub, "3")
+
+	res := stub.MockInvoke("4", [][]byte{[]byte("migrate"), []byte(APPID1), []byte("1")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	result := migrationResult{}
+	json.Unmarshal(res.Payload, &result)
+	if result.Migrated != 1 || result.Remaining != 1 {
+		t.Log("bad migration result reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("5", [][]byte{[]byte("migrate"), []byte(APPID1), []byte("10")})
+	json.Unmarshal(res.Payload, &result)
+	if result.Migrated != 1 || result.Remaining != 0 {
+		t.Log("bad migration result reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	for _, consentID := range []string{"1", "3"} {
+		if !strings.Contains(string(stub.State[consentID]), "\"schemaversion\":"+ strconv.Itoa(CONSENT_SCHEMA_VERSION)) {
+			t.Log("consent not migrated: "+ string(stub.State[consentID]))
+			t.FailNow()
+		}
+	}
+	res = stub.MockInvoke("6", [][]byte{[]byte("migrate"), []byte(APPID1), []byte("0")})
+	if res.Status == shim.OK {
+		t.Log("bad status received for a bad batch size, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// A corrupted consent or a consent of a newer schema is reported instead of being dropped from the lists
+// =====================================================================================================================
+func TestConsentV2_SchemaDecodeErrors(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID2), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+
+	stub.MockTransactionStart("corrupt")
+	stub.PutState("2", []byte("{\"appid\":"))
+	stub.MockTransactionEnd("corrupt")
+	res := stub.MockInvoke("3", [][]byte{[]byte("getownerconsents"), []byte(APPID1), []byte(OWNERID1)})
+	if res.Status == shim.OK || !strings.Contains(res.Message, errorDecodeConsent) {
+		t.Log("corrupted consent not reported: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+
+	stub.MockTransactionStart("newer")
+	stub.PutState("2", []byte("{\"appid\":\""+ APPID1+ "\",\"consentid\":\"2\",\"schemaversion\":"+ strconv.Itoa(CONSENT_SCHEMA_VERSION+1)+ "}"))
+	stub.MockTransactionEnd("newer")
+	res = stub.MockInvoke("4", [][]byte{[]byte("getconsent"), []byte(APPID1), []byte("2")})
+	if res.Status == shim.OK || !strings.Contains(res.Message, errorSchemaVersion) {
+		t.Log("newer schema version not reported: "+ string(res.Payload)+ " "+ res.Message)
+		t.FailNow()
+	}
+}
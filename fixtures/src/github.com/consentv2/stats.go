@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Statistics constantes
+// =====================================================================================================================
+const (
+	//Chainccode index
+	indexStats = "app~stats"		// to get the consent counters of an appID
+
+	// Chaincode errors
+	errorGetStats             = "Get statistics for appID:"
+	errorUpdateStats          = "Update statistics for appID:"
+)
+
+// =====================================================================================================================
+// Active:  int: number of active consents
+// Revoked: int: number of revoked consents
+// =====================================================================================================================
+type counter struct {
+	Active		int        `json:"active"`
+	Revoked		int        `json:"revoked"`
+}
+
+// =====================================================================================================================
+// The counters are updated by the transactions (create, revoke), a consent is counted active until it is revoked:
+// an expired consent stays in Active
+// AppID:      string: id of the client application
+// Active:     int:    number of active consents
+// Revoked:    int:    number of revoked consents
+// ByDataType: map:    counters per data type
+// ByConsumer: map:    counters per consumer
+// ByMonth:    map:    number of consents created per month (yyyy-mm)
+// =====================================================================================================================
+type stats struct {
+	AppID 		string              `json:"appid"`
+	Active		int                 `json:"active"`
+	Revoked		int                 `json:"revoked"`
+	ByDataType	map[string]*counter `json:"bydatatype"`
+	ByConsumer	map[string]*counter `json:"byconsumer"`
+	ByMonth		map[string]int      `json:"bymonth"`
+}
+
+// =====================================================================================================================
+// Get the consent statistics of an appID
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getstats","APPID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getStats(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		errStr := errorArgs+" Expecting appID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getStats(Appid:"+ args[0]+") : calling method -")
+	appStats, err := readStats(stub, args[0])
+	if err != nil {
+		return shim.Error(buildError(errorGetStats+ args[0]))
+	}
+	valAsBytes, err := json.Marshal(appStats)
+	if err != nil {
+		return shim.Error(buildError(errorGetStats+ args[0]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Fold the deltas of the counters of an appID into a single delta, the reads of getstats stay bounded by the number
+// of transactions since the last compaction (to call by an admin or periodically)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["compactstats","APPID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)compactStats(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		errStr := errorArgs+" Expecting appID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("compactStats(Appid:"+ args[0]+") : calling method -")
+	appStats, err := readStats(stub, args[0])
+	if err != nil {
+		return shim.Error(buildError(errorGetStats+ args[0]))
+	}
+	// a delta written by a concurrent transaction invalidates the compaction (phantom read), never lost
+	err = resetStats(stub, args[0])
+	if err != nil {
+		return shim.Error(buildError(errorUpdateStats+ args[0]))
+	}
+	err = putStats(stub, appStats)
+	if err != nil {
+		return shim.Error(buildError(errorUpdateStats+ args[0]))
+	}
+	valAsBytes, err := json.Marshal(appStats)
+	if err != nil {
+		return shim.Error(buildError(errorGetStats+ args[0]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// countCreatedConsent - count a new consent in the delta of the transaction
+// =====================================================================================================================
+func (s *stats) countCreatedConsent(consent consent) {
+	s.Active++
+	getCounter(s.ByDataType, consent.DataType).Active++
+	getCounter(s.ByConsumer, consent.ConsumerID).Active++
+	s.ByMonth[consent.CreatedAt.UTC().Format("2006-01")]++
+}
+
+// =====================================================================================================================
+// countRevokedConsent - count a revoked consent in the delta of the transaction
+// =====================================================================================================================
+func (s *stats) countRevokedConsent(consent consent) {
+	s.Active--
+	s.Revoked++
+	dataTypeCounter := getCounter(s.ByDataType, consent.DataType)
+	dataTypeCounter.Active--
+	dataTypeCounter.Revoked++
+	consumerCounter := getCounter(s.ByConsumer, consent.ConsumerID)
+	consumerCounter.Active--
+	consumerCounter.Revoked++
+}
+
+// =====================================================================================================================
+// add - add the counters of a delta
+// =====================================================================================================================
+func (s *stats) add(delta stats) {
+	s.Active += delta.Active
+	s.Revoked += delta.Revoked
+	for name, value := range delta.ByDataType {
+		dataTypeCounter := getCounter(s.ByDataType, name)
+		dataTypeCounter.Active += value.Active
+		dataTypeCounter.Revoked += value.Revoked
+	}
+	for name, value := range delta.ByConsumer {
+		consumerCounter := getCounter(s.ByConsumer, name)
+		consumerCounter.Active += value.Active
+		consumerCounter.Revoked += value.Revoked
+	}
+	for month, value := range delta.ByMonth {
+		s.ByMonth[month] += value
+	}
+}
+
+func newStats(appID string) stats {
+	return stats{AppID: appID, ByDataType: map[string]*counter{}, ByConsumer: map[string]*counter{},
+		ByMonth: map[string]int{}}
+}
+
+func getCounter(counters map[string]*counter, name string) *counter {
+	if counters[name] == nil {
+		counters[name] = &counter{}
+	}
+	return counters[name]
+}
+
+// =====================================================================================================================
+// readStats - sum the deltas of the transactions of the appID (empty counters if none)
+// =====================================================================================================================
+func readStats(stub shim.ChaincodeStubInterface, appID string) (stats, error) {
+	appStats := newStats(appID)
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexStats, []string{appID})
+	if err != nil {
+		return appStats, err
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		_, valAsBytes, err := resultsIterator.Next()
+		if err != nil {
+			return appStats, err
+		}
+		delta := newStats(appID)
+		err = json.Unmarshal(valAsBytes, &delta)
+		if err != nil {
+			return appStats, err
+		}
+		appStats.add(delta)
+	}
+	return appStats, nil
+}
+
+// =====================================================================================================================
+// putStats - write the delta of the counters of the transaction under its own key: the transactions do not update
+// a shared key (no read-write conflict between concurrent creates), the deltas are summed by readStats. Called once
+// per transaction and appID, with the changes of all the consents of the transaction
+// =====================================================================================================================
+func putStats(stub shim.ChaincodeStubInterface, delta stats) error {
+	key, err := stub.CreateCompositeKey(indexStats, []string{delta.AppID, stub.GetTxID()})
+	if err != nil {
+		return err
+	}
+	valAsBytes, err := json.Marshal(delta)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, valAsBytes)
+}
+
+// =====================================================================================================================
+// resetStats - delete the deltas of the counters of the appID
+// =====================================================================================================================
+func resetStats(stub shim.ChaincodeStubInterface, appID string) error {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexStats, []string{appID})
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0)
+	for resultsIterator.HasNext() {
+		key, _, err := resultsIterator.Next()
+		if err != nil {
+			resultsIterator.Close()
+			return err
+		}
+		keys = append(keys, key)
+	}
+	resultsIterator.Close()
+	for _, key := range keys {
+		err = stub.DelState(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"time"
+)
+
+// =====================================================================================================================
+// Get the statistics updated by create and revoke (nominal case)
+// =====================================================================================================================
+func TestConsentV2_GetStatsNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE2), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID2), []byte(DATATYPE2), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("4", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("2")})
+	stub.MockInvoke("5", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("2")})
+	res := stub.MockInvoke("6", [][]byte{[]byte("getstats"), []byte(APPID1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	appStats := stats{}
+	json.Unmarshal(res.Payload, &appStats)
+	if appStats.Active != 2 || appStats.Revoked != 1 {
+		t.Log("bad counters reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	if appStats.ByDataType[DATATYPE2].Active != 1 || appStats.ByDataType[DATATYPE2].Revoked != 1 {
+		t.Log("bad counters per data type reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	if appStats.ByConsumer[CONSUMERID1].Active != 1 || appStats.ByConsumer[CONSUMERID2].Active != 1 {
+		t.Log("bad counters per consumer reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	if appStats.ByMonth[time.Now().UTC().Format("2006-01")] != 3 {
+		t.Log("bad counters per month reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Get the statistics after a reset of the application
+// =====================================================================================================================
+func TestConsentV2_GetStatsAfterReset(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("resetconsents"), []byte(APPID1)})
+	res := stub.MockInvoke("3", [][]byte{[]byte("getstats"), []byte(APPID1)})
+	appStats := stats{}
+	json.Unmarshal(res.Payload, &appStats)
+	if appStats.Active != 0 || len(appStats.ByConsumer) != 0 {
+		t.Log("counters not reset: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Get the statistics of the consents of a template: one delta of the counters per transaction
+// =====================================================================================================================
+func TestConsentV2_GetStatsOfGrant(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(TEMPLATE1)})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0))})
+	stub.MockInvoke("3", [][]byte{[]byte("revokegrant"), []byte(APPID1), []byte("2")})
+	res := stub.MockInvoke("4", [][]byte{[]byte("getstats"), []byte(APPID1)})
+	appStats := stats{}
+	json.Unmarshal(res.Payload, &appStats)
+	if appStats.Active != 0 || appStats.Revoked != 3 || appStats.ByConsumer[CONSUMERID1].Revoked != 3 {
+		t.Log("bad counters reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	resultsIterator, _ := stub.GetStateByPartialCompositeKey(indexStats, []string{APPID1})
+	defer resultsIterator.Close()
+	deltas := 0
+	for resultsIterator.HasNext() {
+		resultsIterator.Next()
+		deltas++
+	}
+	if deltas != 2 {
+		t.Log("bad number of deltas of the counters, expected: 2 received:"+ strconv.Itoa(deltas))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Compact the deltas of the counters: a single delta is kept and the counters are unchanged
+// =====================================================================================================================
+func TestConsentV2_CompactStats(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte(DATATYPE2), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("3", [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("2")})
+	res := stub.MockInvoke("4", [][]byte{[]byte("compactstats"), []byte(APPID1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	if deltas := countStatsDeltas(stub, APPID1); deltas != 1 {
+		t.Log("bad number of deltas after compaction, expected: 1 received:"+ strconv.Itoa(deltas))
+		t.FailNow()
+	}
+	stub.MockInvoke("5", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID2), []byte(DATATYPE2), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res = stub.MockInvoke("6", [][]byte{[]byte("getstats"), []byte(APPID1)})
+	appStats := stats{}
+	json.Unmarshal(res.Payload, &appStats)
+	if appStats.Active != 2 || appStats.Revoked != 1 || appStats.ByConsumer[CONSUMERID1].Revoked != 1 ||
+		appStats.ByMonth[time.Now().UTC().Format("2006-01")] != 3 {
+		t.Log("bad counters after compaction reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	if deltas := countStatsDeltas(stub, APPID1); deltas != 2 {
+		t.Log("bad number of deltas, expected: 2 received:"+ strconv.Itoa(deltas))
+		t.FailNow()
+	}
+}
+
+func countStatsDeltas(stub *shim.MockStub, appID string) int {
+	resultsIterator, _ := stub.GetStateByPartialCompositeKey(indexStats, []string{appID})
+	defer resultsIterator.Close()
+	deltas := 0
+	for resultsIterator.HasNext() {
+		resultsIterator.Next()
+		deltas++
+	}
+	return deltas
+}
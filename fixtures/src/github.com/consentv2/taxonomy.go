@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Taxonomy constantes
+// =====================================================================================================================
+const (
+	ALL_DATATYPES = "All"
+	MAX_TAXONOMY_DEPTH = 16
+
+	//Chainccode index
+	indexDataType = "datatype"		// to get a data type of the taxonomy
+
+	// Chaincode errors
+	errorAddDataType          = "Add data type!"
+	errorDataTypeExist        = "Data type already exists:"
+	errorDataTypeNotExist     = "Data type does not exist:"
+	errorDataTypeDeprecated   = "Data type is deprecated:"
+	errorGetDataTypes         = "Get data types!"
+)
+
+// =====================================================================================================================
+// Code:       string: code of the data type (ex: HR)
+// Label:      string: description of the data type (ex: Heart rate)
+// Parent:     string: code of the parent data type, a consent on the parent includes the child (ex: CAR includes HR)
+// Deprecated: bool:   true when no more consent can be created on the data type
+// =====================================================================================================================
+type dataType struct {
+	Code		string     `json:"code"`
+	Label		string     `json:"label"`
+	Parent		string     `json:"parent,omitempty"`
+	Deprecated	bool       `json:"deprecated"`
+}
+
+// default taxonomy created when the chaincode is instantiated, All (the default data type of the API) is not the
+// parent of the other types: the consents created without data type must not grant the types added to the taxonomy
+var defaultDataTypes = []dataType{
+	{Code: ALL_DATATYPES, Label: "All data types"},
+	{Code: "BC", Label: "Body composition"},
+	{Code: "BM", Label: "Body measurement"},
+	{Code: "BP", Label: "Blood pressure"},
+	{Code: "WS", Label: "Weight scale"},
+	{Code: "CGM", Label: "Continue glucose monitoring"},
+	{Code: "BGM", Label: "Blood glucose monitoring"},
+	{Code: "CAR", Label: "Cardio vascular and fitness"},
+	{Code: "HR", Label: "Heart rate", Parent: "CAR"},
+}
+
+// =====================================================================================================================
+// Add a data type in the taxonomy
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["adddatatype","CODE","LABEL","PARENT"]}' -o 127.0.0.1:7050
+// the parent is optional (empty string) and must exist and not be deprecated
+// =====================================================================================================================
+func (c *ConsentCC)addDataType(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		errStr := errorArgs+" expecting code, label, parent!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("addDataType(Code:"+ args[0]+ " Label:"+ args[1]+" Parent:"+ args[2]+") : calling method -")
+	if args[0] == "" {
+		return shim.Error(buildError(errorAddDataType))
+	}
+	_, err := readDataType(stub, args[0])
+	if err == nil {
+		return shim.Error(buildError(errorDataTypeExist+ args[0]))
+	}
+	if args[2] != "" {
+		parent, err := readDataType(stub, args[2])
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		if parent.Deprecated {
+			return shim.Error(buildError(errorDataTypeDeprecated+ args[2]))
+		}
+	}
+	err = putDataType(stub, dataType{Code: args[0], Label: args[1], Parent: args[2]})
+	if err != nil {
+		return shim.Error(buildError(errorAddDataType))
+	}
+	return shim.Success([]byte(args[0]))
+}
+
+// =====================================================================================================================
+// Get all the data types of the taxonomy
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["listdatatypes"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)listDataTypes(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		errStr := errorArgs+" None argument expecting!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("listDataTypes() : calling method -")
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexDataType, []string{})
+	if err != nil {
+		return shim.Error(buildError(errorGetDataTypes))
+	}
+	defer resultsIterator.Close()
+
+	dataTypes := make([]dataType, 0)
+	for resultsIterator.HasNext() {
+		_, valAsBytes, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(buildError(errorGetDataTypes))
+		}
+		var dataType dataType
+		err = json.Unmarshal(valAsBytes, &dataType)
+		if err != nil {
+			return shim.Error(buildError(errorGetDataTypes))
+		}
+		dataTypes = append(dataTypes, dataType)
+	}
+	valAsBytes, err := json.Marshal(dataTypes)
+	if err != nil {
+		return shim.Success([]byte("[]"))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Deprecate a data type: the existing consents remain valid, but no new consent can be created on it
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["deprecatedatatype","CODE"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)deprecateDataType(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		errStr := errorArgs+" expecting code!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("deprecateDataType(Code:"+ args[0]+") : calling method -")
+	dataType, err := readDataType(stub, args[0])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	dataType.Deprecated = true
+	err = putDataType(stub, dataType)
+	if err != nil {
+		return shim.Error(buildError(errorAddDataType))
+	}
+	return shim.Success([]byte(args[0]))
+}
+
+// =====================================================================================================================
+// initTaxonomy - create the default data types not already in the taxonomy
+// =====================================================================================================================
+func initTaxonomy(stub shim.ChaincodeStubInterface) error {
+	logger.Debug("initTaxonomy() : calling method -")
+	for _, defaultDataType := range defaultDataTypes {
+		if _, err := readDataType(stub, defaultDataType.Code); err == nil {
+			continue
+		}
+		if err := putDataType(stub, defaultDataType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// =====================================================================================================================
+// checkDataType - verify that a consent can be created on the data type
+// =====================================================================================================================
+func checkDataType(stub shim.ChaincodeStubInterface, code string) error {
+	dataType, err := readDataType(stub, code)
+	if err != nil {
+		return err
+	}
+	if dataType.Deprecated {
+		return errors.New(errorDataTypeDeprecated+ code)
+	}
+	return nil
+}
+
+// =====================================================================================================================
+// getDataTypeLineage - return the data type followed by all its ancestors (only the data type if unknown)
+// =====================================================================================================================
+func getDataTypeLineage(stub shim.ChaincodeStubInterface, code string) []string {
+	lineage := []string{code}
+	for {
+		dataType, err := readDataType(stub, lineage[len(lineage) - 1])
+		if err != nil || dataType.Parent == "" || len(lineage) >= MAX_TAXONOMY_DEPTH {
+			return lineage
+		}
+		lineage = append(lineage, dataType.Parent)
+	}
+}
+
+// =====================================================================================================================
+// readDataType - read and decode a data type of the taxonomy
+// =====================================================================================================================
+func readDataType(stub shim.ChaincodeStubInterface, code string) (dataType, error) {
+	var dataType dataType
+	key, err := stub.CreateCompositeKey(indexDataType, []string{code})
+	if err != nil {
+		return dataType, err
+	}
+	valAsBytes, err := stub.GetState(key)
+	if err != nil {
+		return dataType, err
+	} else if valAsBytes == nil {
+		return dataType, errors.New(errorDataTypeNotExist+ code)
+	}
+	err = json.Unmarshal(valAsBytes, &dataType)
+	return dataType, err
+}
+
+// =====================================================================================================================
+// putDataType - write a data type of the taxonomy
+// =====================================================================================================================
+func putDataType(stub shim.ChaincodeStubInterface, dataType dataType) error {
+	key, err := stub.CreateCompositeKey(indexDataType, []string{dataType.Code})
+	if err != nil {
+		return err
+	}
+	valAsBytes, err := json.Marshal(dataType)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, valAsBytes)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+// =====================================================================================================================
+// List the default data types (nominal case)
+// =====================================================================================================================
+func TestConsentV2_ListDataTypesNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("listdatatypes")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	dataTypes := []dataType{}
+	json.Unmarshal(res.Payload, &dataTypes)
+	if len(dataTypes) != len(defaultDataTypes) {
+		t.Log("bad number of data types, expected: "+strconv.Itoa(len(defaultDataTypes))+" reveived: "+
+			strconv.Itoa(len(dataTypes)))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Add a data type with a parent (nominal case)
+// =====================================================================================================================
+func TestConsentV2_AddDataTypeNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("adddatatype"), []byte("HRV"), []byte("Heart rate variability"), []byte("HR")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("HRV"), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Add a data type already in the taxonomy or with an unknown parent
+// =====================================================================================================================
+func TestConsentV2_AddDataTypeWithBadParameters(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("adddatatype"), []byte("HR"), []byte("Heart rate"), []byte("")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorDataTypeExist) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("2", [][]byte{[]byte("adddatatype"), []byte("SLP"), []byte("Sleep"), []byte("XXX")})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorDataTypeNotExist) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create a consent with a data type not in the taxonomy
+// =====================================================================================================================
+func TestConsentV2_CreateConsentWithUnknownDataType(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("BPP"), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	if res.Status != shim.ERROR {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	if !strings.Contains(res.Message, errorDataTypeNotExist) {
+		t.Log("Bad return message, expected:"+errorDataTypeNotExist+" reveived:"+string(res.Message))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create a consent with a deprecated data type, the existing consents are still honored
+// =====================================================================================================================
+func TestConsentV2_CreateConsentWithDeprecatedDataType(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("WS"), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res := stub.MockInvoke("2", [][]byte{[]byte("deprecatedatatype"), []byte("WS")})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte("WS"), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorDataTypeDeprecated) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("WS"), []byte(DATAACCESS1)})
+	if string(res.Payload) != AUTHORIZED {
+		t.Log(AUTHORIZED, "expected, but ", string(res.Payload), "reveived")
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Verify that a consent on a parent data type grants its children, but not the opposite
+// =====================================================================================================================
+func TestConsentV2_IsConsentOnParentDataType(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("CAR"), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte("HR"), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+
+	res := stub.MockInvoke("3", [][]byte{[]byte("checkconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("HR"), []byte(DATAACCESS1)})
+	result := decision{}
+	json.Unmarshal(res.Payload, &result)
+	if result.Consent != AUTHORIZED || result.ConsentID != "1" {
+		t.Log("bad decision for a child data type reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID2), []byte(CONSUMERID1), []byte("CAR"), []byte(DATAACCESS1)})
+	if string(res.Payload) != NOT_AUTHORIZED {
+		t.Log(NOT_AUTHORIZED, "expected for a parent data type, but ", string(res.Payload), "reveived")
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Verify that a consent on All (the default data type of the API) grants only All, not the other data types
+// =====================================================================================================================
+func TestConsentV2_IsConsentOnAllDataTypes(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(ALL_DATATYPES), []byte(DATAACCESS1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+
+	for _, code := range []string{"HR", "CAR", "BP"} {
+		res := stub.MockInvoke("2", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(code), []byte(DATAACCESS1)})
+		if string(res.Payload) != NOT_AUTHORIZED {
+			t.Log(NOT_AUTHORIZED, "expected for", code, "with a consent on All, but ", string(res.Payload), "reveived")
+			t.FailNow()
+		}
+	}
+	res := stub.MockInvoke("3", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(ALL_DATATYPES), []byte(DATAACCESS1)})
+	if string(res.Payload) != AUTHORIZED {
+		t.Log(AUTHORIZED, "expected for All, but ", string(res.Payload), "reveived")
+		t.FailNow()
+	}
+}
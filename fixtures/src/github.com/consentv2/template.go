@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Template constantes
+// =====================================================================================================================
+const (
+	//Chainccode index
+	indexTemplate = "app~template"		// to get the consent templates of an appID
+	indexGrant    = "app~grant~id"		// to get all consents created from a template in the same grant
+
+	// Chaincode errors
+	errorTemplate             = "Template is not valid:"
+	errorPutTemplate          = "Put template:"
+	errorGetTemplate          = "Get template:"
+	errorTemplateNotExist     = "Template does not exist:"
+	errorGetTemplates         = "Get list of templates for appID:"
+	errorConsentFromTemplate  = "Create consents from template:"
+	errorGrantNotExist        = "Grant does not exist:"
+	errorRevokeGrant          = "Revoke grant:"
+)
+
+// =====================================================================================================================
+// DataType:   string: type of data of the consent
+// DataAccess: string: type of access of the consent
+// =====================================================================================================================
+type templateItem struct {
+	DataType	string     `json:"datatype"`
+	DataAccess	string     `json:"dataaccess"`
+}
+
+// =====================================================================================================================
+// AppID:        string: id of the client application
+// TemplateID:   string: id of the template, unique per appID
+// Label:        string: label of the consent package displayed to the owner
+// Items:        list:   data type and data access of each consent of the package
+// ValidityDays: int:    default validity of the consents in days (used when no end date is given)
+// =====================================================================================================================
+type template struct {
+	AppID 		string          `json:"appid"`
+	TemplateID	string          `json:"templateid"`
+	Label		string          `json:"label"`
+	Items		[]templateItem  `json:"items"`
+	ValidityDays	int             `json:"validitydays"`
+}
+
+// =====================================================================================================================
+// Optional arguments of postconsentfromtemplate (json object)
+// Mode:            string: behavior when an active consent overlaps the period of an item ('allow' (default), 'reject')
+// OwnerCert:       string: PEM certificate of the owner (must match the owner key registry)
+// Nonce:           string: unique value, the consent of the item n is signed with the nonce "Nonce#n"
+// OwnerSignatures: list:   signatures of the owner over the canonical payload of each item, in the order of the items
+//                          (the end date is the one given or computed from the validity of the template)
+// =====================================================================================================================
+type grantOptions struct {
+	Mode		string      `json:"mode,omitempty"`
+	OwnerCert	string      `json:"ownercert,omitempty"`
+	Nonce		string      `json:"nonce,omitempty"`
+	OwnerSignatures	[]string    `json:"ownersignatures,omitempty"`
+}
+
+// =====================================================================================================================
+// GrantID:    string: id of the grant (txID of the creation)
+// ConsentIDs: list:   ids of the consents created from the template
+// =====================================================================================================================
+type grant struct {
+	AppID 		string     `json:"appid"`
+	GrantID		string     `json:"grantid"`
+	TemplateID	string     `json:"templateid"`
+	ConsentIDs	[]string   `json:"consentids"`
+}
+
+// =====================================================================================================================
+// Create or update a consent template of an appID
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["puttemplate","APPID","TEMPLATEID",
+// 	"{\"label\":\"remote monitoring\",\"items\":[{\"datatype\":\"BP\",\"dataaccess\":\"R\"}],\"validitydays\":365}"]}'
+// 	-o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)putTemplate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		errStr := errorArgs+" Expecting appID, templateID, template!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("putTemplate(Appid:"+ args[0]+ " TemplateID:"+ args[1]+") : calling method -")
+	consentTemplate := template{}
+	err := json.Unmarshal([]byte(args[2]), &consentTemplate)
+	if err != nil {
+		return shim.Error(buildError(errorTemplate+ args[1]))
+	}
+	consentTemplate.AppID = args[0]
+	consentTemplate.TemplateID = args[1]
+	err = checkTemplate(stub, consentTemplate)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	valAsBytes, err := json.Marshal(consentTemplate)
+	if err != nil {
+		return shim.Error(buildError(errorPutTemplate+ args[1]))
+	}
+	templateKey, err := stub.CreateCompositeKey(indexTemplate, []string{args[0], args[1]})
+	if err != nil {
+		return shim.Error(buildError(errorPutTemplate+ args[1]))
+	}
+	err = stub.PutState(templateKey, valAsBytes)
+	if err != nil {
+		return shim.Error(buildError(errorPutTemplate+ args[1]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Get a consent template of an appID
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["gettemplate","APPID","TEMPLATEID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getTemplate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, templateID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getTemplate(Appid:"+ args[0]+ " TemplateID:"+ args[1]+") : calling method -")
+	consentTemplate, err := readTemplate(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	valAsBytes, err := json.Marshal(consentTemplate)
+	if err != nil {
+		return shim.Error(buildError(errorGetTemplate+ args[1]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Get the list of consent templates of an appID
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["listtemplates","APPID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)listTemplates(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		errStr := errorArgs+" Expecting appID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("listTemplates(Appid:"+ args[0]+") : calling method -")
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(indexTemplate, []string{args[0]})
+	if err != nil {
+		return shim.Error(buildError(errorGetTemplates+ args[0]))
+	}
+	defer resultsIterator.Close()
+
+	templates := make([]template, 0)
+	for resultsIterator.HasNext() {
+		_, valAsBytes, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(buildError(errorGetTemplates+ args[0]))
+		}
+		consentTemplate := template{}
+		err = json.Unmarshal(valAsBytes, &consentTemplate)
+		if err == nil {
+			templates = append(templates, consentTemplate)
+		}
+	}
+	valAsBytes, err := json.Marshal(templates)
+	if err != nil {
+		return shim.Error(buildError(errorGetTemplates+ args[0]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Create all consents of a template in one transaction, the consents are linked by a grant ID (the txID)
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["postconsentfromtemplate","APPID","TEMPLATEID","OWNERID",
+// 							"CONSUMERID", "DT_BEGIN", "DT_END", "OPTIONS"]}' -o 127.0.0.1:7050
+// the DT_END argument is optional (or empty), the validity of the template is used when it is not given
+// the OPTIONS argument is optional, it is a json object (see grantOptions), each item is checked as a postconsent
+// return the grant (grantID and consentIDs)
+// =====================================================================================================================
+func (c *ConsentCC)createConsentsFromTemplate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 5 || len(args) > 7 {
+		errStr := errorArgs+" expecting appID, templateID, ownerID, consumerID, dt_begin, [dt_end], [options]!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("createConsentsFromTemplate(Appid:"+ args[0]+ " TemplateID:"+ args[1]+ " Ownerid:"+ args[2]+
+		" Consumerid:"+ args[3]+ " Dt_begin:"+ args[4]+") : calling method -")
+	appID := args[0]
+	consentTemplate, err := readTemplate(stub, appID, args[1])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	options, err := parseGrantOptions(args, len(consentTemplate.Items))
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	end := ""
+	if len(args) > 5 && args[5] != "" {
+		end = args[5]
+	} else {
+		end, err = getTemplateEnd(consentTemplate, args[4])
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+	}
+	dt_begin, dt_end, err := checkDates(args[4], end)
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	err = checkConsumer(stub, appID, args[3])
+	if err != nil {
+		return shim.Error(buildError(err.Error()))
+	}
+	grantID := stub.GetTxID()
+	appGrant := grant{AppID: appID, GrantID: grantID, TemplateID: args[1], ConsentIDs: []string{}}
+	delta := newStats(appID)
+	for i, item := range consentTemplate.Items {
+		err = checkDataType(stub, item.DataType)
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		// the owner signature and the overlaps are checked as for a consent created alone
+		itemOptions := consentOptions{Mode: options.Mode, OwnerCert: options.OwnerCert}
+		if len(options.OwnerSignatures) > 0 {
+			itemOptions.OwnerSignature = options.OwnerSignatures[i]
+			itemOptions.Nonce = options.Nonce+ "#"+ strconv.Itoa(i+1)
+		}
+		ownerSigned, _, err := checkNewConsent(stub, []string{appID, args[2], args[3], item.DataType, item.DataAccess,
+			args[4], end}, dt_begin, dt_end, itemOptions)
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		// each consent of the grant needs its own id in the same transaction
+		consent := consent{AppID: appID, State: ACTIVE, ConsentID: grantID+ "-"+ strconv.Itoa(i+1), OwnerID: args[2],
+			ConsumerID: args[3], DataType: item.DataType, DataAccess: item.DataAccess, Dt_begin: dt_begin,
+			Dt_end: dt_end, OwnerSigned: ownerSigned, OwnerSignature: itemOptions.OwnerSignature, GrantID: grantID,
+			CreatedAt: getTxTime(stub), UpdatedAt: getTxTime(stub)}
+		err = putConsent(stub, consent)
+		if err != nil {
+			return shim.Error(buildError(errorConsentFromTemplate+ args[1]))
+		}
+		err = createIndex(stub, consent)
+		if err != nil {
+			return shim.Error(buildError(errorConsentFromTemplate+ args[1]))
+		}
+		delta.countCreatedConsent(consent)
+		appGrant.ConsentIDs = append(appGrant.ConsentIDs, consent.ConsentID)
+	}
+	err = putStats(stub, delta)
+	if err != nil {
+		return shim.Error(buildError(errorUpdateStats+ appID))
+	}
+	valAsBytes, err := json.Marshal(appGrant)
+	if err != nil {
+		return shim.Error(buildError(errorConsentFromTemplate+ args[1]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Get all consents of a grant
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["getgrant","APPID","GRANTID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)getGrant(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, grantID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("getGrant(Appid:"+ args[0]+ " GrantID:"+ args[1]+") : calling method -")
+	consents, err := getConsentsByIndex(stub, indexGrant, []string{args[0], args[1]})
+	if err != nil {
+		return shim.Error(buildError(errorGrantNotExist+ args[1]))
+	}
+	if len(consents) == 0 {
+		return shim.Error(buildError(errorGrantNotExist+ args[1]))
+	}
+	valAsBytes, err := json.Marshal(consents)
+	if err != nil {
+		return shim.Error(buildError(errorGrantNotExist+ args[1]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// Inactivate all consents of a grant
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["revokegrant","APPID","GRANTID"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)revokeGrant(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		errStr := errorArgs+" Expecting appID, grantID!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("revokeGrant(Appid:"+ args[0]+ " GrantID:"+ args[1]+") : calling method -")
+	consents, err := getConsentsByIndex(stub, indexGrant, []string{args[0], args[1]})
+	if err != nil {
+		return shim.Error(buildError(errorRevokeGrant+ args[1]))
+	}
+	if len(consents) == 0 {
+		return shim.Error(buildError(errorGrantNotExist+ args[1]))
+	}
+	delta := newStats(args[0])
+	for i := 0; i < len(consents); i++ {
+		if consents[i].State != ACTIVE {
+			continue
+		}
+		consents[i].State = NOT_ACTIVE
+		consents[i].UpdatedAt = getTxTime(stub)
+		err = putConsent(stub, consents[i])
+		if err != nil {
+			return shim.Error(buildError(errorRevokeGrant+ args[1]))
+		}
+		delta.countRevokedConsent(consents[i])
+	}
+	err = putStats(stub, delta)
+	if err != nil {
+		return shim.Error(buildError(errorUpdateStats+ args[0]))
+	}
+	return shim.Success(nil)
+}
+
+// =====================================================================================================================
+// parseGrantOptions - decode the optional json argument of postconsentfromtemplate, a consent of a grant is not
+// merged in another consent (it would leave the grant)
+// =====================================================================================================================
+func parseGrantOptions(args []string, items int) (grantOptions, error) {
+	options := grantOptions{}
+	if len(args) < 7 || args[6] == "" {
+		return options, nil
+	}
+	err := json.Unmarshal([]byte(args[6]), &options)
+	if err != nil {
+		return options, errors.New(errorConsentOptions)
+	}
+	if options.Mode != "" && options.Mode != MODE_ALLOW && options.Mode != MODE_REJECT {
+		return options, errors.New(errorConsentOptions)
+	}
+	if len(options.OwnerSignatures) > 0 && len(options.OwnerSignatures) != items {
+		return options, errors.New(errorConsentOptions)
+	}
+	return options, nil
+}
+
+// =====================================================================================================================
+// checkTemplate - verify the items and the validity of a template
+// =====================================================================================================================
+func checkTemplate(stub shim.ChaincodeStubInterface, consentTemplate template) error {
+	if consentTemplate.TemplateID == "" || len(consentTemplate.Items) == 0 || consentTemplate.ValidityDays < 0 {
+		return errors.New(errorTemplate+ consentTemplate.TemplateID)
+	}
+	for _, item := range consentTemplate.Items {
+		if item.DataAccess == "" {
+			return errors.New(errorTemplate+ consentTemplate.TemplateID)
+		}
+		err := checkDataType(stub, item.DataType)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// =====================================================================================================================
+// getTemplateEnd - end date of the consents of a template from the begin date and the validity of the template
+// =====================================================================================================================
+func getTemplateEnd(consentTemplate template, start string) (string, error) {
+	if consentTemplate.ValidityDays == 0 {
+		return "", errors.New(errorArgs+" Expecting dt_end, the template "+ consentTemplate.TemplateID+
+			" has no validity!")
+	}
+	dt_start, err := dateString2Date(start)
+	if err != nil {
+		return "", errors.New(errorDateBegin+ start)
+	}
+	dt_end := dt_start.Add(time.Duration(consentTemplate.ValidityDays) * 24 * time.Hour)
+	return dt_end.Format("2006-01-02"), nil
+}
+
+// =====================================================================================================================
+// readTemplate - read a template from the ledger
+// =====================================================================================================================
+func readTemplate(stub shim.ChaincodeStubInterface, appID, templateID string) (template, error) {
+	consentTemplate := template{}
+	templateKey, err := stub.CreateCompositeKey(indexTemplate, []string{appID, templateID})
+	if err != nil {
+		return consentTemplate, errors.New(errorGetTemplate+ templateID)
+	}
+	valAsBytes, err := stub.GetState(templateKey)
+	if err != nil {
+		return consentTemplate, errors.New(errorGetTemplate+ templateID)
+	}
+	if valAsBytes == nil {
+		return consentTemplate, errors.New(errorTemplateNotExist+ templateID)
+	}
+	err = json.Unmarshal(valAsBytes, &consentTemplate)
+	if err != nil {
+		return consentTemplate, errors.New(errorGetTemplate+ templateID)
+	}
+	return consentTemplate, nil
+}
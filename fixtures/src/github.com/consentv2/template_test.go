@@ -0,0 +1,212 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	TEMPLATEID1 = "monitoring"
+	TEMPLATE1   = `{"label":"remote monitoring","items":[{"datatype":"BP","dataaccess":"R"},{"datatype":"HR","dataaccess":"R"},{"datatype":"WS","dataaccess":"R"}],"validitydays":365}`
+)
+
+// =====================================================================================================================
+// Put, get and list templates (nominal case)
+// =====================================================================================================================
+func TestConsentV2_TemplatesNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(TEMPLATE1)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	stub.MockInvoke("2", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte("single"), []byte(`{"label":"blood pressure","items":[{"datatype":"BP","dataaccess":"A"}]}`)})
+	res = stub.MockInvoke("3", [][]byte{[]byte("gettemplate"), []byte(APPID1), []byte(TEMPLATEID1)})
+	consentTemplate := template{}
+	json.Unmarshal(res.Payload, &consentTemplate)
+	if consentTemplate.TemplateID != TEMPLATEID1 || len(consentTemplate.Items) != 3 || consentTemplate.ValidityDays != 365 {
+		t.Log("bad template reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", [][]byte{[]byte("listtemplates"), []byte(APPID1)})
+	templates := []template{}
+	json.Unmarshal(res.Payload, &templates)
+	if len(templates) != 2 {
+		t.Log("bad number of templates reveived: "+ strconv.Itoa(len(templates)))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("5", [][]byte{[]byte("listtemplates"), []byte(APPID2)})
+	json.Unmarshal(res.Payload, &templates)
+	if len(templates) != 0 {
+		t.Log("bad number of templates for another appID reveived: "+ strconv.Itoa(len(templates)))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Put a template with an unknown data type
+// =====================================================================================================================
+func TestConsentV2_PutTemplateBadDataType(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(`{"items":[{"datatype":"XX","dataaccess":"R"}]}`)})
+	if res.Status == shim.OK {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("2", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(`{"items":[]}`)})
+	if res.Status == shim.OK {
+		t.Log("bad status received for an empty template, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create the consents of a template and revoke them as a group (nominal case)
+// =====================================================================================================================
+func TestConsentV2_ConsentFromTemplateNominal(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(TEMPLATE1)})
+	res := stub.MockInvoke("2", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0))})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	appGrant := grant{}
+	json.Unmarshal(res.Payload, &appGrant)
+	if appGrant.GrantID != "2" || len(appGrant.ConsentIDs) != 3 {
+		t.Log("bad grant reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("3", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("HR"), []byte("R")})
+	if string(res.Payload) != AUTHORIZED {
+		t.Log("bad consent status reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", [][]byte{[]byte("getgrant"), []byte(APPID1), []byte(appGrant.GrantID)})
+	consents := []consent{}
+	json.Unmarshal(res.Payload, &consents)
+	if len(consents) != 3 || consents[0].GrantID != appGrant.GrantID || consents[0].Dt_end.Sub(consents[0].Dt_begin).Hours() != 366 * 24 {
+		t.Log("bad consents of the grant reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("5", [][]byte{[]byte("revokegrant"), []byte(APPID1), []byte(appGrant.GrantID)})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("6", [][]byte{[]byte("getgrant"), []byte(APPID1), []byte(appGrant.GrantID)})
+	json.Unmarshal(res.Payload, &consents)
+	for _, consent := range consents {
+		if consent.State != NOT_ACTIVE {
+			t.Log("consent of the grant not revoked: "+ consent.ConsentID)
+			t.FailNow()
+		}
+	}
+}
+
+// =====================================================================================================================
+// Create the consents of a template that does not exist
+// =====================================================================================================================
+func TestConsentV2_ConsentFromTemplateNotExist(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	res := stub.MockInvoke("1", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	if res.Status == shim.OK {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// A revoked grant does not authorize the accesses of its consents
+// =====================================================================================================================
+func TestConsentV2_IsConsentAfterRevokeGrant(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(TEMPLATE1)})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0))})
+	stub.MockInvoke("3", [][]byte{[]byte("revokegrant"), []byte(APPID1), []byte("2")})
+	res := stub.MockInvoke("4", [][]byte{[]byte("isconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("HR"), []byte("R")})
+	if string(res.Payload) != NOT_AUTHORIZED {
+		t.Log("bad consent status reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create the consents of a template in reject mode when a consent of an item exists
+// =====================================================================================================================
+func TestConsentV2_ConsentFromTemplateOverlapRejected(t *testing.T) {
+	scc := new(ConsentCC)
+	stub := shim.NewMockStub("consentv2", scc)
+	stub.MockInit("0", nil)
+	stub.MockInvoke("1", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(TEMPLATE1)})
+	stub.MockInvoke("2", [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("HR"), []byte("R"), []byte(getStringDateNow(0)), []byte(getStringDateNow(7))})
+	res := stub.MockInvoke("3", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0)), []byte(""), []byte(`{"mode":"reject"}`)})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentOverlap+"2") {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("4", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0)), []byte(""), []byte(`{"mode":"merge"}`)})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorConsentOptions) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Create the consents of a template signed by the owner, each item is signed with its own nonce
+// =====================================================================================================================
+func TestConsentV2_ConsentFromTemplateOwnerSigned(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	key, certPEM := buildCertificate(OWNERID1, nil)
+	stub.mockInvokeAs("1", OWNERID1, nil, registerOwnerKeyArgs(OWNERID1, certPEM))
+	stub.MockInvoke("2", [][]byte{[]byte("puttemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(TEMPLATE1)})
+	items := []string{"BP", "HR", "WS"}
+	signatures := []string{}
+	for i, item := range items {
+		args := []string{APPID1, OWNERID1, CONSUMERID1, item, "R", getStringDateNow(0), getStringDateNow(7)}
+		signatures = append(signatures, sign(key, canonicalConsentPayload(args, "grant1#"+ strconv.Itoa(i+1))))
+	}
+	options := grantOptions{OwnerCert: string(certPEM), Nonce: "grant1", OwnerSignatures: signatures}
+	options.OwnerSignatures[2] = signatures[0]
+	optionsAsBytes, _ := json.Marshal(options)
+	res := stub.MockInvoke("3", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7)), optionsAsBytes})
+	if res.Status != shim.ERROR || !strings.Contains(res.Message, errorOwnerSignature) {
+		t.Log("bad response reveived: "+ res.Message)
+		t.FailNow()
+	}
+	// the MockStub keeps the writes of a failed transaction (the nonces of the first items), new nonce
+	for i, item := range items {
+		args := []string{APPID1, OWNERID1, CONSUMERID1, item, "R", getStringDateNow(0), getStringDateNow(7)}
+		options.OwnerSignatures[i] = sign(key, canonicalConsentPayload(args, "grant2#"+ strconv.Itoa(i+1)))
+	}
+	options.Nonce = "grant2"
+	optionsAsBytes, _ = json.Marshal(options)
+	res = stub.MockInvoke("4", [][]byte{[]byte("postconsentfromtemplate"), []byte(APPID1), []byte(TEMPLATEID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(getStringDateNow(0)), []byte(getStringDateNow(7)), optionsAsBytes})
+	if res.Status != shim.OK {
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("5", [][]byte{[]byte("getgrant"), []byte(APPID1), []byte("4")})
+	consents := []consent{}
+	json.Unmarshal(res.Payload, &consents)
+	if len(consents) != 3 || !consents[0].OwnerSigned {
+		t.Log("bad consents of the grant reveived: "+ string(res.Payload))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// =====================================================================================================================
+// Past decision constantes
+// =====================================================================================================================
+const (
+	// Chaincode errors
+	errorInstant              = "Instant format error (RFC3339 expected):"
+	errorGetHistory           = "Get history of consent:"
+)
+
+// =====================================================================================================================
+// Consent:   string: AUTHORIZED or NOT_AUTHORIZED at the instant
+// ConsentID: string: id of the consent in effect at the instant (empty if none)
+// Version:   int:    version of the consent in effect (1 for the creation, +1 for each update)
+// TxID:      string: id of the transaction that wrote this version
+// UpdatedAt: date:   time of the transaction that wrote this version
+// =====================================================================================================================
+type pastDecision struct {
+	Consent		string     `json:"consent"`
+	ConsentID	string     `json:"consentid,omitempty"`
+	Version		int        `json:"version,omitempty"`
+	TxID		string     `json:"txid,omitempty"`
+	UpdatedAt	*time.Time `json:"updatedat,omitempty"`
+}
+
+// =====================================================================================================================
+// Verify if a consent was granted at an instant in the past (RFC3339), the history of the consents is used to get
+// the state and the period of each consent at this instant
+// example:
+// ./peer chaincode invoke -C mch -n consent -c '{"Args":["wasconsent","APPID", "OWNERID", "CONSUMERID", "DATATYPE",
+// 							"ACCESSTYPE", "2017-06-01T12:00:00Z"]}' -o 127.0.0.1:7050
+// =====================================================================================================================
+func (c *ConsentCC)wasConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 6 {
+		errStr := errorArgs+" Expecting AppID, OwnerID, CounsumerID, Datatype, Dataaccess, Instant!"
+		return shim.Error(buildError(errStr))
+	}
+	logger.Debug("wasConsent(Appid:"+ args[0]+ "Ownerid:"+ args[1]+" Consumerid:"+ args[2]+ " Datatype:"+ args[3]+
+		" Dataaccess:" + args[4] + " Instant:" + args[5] +") : calling method -")
+	instant, err := time.Parse(time.RFC3339, args[5])
+	if err != nil {
+		return shim.Error(buildError(errorInstant+ args[5]))
+	}
+	consentIDs, err := getCandidateConsentIDs(stub, args[0], args[1], args[2], args[3], args[4])
+	if err != nil {
+		return shim.Error(buildError(errorGetConsent4Params+"appID:"+args[0]+" OwnerID:"+args[1]+" ConsumerID:"+
+			args[2]+" dataType:"+args[3]+" DataAccess:"+args[4]))
+	}
+	decision := pastDecision{Consent: NOT_AUTHORIZED}
+	for _, consentID := range consentIDs {
+		version, err := getConsentVersionAt(stub, consentID, instant)
+		if err != nil {
+			return shim.Error(buildError(err.Error()))
+		}
+		if version == nil {
+			continue
+		}
+		if version.consent.State == ACTIVE && isValidAt(version.consent.Dt_begin, version.consent.Dt_end, instant) {
+			updatedAt := version.consent.UpdatedAt
+			decision = pastDecision{Consent: AUTHORIZED, ConsentID: consentID, Version: version.number,
+				TxID: version.txID, UpdatedAt: &updatedAt}
+			break
+		}
+	}
+	valAsBytes, err := json.Marshal(decision)
+	if err != nil {
+		return shim.Error(buildError(errorGetConsent4Params+"appID:"+args[0]))
+	}
+	return shim.Success(valAsBytes)
+}
+
+// =====================================================================================================================
+// consentVersion - a version of a consent read from the history of its key
+// =====================================================================================================================
+type consentVersion struct {
+	number  int
+	txID    string
+	consent consent
+}
+
+// =====================================================================================================================
+// getCandidateConsentIDs - ids of the consents (any state) matching the data type lineage and the data access, given
+// to the consumer or to one of its groups. The membership of the groups is the current one, not the membership at the
+// instant (the members of a group have no history): a consumer added to a group after the instant gets the consents of
+// the group, a consumer removed from it loses them
+// =====================================================================================================================
+func getCandidateConsentIDs(stub shim.ChaincodeStubInterface, appID, ownerID, consumerID, dataType, dataAccess string) ([]string, error) {
+	lineage := map[string]bool{}
+	for _, grantedType := range getDataTypeLineage(stub, dataType) {
+		lineage[grantedType] = true
+	}
+	references, err := getConsumerReferences(stub, appID, consumerID)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	consentIDs := make([]string, 0)
+	for _, reference := range references {
+		resultsIterator, err := stub.GetStateByPartialCompositeKey(indexIsConsent, []string{appID, ownerID, reference})
+		if err != nil {
+			return nil, err
+		}
+		for resultsIterator.HasNext() {
+			indexKey, _, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			_, compositeKeyParts, err := stub.SplitCompositeKey(indexKey)
+			if err != nil || len(compositeKeyParts) != 7 {
+				continue
+			}
+			consentID := compositeKeyParts[6]
+			if !lineage[compositeKeyParts[4]] || compositeKeyParts[5] != dataAccess || seen[consentID] {
+				continue
+			}
+			seen[consentID] = true
+			consentIDs = append(consentIDs, consentID)
+		}
+		resultsIterator.Close()
+	}
+	return consentIDs, nil
+}
+
+// =====================================================================================================================
+// getConsentVersionAt - last version of a consent written before the instant, nil if the consent did not exist yet
+// (versions written before the UpdatedAt field existed are considered as written at the creation of the ledger)
+// =====================================================================================================================
+func getConsentVersionAt(stub shim.ChaincodeStubInterface, consentID string, instant time.Time) (*consentVersion, error) {
+	logger.Debug("getConsentVersionAt(ConsentID:"+ consentID+ " Instant:"+ instant.String()+") : calling method -")
+	historyIterator, err := stub.GetHistoryForKey(consentID)
+	if err != nil {
+		return nil, errors.New(errorGetHistory+ consentID)
+	}
+	defer historyIterator.Close()
+
+	var found *consentVersion
+	for number := 1; historyIterator.HasNext(); number++ {
+		txID, valAsBytes, err := historyIterator.Next()
+		if err != nil {
+			return nil, errors.New(errorGetHistory+ consentID)
+		}
+		if len(valAsBytes) == 0 {
+			// deletion of the consent (resetconsents)
+			continue
+		}
+		version, _, err := decodeConsent(consentID, valAsBytes)
+		if err != nil {
+			return nil, err
+		}
+		if version.UpdatedAt.After(instant) {
+			continue
+		}
+		if found == nil || !version.UpdatedAt.Before(found.consent.UpdatedAt) {
+			found = &consentVersion{number: number, txID: txID, consent: version}
+		}
+	}
+	return found, nil
+}
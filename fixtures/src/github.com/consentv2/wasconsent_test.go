@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"strconv"
+	"encoding/json"
+	"time"
+)
+
+// =====================================================================================================================
+// Verify a past decision from the history of a consent revoked after the instant (nominal case)
+// =====================================================================================================================
+func TestConsentV2_WasConsentNominal(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	now := time.Now().UTC()
+	stub.mockInvokeAt("1", now.Add(-2 * time.Hour), [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(-1)), []byte(getStringDateNow(7))})
+	stub.mockInvokeAt("2", now.Add(-1 * time.Hour), [][]byte{[]byte("removeconsent"), []byte(APPID1), []byte("1")})
+
+	decision := wasConsentAt(t, stub, "3", DATATYPE1, now.Add(-90 * time.Minute))
+	if decision.Consent != AUTHORIZED || decision.ConsentID != "1" || decision.Version != 1 || decision.TxID != "1" {
+		t.Log("bad decision before the revocation reveived: "+ decision.Consent+ " version:"+ strconv.Itoa(decision.Version))
+		t.FailNow()
+	}
+	decision = wasConsentAt(t, stub, "4", DATATYPE1, now.Add(-30 * time.Minute))
+	if decision.Consent != NOT_AUTHORIZED {
+		t.Log("bad decision after the revocation reveived: "+ decision.Consent)
+		t.FailNow()
+	}
+	decision = wasConsentAt(t, stub, "5", DATATYPE1, now.Add(-3 * time.Hour))
+	if decision.Consent != NOT_AUTHORIZED {
+		t.Log("bad decision before the creation reveived: "+ decision.Consent)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Verify a past decision on a child data type of the granted data type
+// =====================================================================================================================
+func TestConsentV2_WasConsentChildDataType(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	now := time.Now().UTC()
+	stub.mockInvokeAt("1", now.Add(-2 * time.Hour), [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte("CAR"), []byte(DATAACCESS1), []byte(getStringDateNow(-1)), []byte(getStringDateNow(7))})
+
+	decision := wasConsentAt(t, stub, "2", "HR", now.Add(-1 * time.Hour))
+	if decision.Consent != AUTHORIZED || decision.ConsentID != "1" {
+		t.Log("bad decision for the child data type reveived: "+ decision.Consent)
+		t.FailNow()
+	}
+}
+
+// =====================================================================================================================
+// Verify a past decision with a bad instant
+// =====================================================================================================================
+func TestConsentV2_WasConsentBadInstant(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	res := stub.mockInvokeAs("1", "user1", nil, [][]byte{[]byte("wasconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(0))})
+	if res.Status == shim.OK {
+		t.Log("bad status received, expected: 500 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.FailNow()
+	}
+}
+
+func wasConsentAt(t *testing.T, stub *identityStub, uuid, dataType string, instant time.Time) pastDecision {
+	res := stub.mockInvokeAs(uuid, "user1", nil, [][]byte{[]byte("wasconsent"), []byte(APPID1), []byte(OWNERID1), []byte(CONSUMERID1), []byte(dataType), []byte(DATAACCESS1), []byte(instant.Format(time.RFC3339))})
+	if res.Status != shim.OK {
+		t.Log("bad status received, expected: 200 received:"+strconv.FormatInt(int64(res.Status), 10))
+		t.Log("response: "+ string(res.Message))
+		t.FailNow()
+	}
+	decision := pastDecision{}
+	json.Unmarshal(res.Payload, &decision)
+	return decision
+}
+
+// =====================================================================================================================
+// Verify a past decision on a consent given to a group of the consumer (current membership)
+// =====================================================================================================================
+func TestConsentV2_WasConsentForGroupMember(t *testing.T) {
+	stub := newIdentityStub("consentv2")
+	now := time.Now().UTC()
+	stub.mockInvokeAt("1", now.Add(-3 * time.Hour), [][]byte{[]byte("creategroup"), []byte(APPID1), []byte(GROUPID1), []byte("care team")})
+	stub.mockInvokeAt("2", now.Add(-2 * time.Hour), [][]byte{[]byte("postconsent"), []byte(APPID1), []byte(OWNERID1), []byte(GROUP_PREFIX+ GROUPID1), []byte(DATATYPE1), []byte(DATAACCESS1), []byte(getStringDateNow(-1)), []byte(getStringDateNow(7))})
+
+	decision := wasConsentAt(t, stub, "3", DATATYPE1, now.Add(-1 * time.Hour))
+	if decision.Consent != NOT_AUTHORIZED {
+		t.Log("bad decision before the membership reveived: "+ decision.Consent)
+		t.FailNow()
+	}
+	stub.mockInvokeAt("4", now, [][]byte{[]byte("addgroupmember"), []byte(APPID1), []byte(GROUPID1), []byte(CONSUMERID1)})
+	decision = wasConsentAt(t, stub, "5", DATATYPE1, now.Add(-1 * time.Hour))
+	if decision.Consent != AUTHORIZED || decision.ConsentID != "2" {
+		t.Log("bad decision for a member reveived: "+ decision.Consent)
+		t.FailNow()
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"time"
 	"strings"
+	"strconv"
 	"encoding/json"
 	"github.com/hyperledger/fabric-sdk-go/fabric-client/events"
+	pb "github.com/hyperledger/fabric/protos/peer"
 	//"errors"
 )
 
@@ -15,6 +17,7 @@ type ConsentHelper struct {
 	StatStorePath   string
 	Chain 	        fabricClient.Chain
 	EventHub        events.EventHub
+	Commit          *Commit
 	Initialized	bool
 }
 
@@ -29,6 +32,189 @@ type Consent struct {
 	DataAccess      string     `json:"dataaccess"`
 	Dt_begin      	string     `json:"dtbegin"`
 	Dt_end       	string     `json:"dtend"`
+	DocumentHash   	string     `json:"documenthash,omitempty"`
+	DocumentURI    	string     `json:"documenturi,omitempty"`
+	DocumentVersion	string     `json:"documentversion,omitempty"`
+	OwnerSigned	bool       `json:"ownersigned,omitempty"`
+	OwnerSignature	string     `json:"ownersignature,omitempty"`
+	OwnerCert	string     `json:"ownercert,omitempty"`
+	Nonce		string     `json:"nonce,omitempty"`
+	Mode		string     `json:"mode,omitempty"`
+	ExternalRef	string     `json:"externalref,omitempty"`
+	Reason      	string     `json:"reason,omitempty"`
+	Duration      	string     `json:"duration,omitempty"`
+	EventID      	string     `json:"eventid,omitempty"`
+	At      	string     `json:"at,omitempty"`
+	GrantID      	string     `json:"grantid,omitempty"`
+	Conditions	[]Condition       `json:"conditions,omitempty"`
+	Context		map[string]string `json:"context,omitempty"`
+	CreatedAt	string     `json:"createdat,omitempty"`
+	SchemaVersion	int        `json:"schemaversion,omitempty"`
+	// filter of the list actions (datatype, dataaccess and state are the attributes above)
+	ValidAt		string     `json:"validat,omitempty"`
+	CreatedAfter	string     `json:"createdafter,omitempty"`
+	CreatedBefore	string     `json:"createdbefore,omitempty"`
+	Sort		string     `json:"sort,omitempty"`
+	Fields		[]string   `json:"fields,omitempty"`
+}
+
+type Condition struct {
+	Attribute	string     `json:"attribute"`
+	Operator	string     `json:"operator"`	// eq, ne, in, notin, lt, le, gt, ge
+	Value		string     `json:"value"`
+}
+
+type ConsentOptions struct {
+	OwnerSignature	string     `json:"ownersignature,omitempty"`
+	OwnerCert	string     `json:"ownercert,omitempty"`
+	Nonce		string     `json:"nonce,omitempty"`	// unique value signed with the consent by the owner
+	Mode		string     `json:"mode,omitempty"`	// allow, reject or merge an overlapping consent
+	ExternalRef	string     `json:"externalref,omitempty"`	// id of the consent in the application, unique per appID
+	Conditions	[]Condition `json:"conditions,omitempty"`	// conditions on the context given to isconsent
+}
+
+// filter of the consent lists, applied by the chaincode
+type ConsentFilter struct {
+	DataType	string     `json:"datatype,omitempty"`
+	DataAccess	string     `json:"dataaccess,omitempty"`
+	ValidAt		string     `json:"validat,omitempty"`	// day (yyyy-mm-dd) or instant (RFC3339) in the period
+	CreatedAfter	string     `json:"createdafter,omitempty"`	// day or instant
+	CreatedBefore	string     `json:"createdbefore,omitempty"`	// day or instant
+	State		string     `json:"state,omitempty"`	// active (default), unactive or all
+	Sort		string     `json:"sort,omitempty"`	// attribute, descending with a '-' prefix (ex: -createdat)
+	Fields		[]string   `json:"fields,omitempty"`	// attributes of the consents, consentid is always returned
+}
+
+type OwnerKey struct {
+	AppID 		string     `json:"appid"`
+	OwnerID       	string     `json:"ownerid"`
+	Certificate	string     `json:"certificate"`
+	Registrant	string     `json:"registrant,omitempty"`
+}
+
+type AccessEvent struct {
+	AppID 		string     `json:"appid"`
+	EventID      	string     `json:"eventid"`
+	OwnerID       	string     `json:"ownerid"`
+	ConsumerID      string     `json:"consumerid"`
+	DataType      	string     `json:"datatype"`
+	DataAccess      string     `json:"dataaccess"`
+	ConsentID      	string     `json:"consentid"`
+	Timestamp      	string     `json:"timestamp"`
+}
+
+type BreakGlassEvent struct {
+	AppID 		string     `json:"appid"`
+	EventID      	string     `json:"eventid"`
+	OwnerID       	string     `json:"ownerid"`
+	ConsumerID      string     `json:"consumerid"`
+	DataType      	string     `json:"datatype"`
+	Reason      	string     `json:"reason"`
+	Requester      	string     `json:"requester"`
+	Dt_begin      	string     `json:"dtbegin"`
+	Dt_end       	string     `json:"dtend"`
+	Reviewed       	bool       `json:"reviewed"`
+	Reviewer       	string     `json:"reviewer,omitempty"`
+	ReviewedAt     	string     `json:"reviewedat,omitempty"`
+}
+
+type DataType struct {
+	Action		string     `json:"action,omitempty"`
+	Code		string     `json:"code"`
+	Label		string     `json:"label"`
+	Parent		string     `json:"parent,omitempty"`
+	Deprecated	bool       `json:"deprecated"`
+}
+
+type Counter struct {
+	Active		int        `json:"active"`
+	Revoked		int        `json:"revoked"`
+}
+
+// statistics of the consents of an application, a consent is active until it is revoked (an expired consent too)
+type ConsentStats struct {
+	AppID 		string              `json:"appid"`
+	Active		int                 `json:"active"`
+	Revoked		int                 `json:"revoked"`
+	ByDataType	map[string]Counter  `json:"bydatatype"`
+	ByConsumer	map[string]Counter  `json:"byconsumer"`
+	ByMonth		map[string]int      `json:"bymonth"`
+}
+
+type ConsentDecision struct {
+	Consent		string     `json:"consent"`
+	ConsentID      	string     `json:"consentid"`
+	BreakGlassID   	string     `json:"breakglassid"`
+	Unmet		[]Condition `json:"unmet,omitempty"`
+}
+
+type TemplateItem struct {
+	DataType      	string     `json:"datatype"`
+	DataAccess      string     `json:"dataaccess"`
+}
+
+type ConsentTemplate struct {
+	Action		string         `json:"action,omitempty"`
+	AppID 		string         `json:"appid,omitempty"`
+	TemplateID	string         `json:"templateid,omitempty"`
+	Label		string         `json:"label,omitempty"`
+	Items		[]TemplateItem `json:"items,omitempty"`
+	ValidityDays	int            `json:"validitydays,omitempty"`	// default validity of the consents created from the template
+	OwnerID       	string         `json:"ownerid,omitempty"`
+	ConsumerID      string         `json:"consumerid,omitempty"`
+	Dt_begin      	string         `json:"dtbegin,omitempty"`
+	Dt_end       	string         `json:"dtend,omitempty"`
+	GrantID      	string         `json:"grantid,omitempty"`
+	Mode		string         `json:"mode,omitempty"`	// allow or reject a consent overlapping an item
+	OwnerCert	string         `json:"ownercert,omitempty"`
+	Nonce		string         `json:"nonce,omitempty"`
+	OwnerSignatures	[]string       `json:"ownersignatures,omitempty"`	// one per item, signed with the nonce "nonce#n"
+}
+
+// options of the consents created from a template, checked for each item as for a consent created alone
+type GrantOptions struct {
+	Mode		string     `json:"mode,omitempty"`
+	OwnerCert	string     `json:"ownercert,omitempty"`
+	Nonce		string     `json:"nonce,omitempty"`
+	OwnerSignatures	[]string   `json:"ownersignatures,omitempty"`
+}
+
+type Grant struct {
+	AppID 		string     `json:"appid"`
+	GrantID      	string     `json:"grantid"`
+	TemplateID	string     `json:"templateid"`
+	ConsentIDs	[]string   `json:"consentids"`
+}
+
+type ConsumerGroup struct {
+	Action		string     `json:"action,omitempty"`
+	AppID 		string     `json:"appid"`
+	GroupID		string     `json:"groupid"`
+	Label		string     `json:"label,omitempty"`
+	Members		[]string   `json:"members,omitempty"`
+	ConsumerID      string     `json:"consumerid,omitempty"`	// member to add or remove
+}
+
+type ConsentPage struct {
+	AppID 		string            `json:"appid"`
+	Consents	[]json.RawMessage `json:"consents"`	// consent records as stored in the ledger
+	Bookmark	string            `json:"bookmark,omitempty"`
+	HasMore		bool              `json:"hasmore"`
+}
+
+type MigrationResult struct {
+	AppID 		string     `json:"appid"`
+	Migrated	int        `json:"migrated"`
+	Remaining	int        `json:"remaining"`	// consents still to upgrade, call Migrate again
+	SchemaVersion	int        `json:"schemaversion"`
+}
+
+type PastDecision struct {
+	Consent		string     `json:"consent"`
+	ConsentID      	string     `json:"consentid,omitempty"`
+	Version		int        `json:"version,omitempty"`
+	TxID		string     `json:"txid,omitempty"`
+	UpdatedAt	*time.Time `json:"updatedat,omitempty"`
 }
 
 
@@ -42,7 +228,7 @@ func (ch *ConsentHelper) Init(userCredentials UserCredentials) error{
 		return err
 	}
 	if err := eventHub.Connect(); err != nil {
-		return err
+		return ledgerError(err, "Connect event hub return error")
 	}
 	ch.Chain    = chain
 	ch.EventHub = eventHub
@@ -50,6 +236,16 @@ func (ch *ConsentHelper) Init(userCredentials UserCredentials) error{
 	return nil
 }
 
+func (ch *ConsentHelper) SetCommit(commit *Commit) {
+	ch.Commit = commit
+}
+
+func (ch *ConsentHelper) InitSession(session *Session) {
+	ch.Chain    = session.Chain
+	ch.EventHub = session.EventHub
+	ch.Initialized = true
+}
+
 func (ch *Consent) Print() string {
 	consentStr := fmt.Sprintf("ConsentID:%s ConsumerID:%s OwnerID:%s Datatype:%s Dataaccess:%s Dt_begin:%s Dt_end:%s", ch.ConsentID, ch.ConsumerID, ch.OwnerID, ch.DataType, ch.DataAccess, ch.Dt_begin, ch.Dt_end)
 	return consentStr
@@ -72,30 +268,95 @@ func (ch *ConsentHelper) GetConsent(chainCodeID, appID, consentID string) (Conse
 	return extractConsent(consentID, strResp, err)
 }
 
-func (ch *ConsentHelper) GetConsents(chainCodeID, appID string) ([]Consent, error) {
+// GetConsentRecord returns the consent whatever its state (a revoked consent keeps its anchored document)
+func (ch *ConsentHelper) GetConsentRecord(chainCodeID, appID, consentID string) (Consent, error) {
+	var args []string
+	args = append(args, "getconsent")
+	args = append(args, appID)
+	args = append(args, consentID)
+	args = append(args, "all")
+	strResp, err := ch.query(chainCodeID, args)
+	return extractConsent(consentID, strResp, err)
+}
+
+func (ch *ConsentHelper) GetConsentByRef(chainCodeID, appID, externalRef string) (Consent, error) {
+	var args []string
+	args = append(args, "getconsentbyref")
+	args = append(args, appID)
+	args = append(args, externalRef)
+	strResp, err := ch.query(chainCodeID, args)
+	return extractConsent(externalRef, strResp, err)
+}
+
+func (ch *ConsentHelper) GetConsents(chainCodeID, appID string, filters ...ConsentFilter) ([]Consent, error) {
 	var args []string
 	args = append(args, "getconsents")
 	args = append(args, appID)
+	args, err := appendFilter(args, filters)
+	if err != nil {
+		return nil, err
+	}
 	return extractConsents(ch.query(chainCodeID, args))
 }
 
-func (ch *ConsentHelper) GetOwnerConsents(chainCodeID, appID, ownerID string) ([]Consent, error) {
+func (ch *ConsentHelper) GetOwnerConsents(chainCodeID, appID, ownerID string, filters ...ConsentFilter) ([]Consent, error) {
 	var args []string
 	args = append(args, "getownerconsents")
 	args = append(args, appID)
 	args = append(args, ownerID)
+	args, err := appendFilter(args, filters)
+	if err != nil {
+		return nil, err
+	}
 	return extractConsents(ch.query(chainCodeID, args))
 }
 
-func (ch *ConsentHelper) GetConsumerConsents(chainCodeID, appID, consumerID string) ([]Consent, error) {
+func (ch *ConsentHelper) GetConsumerConsents(chainCodeID, appID, consumerID string, filters ...ConsentFilter) ([]Consent, error) {
 	var args []string
 	args = append(args, "getconsumerconsents")
 	args = append(args, appID)
 	args = append(args, consumerID)
+	args, err := appendFilter(args, filters)
+	if err != nil {
+		return nil, err
+	}
 	return extractConsents(ch.query(chainCodeID, args))
 }
 
-func (ch *ConsentHelper) CreateConsent(chainCodeID, appID, ownerID, consumerID, datatype, dataaccess, st_date, end_date string) (string, error) {
+// the filter is the optional json argument of the list functions of the chaincode
+func appendFilter(args []string, filters []ConsentFilter) ([]string, error) {
+	if len(filters) == 0 {
+		return args, nil
+	}
+	jsonFilter, err := json.Marshal(filters[0])
+	if err != nil {
+		return args, err
+	}
+	return append(args, string(jsonFilter)), nil
+}
+
+// SelectFields returns the consents reduced to the fields selected by a filter (and the consentid)
+func SelectFields(consents []Consent, fields []string) ([]map[string]interface{}, error) {
+	selected := make([]map[string]interface{}, 0, len(consents))
+	for _, consent := range consents {
+		content, err := json.Marshal(consent)
+		if err != nil {
+			return nil, err
+		}
+		record := map[string]interface{}{}
+		json.Unmarshal(content, &record)
+		values := map[string]interface{}{"consentid": consent.ConsentID}
+		for _, field := range fields {
+			if value, ok := record[field]; ok {
+				values[field] = value
+			}
+		}
+		selected = append(selected, values)
+	}
+	return selected, nil
+}
+
+func (ch *ConsentHelper) CreateConsent(chainCodeID, appID, ownerID, consumerID, datatype, dataaccess, st_date, end_date string, options ...ConsentOptions) (string, error) {
 	var args []string
 	args = append(args, "postconsent")
 	args = append(args, appID)
@@ -105,10 +366,48 @@ func (ch *ConsentHelper) CreateConsent(chainCodeID, appID, ownerID, consumerID,
 	args = append(args, dataaccess)
 	args = append(args, st_date)
 	args = append(args, end_date)
+	if len(options) > 0 {
+		jsonOptions, err := json.Marshal(options[0])
+		if err != nil {
+			return "", err
+		}
+		args = append(args, string(jsonOptions))
+	}
+	// the consentID is the txID, except when the period is merged in an existing consent
+	_, consentID, err := ch.createTransactionWithPayload(chainCodeID, args)
+	return consentID, err
+}
+
+// CanonicalConsentPayload returns the payload to sign by the owner for an owner-signed consent: the json array of
+// the arguments and of the nonce (a signed payload is accepted once).
+func CanonicalConsentPayload(appID, ownerID, consumerID, datatype, dataaccess, st_date, end_date, nonce string) []byte {
+	payload, _ := json.Marshal([]string{appID, ownerID, consumerID, datatype, dataaccess, st_date, end_date, nonce})
+	return payload
+}
+
+// RegisterOwnerKey registers the certificate of an owner, a registered key is rotated with the signature of the new
+// certificate by the registered key.
+func (ch *ConsentHelper) RegisterOwnerKey(chainCodeID, appID, ownerID, certificate string, signature ...string) (string, error) {
+	var args []string
+	args = append(args, "registerownerkey")
+	args = append(args, appID)
+	args = append(args, ownerID)
+	args = append(args, certificate)
+	if len(signature) > 0 && signature[0] != "" {
+		args = append(args, signature[0])
+	}
 	txID, err := ch.createTransaction(chainCodeID, args)
 	return txID, err
 }
 
+func (ch *ConsentHelper) GetOwnerKey(chainCodeID, appID, ownerID string) (OwnerKey, error) {
+	var args []string
+	args = append(args, "getownerkey")
+	args = append(args, appID)
+	args = append(args, ownerID)
+	return extractOwnerKey(ch.query(chainCodeID, args))
+}
+
 func (ch *ConsentHelper) DeleteConsents4Application(chainCodeID, appID string) (string, error) {
 	var args []string
 	args = append(args, "resetconsents")
@@ -137,6 +436,293 @@ func (ch *ConsentHelper) IsConsentExist(chainCodeID, appID, ownerID, consumerID,
 	return extractIsConsent(ch.query(chainCodeID, args))
 }
 
+// CheckConsent returns the decision for the access, the optional context is matched against the conditions of the consents.
+func (ch *ConsentHelper) CheckConsent(chainCodeID, appID, ownerID, consumerID, dataType, dataAccess string, context ...map[string]string) (ConsentDecision, error) {
+	var args []string
+	args = append(args, "checkconsent")
+	args = append(args, appID)
+	args = append(args, ownerID)
+	args = append(args, consumerID)
+	args = append(args, dataType)
+	args = append(args, dataAccess)
+	if len(context) > 0 && context[0] != nil {
+		jsonContext, err := json.Marshal(context[0])
+		if err != nil {
+			return ConsentDecision{}, err
+		}
+		args = append(args, string(jsonContext))
+	}
+	return extractDecision(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) WasConsent(chainCodeID, appID, ownerID, consumerID, dataType, dataAccess, instant string) (PastDecision, error) {
+	var args []string
+	args = append(args, "wasconsent")
+	args = append(args, appID)
+	args = append(args, ownerID)
+	args = append(args, consumerID)
+	args = append(args, dataType)
+	args = append(args, dataAccess)
+	args = append(args, instant)
+	return extractPastDecision(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) LogAccess(chainCodeID, appID, ownerID, consumerID, dataType, dataAccess, consentID string) (string, error) {
+	var args []string
+	args = append(args, "logaccess")
+	args = append(args, appID)
+	args = append(args, ownerID)
+	args = append(args, consumerID)
+	args = append(args, dataType)
+	args = append(args, dataAccess)
+	args = append(args, consentID)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) GetOwnerAccesses(chainCodeID, appID, ownerID string) ([]AccessEvent, error) {
+	var args []string
+	args = append(args, "getowneraccesses")
+	args = append(args, appID)
+	args = append(args, ownerID)
+	return extractAccessEvents(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) BreakGlass(chainCodeID, appID, ownerID, consumerID, dataType, reason, duration string) (string, error) {
+	var args []string
+	args = append(args, "breakglass")
+	args = append(args, appID)
+	args = append(args, ownerID)
+	args = append(args, consumerID)
+	args = append(args, dataType)
+	args = append(args, reason)
+	args = append(args, duration)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) GetPendingBreakGlass(chainCodeID, appID string) ([]BreakGlassEvent, error) {
+	var args []string
+	args = append(args, "getpendingbreakglass")
+	args = append(args, appID)
+	return extractBreakGlassEvents(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) AckBreakGlass(chainCodeID, appID, eventID string) (string, error) {
+	var args []string
+	args = append(args, "ackbreakglass")
+	args = append(args, appID)
+	args = append(args, eventID)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) AddDataType(chainCodeID, code, label, parent string) (string, error) {
+	var args []string
+	args = append(args, "adddatatype")
+	args = append(args, code)
+	args = append(args, label)
+	args = append(args, parent)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) ListDataTypes(chainCodeID string) ([]DataType, error) {
+	var args []string
+	args = append(args, "listdatatypes")
+	return extractDataTypes(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) DeprecateDataType(chainCodeID, code string) (string, error) {
+	var args []string
+	args = append(args, "deprecatedatatype")
+	args = append(args, code)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) PutTemplate(chainCodeID, appID, templateID, label string, items []TemplateItem, validityDays int) (string, error) {
+	jsonTemplate, err := json.Marshal(ConsentTemplate{Label: label, Items: items, ValidityDays: validityDays})
+	if err != nil {
+		return "", err
+	}
+	var args []string
+	args = append(args, "puttemplate")
+	args = append(args, appID)
+	args = append(args, templateID)
+	args = append(args, string(jsonTemplate))
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) GetTemplate(chainCodeID, appID, templateID string) (ConsentTemplate, error) {
+	var args []string
+	args = append(args, "gettemplate")
+	args = append(args, appID)
+	args = append(args, templateID)
+	return extractTemplate(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) ListTemplates(chainCodeID, appID string) ([]ConsentTemplate, error) {
+	var args []string
+	args = append(args, "listtemplates")
+	args = append(args, appID)
+	return extractTemplates(ch.query(chainCodeID, args))
+}
+
+// CreateConsentsFromTemplate creates all consents of a template in one transaction, end_date is optional
+// (the validity of the template is used when it is empty).
+func (ch *ConsentHelper) CreateConsentsFromTemplate(chainCodeID, appID, templateID, ownerID, consumerID, st_date, end_date string, options ...GrantOptions) (Grant, error) {
+	var args []string
+	args = append(args, "postconsentfromtemplate")
+	args = append(args, appID)
+	args = append(args, templateID)
+	args = append(args, ownerID)
+	args = append(args, consumerID)
+	args = append(args, st_date)
+	if end_date != "" || len(options) > 0 {
+		args = append(args, end_date)
+	}
+	if len(options) > 0 {
+		jsonOptions, err := json.Marshal(options[0])
+		if err != nil {
+			return Grant{}, err
+		}
+		args = append(args, string(jsonOptions))
+	}
+	_, payload, err := ch.createTransactionWithPayload(chainCodeID, args)
+	return extractGrant(payload, err)
+}
+
+func (ch *ConsentHelper) GetGrant(chainCodeID, appID, grantID string) ([]Consent, error) {
+	var args []string
+	args = append(args, "getgrant")
+	args = append(args, appID)
+	args = append(args, grantID)
+	return extractConsents(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) RevokeGrant(chainCodeID, appID, grantID string) (string, error) {
+	var args []string
+	args = append(args, "revokegrant")
+	args = append(args, appID)
+	args = append(args, grantID)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+// GroupReference returns the consumerID to use to give a consent to a consumer group.
+func GroupReference(groupID string) string {
+	return "group:" + groupID
+}
+
+func (ch *ConsentHelper) CreateGroup(chainCodeID, appID, groupID, label string) (string, error) {
+	var args []string
+	args = append(args, "creategroup")
+	args = append(args, appID)
+	args = append(args, groupID)
+	args = append(args, label)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) AddGroupMember(chainCodeID, appID, groupID, consumerID string) (string, error) {
+	var args []string
+	args = append(args, "addgroupmember")
+	args = append(args, appID)
+	args = append(args, groupID)
+	args = append(args, consumerID)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) RemoveGroupMember(chainCodeID, appID, groupID, consumerID string) (string, error) {
+	var args []string
+	args = append(args, "removegroupmember")
+	args = append(args, appID)
+	args = append(args, groupID)
+	args = append(args, consumerID)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) GetGroupMembers(chainCodeID, appID, groupID string) (ConsumerGroup, error) {
+	var args []string
+	args = append(args, "getgroupmembers")
+	args = append(args, appID)
+	args = append(args, groupID)
+	return extractGroup(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) ExportConsents(chainCodeID, appID string, pageSize int, bookmark string) (ConsentPage, error) {
+	var args []string
+	args = append(args, "exportconsents")
+	args = append(args, appID)
+	args = append(args, strconv.Itoa(pageSize))
+	args = append(args, bookmark)
+	return extractConsentPage(ch.query(chainCodeID, args))
+}
+
+func (ch *ConsentHelper) ImportConsents(chainCodeID, appID string, consents []json.RawMessage) (string, error) {
+	jsonConsents, err := json.Marshal(consents)
+	if err != nil {
+		return "", err
+	}
+	var args []string
+	args = append(args, "importconsents")
+	args = append(args, appID)
+	args = append(args, string(jsonConsents))
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) Migrate(chainCodeID, appID string, maxConsents int) (MigrationResult, error) {
+	var args []string
+	args = append(args, "migrate")
+	args = append(args, appID)
+	args = append(args, strconv.Itoa(maxConsents))
+	_, payload, err := ch.createTransactionWithPayload(chainCodeID, args)
+	return extractMigrationResult(payload, err)
+}
+
+func (ch *ConsentHelper) AnchorDocument(chainCodeID, appID, consentID, documentHash, documentURI, documentVersion string) (string, error) {
+	var args []string
+	args = append(args, "anchordocument")
+	args = append(args, appID)
+	args = append(args, consentID)
+	args = append(args, documentHash)
+	args = append(args, documentURI)
+	args = append(args, documentVersion)
+	txID, err := ch.createTransaction(chainCodeID, args)
+	return txID, err
+}
+
+func (ch *ConsentHelper) GetStats(chainCodeID, appID string) (ConsentStats, error) {
+	var args []string
+	args = append(args, "getstats")
+	args = append(args, appID)
+	return extractStats(ch.query(chainCodeID, args))
+}
+
+// CompactStats folds the deltas of the counters of the appID, the statistics are returned
+func (ch *ConsentHelper) CompactStats(chainCodeID, appID string) (ConsentStats, error) {
+	var args []string
+	args = append(args, "compactstats")
+	args = append(args, appID)
+	_, payload, err := ch.createTransactionWithPayload(chainCodeID, args)
+	return extractStats(payload, err)
+}
+
+func (ch *ConsentHelper) GetBlockHeight() (uint64, error) {
+	log.Debug("GetBlockHeight() : calling method -")
+	blockchainInfo, err := ch.Chain.QueryInfo()
+	if err != nil {
+		log.Error("QueryInfo return error: %v", err)
+		return 0, ledgerError(err, "Query blockchain info return error")
+	}
+	return blockchainInfo.Height, nil
+}
+
 func (ch *ConsentHelper) CreateConsentWithRegistration(chainCodeID, appID, ownerID, consumerID, datatype, dataaccess, st_date, end_date string) (string, error) {
 	var args []string
 	args = append(args, "postconsent")
@@ -158,27 +744,129 @@ func (ch *ConsentHelper) query(chainCodeID string, args []string) (string, error
 	transactionProposalResponses, _, err := sdkUtil.CreateAndSendTransactionProposal(ch.Chain, chainCodeID, ch.ChainID, args, []fabricClient.Peer{ch.Chain.GetPrimaryPeer()}, transientDataMap)
 	if err != nil {
 		log.Error("CreateAndSendTransactionProposal return error: %v", err)
-		return "", fmt.Errorf("Query CC return error")
+		return "", ledgerError(err, "Query CC return error")
 	}
 	response := string(transactionProposalResponses[0].GetResponsePayload())
 	return response, nil
 }
 
 func (ch *ConsentHelper) createTransaction(chainCodeID string, args []string) (string, error) {
+	txID, _, err := ch.createTransactionWithPayload(chainCodeID, args)
+	return txID, err
+}
+
+// createTransactionWithPayload returns the transaction id and the payload returned by the chaincode
+func (ch *ConsentHelper) createTransactionWithPayload(chainCodeID string, args []string) (string, string, error) {
 	log.Debug("createTransaction(chainCodeID:"+ chainCodeID+" args:"+ strings.Join(args," ") +") : calling method -")
 	transientDataMap := make(map[string][]byte)
 	transientDataMap["result"] = []byte("TODO change...")
 	transactionProposalResponse, txID, err := sdkUtil.CreateAndSendTransactionProposal(ch.Chain, chainCodeID, ch.ChainID, args, []fabricClient.Peer{ch.Chain.GetPrimaryPeer()}, transientDataMap)
 	if err != nil {
 		log.Error("CreateAndSendTransactionProposal return error: %v", err)
-		return "", fmt.Errorf("CreateTransactionProposal for CC return error")
+		return "", "", ledgerError(err, "CreateTransactionProposal for CC return error")
+	}
+	mode := COMMIT_SUBMIT
+	if ch.Commit != nil && ch.Commit.Mode != "" {
+		mode = ch.Commit.Mode
+	}
+	if mode == COMMIT_ASYNC && ch.Commit.Tracker == nil {
+		mode = COMMIT_SUBMIT
+	}
+	// the commit event is registered before the transaction is sent
+	var committed chan error
+	switch mode {
+	case COMMIT_WAIT:
+		committed = make(chan error, 1)
+		ch.EventHub.RegisterTxEvent(txID, func(txID string, err error) {
+			select {
+			case committed <- err:
+			default:
+			}
+		})
+		defer ch.EventHub.UnregisterTxEvent(txID)
+	case COMMIT_ASYNC:
+		ch.trackTransaction(txID)
 	}
 	_, err = sdkUtil.CreateAndSendTransaction(ch.Chain, transactionProposalResponse)
 	if err != nil {
 		log.Error("CreateAndSendTransaction return error: %v", err)
-		return "", fmt.Errorf("CreateTransaction for CC return error")
+		if mode == COMMIT_ASYNC {
+			ch.EventHub.UnregisterTxEvent(txID)
+			ch.Commit.Tracker.Set(TransactionStatus{TxID: txID, Status: TX_INVALID, Reason: "transaction not sent"})
+		}
+		return "", "", ledgerError(err, "CreateTransaction for CC return error")
 	}
-	return txID, nil
+	status := TransactionStatus{TxID: txID, Status: TX_PENDING}
+	if mode == COMMIT_WAIT {
+		status, err = ch.waitForCommit(txID, committed)
+		if ch.Commit != nil {
+			ch.Commit.Record(status)
+		}
+		if err != nil {
+			return "", "", err
+		}
+	} else if ch.Commit != nil {
+		ch.Commit.Record(status)
+	}
+	return txID, string(transactionProposalResponse[0].GetResponsePayload()), nil
+}
+
+// waitForCommit waits the commit event of the transaction and returns its validation code
+func (ch *ConsentHelper) waitForCommit(txID string, committed chan error) (TransactionStatus, error) {
+	log.Debug("waitForCommit(txID:"+ txID+") : calling method -")
+	timeout := time.Second * 30
+	if ch.Commit.Timeout > 0 {
+		timeout = ch.Commit.Timeout
+	}
+	select {
+	case err := <-committed:
+		if err == nil {
+			return TransactionStatus{TxID: txID, Status: TX_VALID, ValidationCode: pb.TxValidationCode_VALID.String()}, nil
+		}
+		status := ch.ledgerTransactionStatus(txID, err)
+		return status, invalidTransactionError(status)
+	case <-time.After(timeout):
+		return TransactionStatus{TxID: txID, Status: TX_PENDING}, NewError(ERROR_TIMEOUT, "transaction %s not committed after %s", txID, timeout)
+	}
+}
+
+// trackTransaction registers the transaction in the tracker, the status is updated by the commit event
+func (ch *ConsentHelper) trackTransaction(txID string) {
+	tracker := ch.Commit.Tracker
+	eventHub := ch.EventHub
+	tracker.Track(txID, eventHub)
+	eventHub.RegisterTxEvent(txID, func(txID string, err error) {
+		eventHub.UnregisterTxEvent(txID)
+		if err == nil {
+			tracker.Set(TransactionStatus{TxID: txID, Status: TX_VALID, ValidationCode: pb.TxValidationCode_VALID.String()})
+			return
+		}
+		// the validation code is queried out of the event hub goroutine
+		go tracker.Set(ch.ledgerTransactionStatus(txID, err))
+	})
+}
+
+// TransactionStatus returns the status of a committed transaction, read in the ledger
+func (ch *ConsentHelper) TransactionStatus(txID string) (TransactionStatus, error) {
+	log.Debug("TransactionStatus(txID:"+ txID+") : calling method -")
+	processedTransaction, err := ch.Chain.QueryTransaction(txID)
+	if err != nil {
+		err = ledgerError(err, "Query transaction return error")
+		if ErrorCode(err) == ERROR_INTERNAL {
+			return TransactionStatus{}, NewError(ERROR_NOTFOUND, "transaction %s not found", txID)
+		}
+		return TransactionStatus{}, err
+	}
+	return committedStatus(txID, processedTransaction.ValidationCode), nil
+}
+
+// status of an invalid transaction, with the validation code of the ledger when it can be read
+func (ch *ConsentHelper) ledgerTransactionStatus(txID string, eventErr error) TransactionStatus {
+	status, err := ch.TransactionStatus(txID)
+	if err != nil || status.Status == TX_VALID {
+		return TransactionStatus{TxID: txID, Status: TX_INVALID, Reason: strings.TrimSpace(eventErr.Error())}
+	}
+	return status
 }
 
 func (ch *ConsentHelper) createTransactionWithRegistration(chainCodeID string, args []string) (string, error) {
@@ -190,28 +878,30 @@ func (ch *ConsentHelper) createTransactionWithRegistration(chainCodeID string, a
 	transientDataMap["result"] = []byte("TODO change...")
 	transactionProposalResponse, txID, err := sdkUtil.CreateAndSendTransactionProposal(ch.Chain, chainCodeID, ch.ChainID, args, []fabricClient.Peer{ch.Chain.GetPrimaryPeer()}, transientDataMap)
 	if err != nil {
-		return "", fmt.Errorf("CreateAndSendTransactionProposal return error: %v", err)
+		log.Error("CreateAndSendTransactionProposal return error: %v", err)
+		return "", ledgerError(err, "CreateTransactionProposal for CC return error")
 	}
 	// Register for commit event
 	done, fail := sdkUtil.RegisterTxEvent(txID, ch.EventHub)
 
 	_, err = sdkUtil.CreateAndSendTransaction(ch.Chain, transactionProposalResponse)
 	if err != nil {
-		return "", fmt.Errorf("CreateAndSendTransaction return error: %v", err)
+		log.Error("CreateAndSendTransaction return error: %v", err)
+		return "", ledgerError(err, "CreateTransaction for CC return error")
 	}
 
 	select {
 	case <-done:
 	case <-fail:
-		return txID, fmt.Errorf("invoke Error received from eventhub for txid(%s) error(%v)", txID, fail)
+		return txID, NewError(ERROR_INTERNAL, "invoke Error received from eventhub for txid(%s) error(%v)", txID, fail)
 	case <-time.After(time.Second * 30):
-		return txID, fmt.Errorf("invoke Didn't receive block event for txid(%s)", txID)
+		return txID, NewError(ERROR_TIMEOUT, "invoke Didn't receive block event for txid(%s)", txID)
 	}
 
 	select {
 	case <-done1:
 	case <-time.After(time.Second * 20):
-		return txID, fmt.Errorf("Did NOT receive CC for eventId(%s)\n", eventID)
+		return txID, NewError(ERROR_TIMEOUT, "Did NOT receive CC for eventId(%s)", eventID)
 	}
 	ch.EventHub.UnregisterChaincodeEvent(rce)
 
@@ -228,7 +918,7 @@ func extractConsents(stringresp string, err error) ([]Consent, error) {
 	err = dec.Decode(&consents)
 	if err != nil {
 		log.Error(err)
-		err = fmt.Errorf("Extract consents return error")
+		err = NewError(ERROR_INTERNAL, "Extract consents return error")
 	}
 	return consents, err
 }
@@ -242,11 +932,193 @@ func extractConsent(consentID, stringresp string, err error) (Consent, error) {
 	err = dec.Decode(&consent)
 	if err != nil {
 		log.Error(err)
-		err = fmt.Errorf("Extract a consent return error")
+		err = NewError(ERROR_INTERNAL, "Extract a consent return error")
 	}
 	return consent, err
 }
 
+func extractAccessEvents(stringresp string, err error) ([]AccessEvent, error) {
+	var events []AccessEvent
+	if err != nil {
+		return events, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&events)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract access events return error")
+	}
+	return events, err
+}
+
+func extractBreakGlassEvents(stringresp string, err error) ([]BreakGlassEvent, error) {
+	var events []BreakGlassEvent
+	if err != nil {
+		return events, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&events)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract break-glass events return error")
+	}
+	return events, err
+}
+
+func extractDataTypes(stringresp string, err error) ([]DataType, error) {
+	var dataTypes []DataType
+	if err != nil {
+		return dataTypes, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&dataTypes)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract data types return error")
+	}
+	return dataTypes, err
+}
+
+func extractTemplate(stringresp string, err error) (ConsentTemplate, error) {
+	var consentTemplate ConsentTemplate
+	if err != nil {
+		return consentTemplate, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&consentTemplate)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract template return error")
+	}
+	return consentTemplate, err
+}
+
+func extractTemplates(stringresp string, err error) ([]ConsentTemplate, error) {
+	var templates []ConsentTemplate
+	if err != nil {
+		return templates, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&templates)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract templates return error")
+	}
+	return templates, err
+}
+
+func extractGrant(stringresp string, err error) (Grant, error) {
+	var grant Grant
+	if err != nil {
+		return grant, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&grant)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract grant return error")
+	}
+	return grant, err
+}
+
+func extractGroup(stringresp string, err error) (ConsumerGroup, error) {
+	var group ConsumerGroup
+	if err != nil {
+		return group, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&group)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract consumer group return error")
+	}
+	return group, err
+}
+
+func extractConsentPage(stringresp string, err error) (ConsentPage, error) {
+	var page ConsentPage
+	if err != nil {
+		return page, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&page)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract consent page return error")
+	}
+	return page, err
+}
+
+func extractMigrationResult(stringresp string, err error) (MigrationResult, error) {
+	var result MigrationResult
+	if err != nil {
+		return result, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&result)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract migration result return error")
+	}
+	return result, err
+}
+
+func extractOwnerKey(stringresp string, err error) (OwnerKey, error) {
+	var ownerKey OwnerKey
+	if err != nil {
+		return ownerKey, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&ownerKey)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract owner key return error")
+	}
+	return ownerKey, err
+}
+
+func extractStats(stringresp string, err error) (ConsentStats, error) {
+	var stats ConsentStats
+	if err != nil {
+		return stats, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&stats)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract consent statistics return error")
+	}
+	return stats, err
+}
+
+func extractDecision(stringresp string, err error) (ConsentDecision, error) {
+	var decision ConsentDecision
+	if err != nil {
+		return decision, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&decision)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract consent decision return error")
+	}
+	return decision, err
+}
+
+func extractPastDecision(stringresp string, err error) (PastDecision, error) {
+	var decision PastDecision
+	if err != nil {
+		return decision, err
+	}
+	dec := json.NewDecoder(strings.NewReader(stringresp))
+	err = dec.Decode(&decision)
+	if err != nil {
+		log.Error(err)
+		err = NewError(ERROR_INTERNAL, "Extract past consent decision return error")
+	}
+	return decision, err
+}
+
 func extractIsConsent(stringresp string, err error) (bool, error) {
 	if err != nil {
 		return false, err
@@ -5,6 +5,15 @@ import (
 	"fmt"
 	"time"
 	"strconv"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
 )
 
 
@@ -164,7 +173,252 @@ func TestIsConsentExist(t *testing.T) {
 	}
 }
 
+func TestCheckConsent(t *testing.T) {
+	_, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID1, OWNERID2, CONSUMERID3, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7))
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	decision, err := consHelper.CheckConsent(configuration.ChainCodeID, APPID1, OWNERID2, CONSUMERID3, DATATYPE1, DATAACCESS1)
+	if err != nil {
+		t.Error("CheckConsent return error: ", err)
+	}
+	if decision.Consent != "True" || decision.ConsentID == "" {
+		t.Error("bad response for CheckConsent...")
+	}
+}
+
+func TestLogAccess(t *testing.T) {
+	consentID, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID6, OWNERID3, CONSUMERID2, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7))
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	eventID, err := consHelper.LogAccess(configuration.ChainCodeID, APPID6, OWNERID3, CONSUMERID2, DATATYPE1, DATAACCESS1, consentID)
+	if err != nil {
+		t.Error("LogAccess return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	events, err := consHelper.GetOwnerAccesses(configuration.ChainCodeID, APPID6, OWNERID3)
+	if err != nil {
+		t.Error("GetOwnerAccesses return error: ", err)
+	}
+	found := false
+	for _, event := range events {
+		if event.EventID == eventID && event.ConsentID == consentID {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("access event not found in owner accesses...")
+	}
+}
+
+func TestDataTypes(t *testing.T) {
+	code := "T" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	_, err := consHelper.AddDataType(configuration.ChainCodeID, code, "test data type", "CAR")
+	if err != nil {
+		t.Error("AddDataType return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	_, err = consHelper.CreateConsent(configuration.ChainCodeID, APPID1, OWNERID1, CONSUMERID1, code, DATAACCESS1, getStringDateNow(0), getStringDateNow(7))
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	_, err = consHelper.DeprecateDataType(configuration.ChainCodeID, code)
+	if err != nil {
+		t.Error("DeprecateDataType return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	dataTypes, err := consHelper.ListDataTypes(configuration.ChainCodeID)
+	if err != nil {
+		t.Error("ListDataTypes return error: ", err)
+	}
+	found := false
+	for _, dataType := range dataTypes {
+		if dataType.Code == code && dataType.Parent == "CAR" && dataType.Deprecated {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("deprecated data type not found in the taxonomy...")
+	}
+}
+
+func TestCreateOwnerSignedConsent(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: OWNERID2}, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	_, err := consHelper.RegisterOwnerKey(configuration.ChainCodeID, APPID5, OWNERID2, certPEM)
+	if err != nil {
+		t.Error("RegisterOwnerKey return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	nonce := strconv.FormatInt(time.Now().UnixNano(), 10)
+	digest := sha256.Sum256(CanonicalConsentPayload(APPID5, OWNERID2, CONSUMERID2, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7), nonce))
+	signature, _ := key.Sign(rand.Reader, digest[:], nil)
+	options := ConsentOptions{OwnerSignature: base64.StdEncoding.EncodeToString(signature), OwnerCert: certPEM, Nonce: nonce}
+	consentID, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID5, OWNERID2, CONSUMERID2, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7), options)
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	consent, err := consHelper.GetConsent(configuration.ChainCodeID, APPID5, consentID)
+	if err != nil {
+		t.Error("GetConsent return error: ", err)
+	}
+	if !consent.OwnerSigned {
+		t.Error("consent is not marked as signed by the owner...")
+	}
+}
+
+func TestCreateConsentMerge(t *testing.T) {
+	options := ConsentOptions{Mode: "merge"}
+	consentID, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID3, OWNERID3, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7), options)
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	mergedID, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID3, OWNERID3, CONSUMERID1, DATATYPE1, DATAACCESS1, getStringDateNow(5), getStringDateNow(10), options)
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	consent, err := consHelper.GetConsent(configuration.ChainCodeID, APPID3, consentID)
+	if err != nil {
+		t.Error("GetConsent return error: ", err)
+	}
+	if consent.Dt_end < getStringDateNow(10) {
+		t.Error("period of the consent ", consentID, " not merged (merged in ", mergedID, ")")
+	}
+}
+
+func TestGetConsentByRef(t *testing.T) {
+	externalRef := "REF" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	options := ConsentOptions{ExternalRef: externalRef}
+	consentID, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID4, OWNERID2, CONSUMERID2, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7), options)
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	retryID, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID4, OWNERID2, CONSUMERID2, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7), options)
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	if retryID != consentID {
+		t.Error("retry created another consent: ", retryID, " instead of ", consentID)
+	}
+	consent, err := consHelper.GetConsentByRef(configuration.ChainCodeID, APPID4, externalRef)
+	if err != nil {
+		t.Error("GetConsentByRef return error: ", err)
+	}
+	if consent.ConsentID != consentID {
+		t.Error("bad consent for the reference: ", consent.ConsentID)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	before, err := consHelper.GetStats(configuration.ChainCodeID, APPID5)
+	if err != nil {
+		t.Error("GetStats return error: ", err)
+	}
+	_, err = consHelper.CreateConsent(configuration.ChainCodeID, APPID5, OWNERID3, CONSUMERID3, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7))
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	after, err := consHelper.GetStats(configuration.ChainCodeID, APPID5)
+	if err != nil {
+		t.Error("GetStats return error: ", err)
+	}
+	if after.Active != before.Active+1 || after.ByDataType[DATATYPE1].Active != before.ByDataType[DATATYPE1].Active+1 {
+		t.Error("counters not updated by the creation of a consent...")
+	}
+}
+
 func getStringDateNow(nbdaysafter time.Duration) string{
 	t := time.Now().Add(nbdaysafter * 24 * time.Hour)
 	return t.Format("2006-01-02")
-}
\ No newline at end of file
+}
+func TestWasConsent(t *testing.T) {
+	consentID, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID3, OWNERID2, CONSUMERID3, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7))
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	instant := time.Now().UTC().Format(time.RFC3339)
+	_, err = consHelper.RemoveConsent(configuration.ChainCodeID, APPID3, consentID)
+	if err != nil {
+		t.Error("RemoveConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	decision, err := consHelper.WasConsent(configuration.ChainCodeID, APPID3, OWNERID2, CONSUMERID3, DATATYPE1, DATAACCESS1, instant)
+	if err != nil {
+		t.Error("WasConsent return error: ", err)
+	}
+	if decision.Consent != "True" || decision.ConsentID != consentID || decision.Version != 1 {
+		t.Error("consent ", consentID, " not granted at ", instant, " before its revocation")
+	}
+	decision, err = consHelper.WasConsent(configuration.ChainCodeID, APPID3, OWNERID2, CONSUMERID3, DATATYPE1, DATAACCESS1, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		t.Error("WasConsent return error: ", err)
+	}
+	if decision.Consent != "False" {
+		t.Error("consent ", consentID, " still granted after its revocation")
+	}
+}
+
+func TestCreateConsentsFromTemplate(t *testing.T) {
+	items := []TemplateItem{{DataType: DATATYPE1, DataAccess: DATAACCESS1}, {DataType: "WS", DataAccess: DATAACCESS1}}
+	_, err := consHelper.PutTemplate(configuration.ChainCodeID, APPID4, "package", "data package", items, 30)
+	if err != nil {
+		t.Error("PutTemplate return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	grant, err := consHelper.CreateConsentsFromTemplate(configuration.ChainCodeID, APPID4, "package", OWNERID1, CONSUMERID2, getStringDateNow(0), "")
+	if err != nil {
+		t.Error("CreateConsentsFromTemplate return error: ", err)
+	}
+	if len(grant.ConsentIDs) != len(items) {
+		t.Error("bad number of consents in grant ", grant.GrantID)
+	}
+	time.Sleep(TransactionTimeout)
+	_, err = consHelper.RevokeGrant(configuration.ChainCodeID, APPID4, grant.GrantID)
+	if err != nil {
+		t.Error("RevokeGrant return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	consents, err := consHelper.GetGrant(configuration.ChainCodeID, APPID4, grant.GrantID)
+	if err != nil {
+		t.Error("GetGrant return error: ", err)
+	}
+	for _, consent := range consents {
+		if consent.State != "unactive" {
+			t.Error("consent ", consent.ConsentID, " of grant ", grant.GrantID, " not revoked")
+		}
+	}
+}
+
+func TestCheckConsentWithConditions(t *testing.T) {
+	options := ConsentOptions{Conditions: []Condition{{Attribute: "country", Operator: "eq", Value: "FR"}}}
+	_, err := consHelper.CreateConsent(configuration.ChainCodeID, APPID4, OWNERID3, CONSUMERID3, DATATYPE1, DATAACCESS1, getStringDateNow(0), getStringDateNow(7), options)
+	if err != nil {
+		t.Error("CreateConsent return error: ", err)
+	}
+	time.Sleep(TransactionTimeout)
+	decision, err := consHelper.CheckConsent(configuration.ChainCodeID, APPID4, OWNERID3, CONSUMERID3, DATATYPE1, DATAACCESS1, map[string]string{"country": "FR"})
+	if err != nil {
+		t.Error("CheckConsent return error: ", err)
+	}
+	if decision.Consent != "True" {
+		t.Error("conditional consent not granted for a matching context")
+	}
+	decision, err = consHelper.CheckConsent(configuration.ChainCodeID, APPID4, OWNERID3, CONSUMERID3, DATATYPE1, DATAACCESS1, map[string]string{"country": "US"})
+	if err != nil {
+		t.Error("CheckConsent return error: ", err)
+	}
+	if decision.Consent != "False" || len(decision.Unmet) != 1 {
+		t.Error("conditional consent granted or unmet condition not returned for a context not matching")
+	}
+}
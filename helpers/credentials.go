@@ -0,0 +1,136 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	fabricCAClient "github.com/hyperledger/fabric-sdk-go/fabric-ca-client"
+)
+
+// iterations of the hash of the enrollment secrets
+const SECRET_HASH_ITERATIONS = 10000
+
+// salted hash of the enrollment secret of a user, kept beside the user (<username>.json) in the state store
+type secretHash struct {
+	Salt		string	`json:"salt"`
+	Hash		string	`json:"hash"`
+	Iterations	int	`json:"iterations"`
+}
+
+// CheckCredentials checks the enrollment secret of a user against the salted hash stored at its enrollment by the
+// API: a user not in the state store is enrolled by the CA, a user enrolled without a stored hash (before the hashes)
+// is checked once by the CA at its first login, then its hash is stored and the CA is not called again
+func CheckCredentials(userCredentials UserCredentials, statStorePath string) error {
+	log.Debug("CheckCredentials(username:"+ userCredentials.UserName+") : calling method -")
+	if userCredentials.UserName == "" || userCredentials.EnrollmentSecret == "" {
+		return NewError(ERROR_VALIDATION, "username and password are mandatory!")
+	}
+	if strings.ContainsAny(userCredentials.UserName, "/\\") {
+		return NewError(ERROR_VALIDATION, "username is not valid")
+	}
+	hashPath := secretHashPath(userCredentials.UserName, statStorePath)
+	data, err := ioutil.ReadFile(hashPath)
+	if err == nil {
+		var stored secretHash
+		if err = json.Unmarshal(data, &stored); err != nil {
+			return NewError(ERROR_INTERNAL, "bad secret hash of %s", userCredentials.UserName)
+		}
+		if !stored.match(userCredentials.EnrollmentSecret) {
+			return NewError(ERROR_UNAUTHORIZED, "bad credentials")
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return NewError(ERROR_INTERNAL, "read secret hash return error: %v", err)
+	}
+	if isEnrolled(userCredentials.UserName, statStorePath) {
+		if err = checkSecretByCA(userCredentials); err != nil {
+			return err
+		}
+		return saveSecretHash(userCredentials, statStorePath)
+	}
+	// the enrollment by the CA checks the secret, its hash is stored by getClient
+	_, err = getClient(userCredentials, statStorePath)
+	return err
+}
+
+// checkSecretByCA checks the secret of a user enrolled before the hashes with an enrollment by the CA (the new
+// certificate is not kept), replaced in the tests
+var checkSecretByCA = func(userCredentials UserCredentials) error {
+	caClient, err := fabricCAClient.NewFabricCAClient()
+	if err != nil {
+		return enrollmentError(err)
+	}
+	if _, _, err = caClient.Enroll(userCredentials.UserName, userCredentials.EnrollmentSecret); err != nil {
+		return enrollmentError(err)
+	}
+	return nil
+}
+
+// saveSecretHash stores the salted hash of a secret checked by an enrollment of the CA
+func saveSecretHash(userCredentials UserCredentials, statStorePath string) error {
+	stored, err := newSecretHash(userCredentials.EnrollmentSecret)
+	if err != nil {
+		return NewError(ERROR_INTERNAL, "hash secret return error: %v", err)
+	}
+	data, _ := json.Marshal(stored)
+	if err = ioutil.WriteFile(secretHashPath(userCredentials.UserName, statStorePath), data, 0600); err != nil {
+		return NewError(ERROR_INTERNAL, "write secret hash return error: %v", err)
+	}
+	return nil
+}
+
+// isEnrolled returns true if the user is in the state store
+func isEnrolled(userName, statStorePath string) bool {
+	_, err := os.Stat(path.Join(statStorePath, userName+".json"))
+	return err == nil
+}
+
+func newSecretHash(secret string) (secretHash, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return secretHash{}, err
+	}
+	stored := secretHash{Salt: hex.EncodeToString(salt), Iterations: SECRET_HASH_ITERATIONS}
+	stored.Hash = hex.EncodeToString(hashSecret(secret, salt, stored.Iterations))
+	return stored, nil
+}
+
+func (sh secretHash) match(secret string) bool {
+	salt, err := hex.DecodeString(sh.Salt)
+	if err != nil {
+		return false
+	}
+	hash, err := hex.DecodeString(sh.Hash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hash, hashSecret(secret, salt, sh.Iterations)) == 1
+}
+
+func hashSecret(secret string, salt []byte, iterations int) []byte {
+	hash := sha256.Sum256(append(salt, secret...))
+	for i := 1; i < iterations; i++ {
+		hash = sha256.Sum256(append(hash[:], salt...))
+	}
+	return hash[:]
+}
+
+func secretHashPath(userName, statStorePath string) string {
+	return path.Join(statStorePath, userName+".secret")
+}
+
+// ForgetCredentials drops the secret hash of a revoked user, the next login is checked by the CA
+func ForgetCredentials(userName, statStorePath string) error {
+	err := os.Remove(secretHashPath(userName, statStorePath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
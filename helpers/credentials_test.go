@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCheckCredentialsWithSecretHash(t *testing.T) {
+	statStorePath, err := ioutil.TempDir("", "statstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(statStorePath)
+	stored, err := newSecretHash("pwd1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(stored)
+	ioutil.WriteFile(secretHashPath("user1", statStorePath), data, 0600)
+
+	err = CheckCredentials(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"}, statStorePath)
+	if err != nil {
+		t.Error(err)
+	}
+	err = CheckCredentials(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd2"}, statStorePath)
+	if ErrorCode(err) != ERROR_UNAUTHORIZED {
+		t.Error("bad credentials accepted: ", err)
+	}
+	err = CheckCredentials(UserCredentials{UserName: "user1"}, statStorePath)
+	if ErrorCode(err) != ERROR_VALIDATION {
+		t.Error("empty secret accepted: ", err)
+	}
+}
+
+func TestCheckCredentialsEnrolledWithoutSecretHash(t *testing.T) {
+	statStorePath, err := ioutil.TempDir("", "statstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(statStorePath)
+	ioutil.WriteFile(path.Join(statStorePath, "user1.json"), []byte("{}"), 0600)
+	checks := 0
+	defer func(check func(UserCredentials) error) { checkSecretByCA = check }(checkSecretByCA)
+	checkSecretByCA = func(userCredentials UserCredentials) error {
+		checks++
+		if userCredentials.EnrollmentSecret != "pwd1" {
+			return NewError(ERROR_UNAUTHORIZED, "bad credentials")
+		}
+		return nil
+	}
+
+	// a bad secret is refused by the CA, no hash is stored
+	err = CheckCredentials(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd2"}, statStorePath)
+	if ErrorCode(err) != ERROR_UNAUTHORIZED {
+		t.Error("bad credentials accepted: ", err)
+	}
+	// the first login is checked by the CA, then by the stored hash
+	for i := 0; i < 2; i++ {
+		err = CheckCredentials(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"}, statStorePath)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	err = CheckCredentials(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd2"}, statStorePath)
+	if ErrorCode(err) != ERROR_UNAUTHORIZED {
+		t.Error("bad credentials accepted: ", err)
+	}
+	if checks != 2 {
+		t.Error("bad number of checks by the CA: ", checks)
+	}
+}
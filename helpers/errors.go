@@ -0,0 +1,131 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// codes of the typed errors returned by the helpers
+const (
+	ERROR_NOTFOUND     = "not_found"
+	ERROR_UNAUTHORIZED = "unauthorized"
+	ERROR_FORBIDDEN    = "forbidden"
+	ERROR_VALIDATION   = "validation"
+	ERROR_CONFLICT     = "conflict"
+	ERROR_UNAVAILABLE  = "ledger_unavailable"
+	ERROR_TIMEOUT      = "timeout"
+	ERROR_INTERNAL     = "internal"
+)
+
+type Error struct {
+	Code		string
+	Message		string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(code, format string, a ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// ErrorCode returns the code of a typed error, empty for an error not returned by the helpers
+func ErrorCode(err error) string {
+	if typedErr, ok := err.(*Error); ok {
+		return typedErr.Code
+	}
+	return ""
+}
+
+// payload of shim.Error(buildError(...)) in the chaincode, the quotes are escaped in some peer messages
+var chaincodeErrorRegexp = regexp.MustCompile(`\{\\?"Error\\?"\s*:\s*\\?"(.*?)\\?"\}`)
+
+// classification of the chaincode messages, the first match wins
+var chaincodeErrorCodes = []struct {
+	pattern		string
+	code		string
+}{
+	{"does not exist", ERROR_NOTFOUND},
+	{"is not registered", ERROR_NOTFOUND},
+	{"already exists", ERROR_CONFLICT},
+	{"already used", ERROR_CONFLICT},
+	{"already anchored", ERROR_CONFLICT},
+	{"overlapping consent", ERROR_CONFLICT},
+	{"is not active", ERROR_CONFLICT},
+	{"Identity without", ERROR_UNAUTHORIZED},
+	{"acknowledged by its requester", ERROR_FORBIDDEN},
+	{"rotated by its registrant", ERROR_FORBIDDEN},
+	{"does not match the registered key", ERROR_UNAUTHORIZED},
+	{"Get creator identity", ERROR_UNAUTHORIZED},
+	{"Incorrect number of arguments", ERROR_VALIDATION},
+	{"Invalid function", ERROR_VALIDATION},
+	{"format error", ERROR_VALIDATION},
+	{"not valid", ERROR_VALIDATION},
+	{"not issued to the owner", ERROR_VALIDATION},
+	{"mandatory", ERROR_VALIDATION},
+	{"is deprecated", ERROR_VALIDATION},
+	{"does not match", ERROR_VALIDATION},
+	{"must be", ERROR_VALIDATION},
+	{"Expecting", ERROR_VALIDATION},
+}
+
+// failures of the connection to the peers, orderer or CA
+var timeoutPatterns = []string{"DeadlineExceeded", "deadline exceeded", "timed out", "timeout", "Didn't receive"}
+var unavailablePatterns = []string{"Unavailable", "connection refused", "transport is closing", "no such host", "connection is unavailable", "EOF"}
+
+// chaincodeError returns the error of the chaincode embedded in a peer error, nil if there is none
+func chaincodeError(err error) *Error {
+	matches := chaincodeErrorRegexp.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return nil
+	}
+	message := strings.Replace(matches[1], `\"`, `"`, -1)
+	for _, c := range chaincodeErrorCodes {
+		if strings.Contains(message, c.pattern) {
+			return &Error{Code: c.code, Message: message}
+		}
+	}
+	return &Error{Code: ERROR_INTERNAL, Message: message}
+}
+
+// ledgerError converts an error of the SDK into a typed error, message describes the failed operation
+func ledgerError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	if ccErr := chaincodeError(err); ccErr != nil {
+		return ccErr
+	}
+	if containsOne(err.Error(), timeoutPatterns) {
+		return &Error{Code: ERROR_TIMEOUT, Message: message + " (timeout)"}
+	}
+	if containsOne(err.Error(), unavailablePatterns) {
+		return &Error{Code: ERROR_UNAVAILABLE, Message: message + " (ledger unavailable)"}
+	}
+	return &Error{Code: ERROR_INTERNAL, Message: message}
+}
+
+// enrollmentError converts an error of the enrollment of a user, a refused enrollment is unauthorized
+func enrollmentError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if containsOne(err.Error(), timeoutPatterns) {
+		return &Error{Code: ERROR_TIMEOUT, Message: "enrollment timeout"}
+	}
+	if containsOne(err.Error(), unavailablePatterns) {
+		return &Error{Code: ERROR_UNAVAILABLE, Message: "CA unavailable"}
+	}
+	return &Error{Code: ERROR_UNAUTHORIZED, Message: "bad credentials"}
+}
+
+func containsOne(s string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(s, pattern) {
+			return true
+		}
+	}
+	return false
+}
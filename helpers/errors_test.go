@@ -0,0 +1,36 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLedgerErrorFromChaincodePayload(t *testing.T) {
+	err := ledgerError(errors.New(`invoke Endorser localhost:7051 return error: rpc error: code = Unknown desc = chaincode error (status: 500, message: {"Error":"Consent does not exist:1234"})`), "Query CC return error")
+	if ErrorCode(err) != ERROR_NOTFOUND || err.Error() != "Consent does not exist:1234" {
+		t.Error("bad error: ", ErrorCode(err), err)
+	}
+	err = ledgerError(errors.New(`message: {\"Error\":\"An overlapping consent exists:1234\"}`), "Query CC return error")
+	if ErrorCode(err) != ERROR_CONFLICT {
+		t.Error("bad error: ", ErrorCode(err), err)
+	}
+	err = ledgerError(errors.New(`message: {"Error":"Incorrect number of arguments. Expecting appID!"}`), "Query CC return error")
+	if ErrorCode(err) != ERROR_VALIDATION {
+		t.Error("bad error: ", ErrorCode(err), err)
+	}
+}
+
+func TestLedgerErrorFromConnection(t *testing.T) {
+	err := ledgerError(errors.New("rpc error: code = Unavailable desc = grpc: the connection is unavailable"), "Query CC return error")
+	if ErrorCode(err) != ERROR_UNAVAILABLE {
+		t.Error("bad error: ", ErrorCode(err), err)
+	}
+	err = ledgerError(errors.New("rpc error: code = DeadlineExceeded desc = context deadline exceeded"), "Query CC return error")
+	if ErrorCode(err) != ERROR_TIMEOUT {
+		t.Error("bad error: ", ErrorCode(err), err)
+	}
+	err = ledgerError(errors.New("CreateTransaction return error: no valid endorsement"), "Query CC return error")
+	if ErrorCode(err) != ERROR_INTERNAL || err.Error() != "Query CC return error" {
+		t.Error("bad error: ", ErrorCode(err), err)
+	}
+}
@@ -10,7 +10,6 @@ import(
 	"github.com/hyperledger/fabric/protos/common"
 	pb "github.com/hyperledger/fabric/protos/peer"
 	"strconv"
-	"errors"
 )
 
 var log = logging.MustGetLogger("ocms.helpers")
@@ -46,7 +45,7 @@ func (nh *NetworkHelper) Init(userCredentials UserCredentials) error{
 		return err
 	}
 	if err := eventHub.Connect(); err != nil {
-		return err
+		return ledgerError(err, "Connect event hub return error")
 	}
 	nh.Chain    = chain
 	nh.Client   = client
@@ -55,6 +54,13 @@ func (nh *NetworkHelper) Init(userCredentials UserCredentials) error{
 	return nil
 }
 
+func (nh *NetworkHelper) InitSession(session *Session) {
+	nh.Chain    = session.Chain
+	nh.Client   = session.Client
+	nh.EventHub = session.EventHub
+	nh.Initialized = true
+}
+
 func (nh *NetworkHelper) StartNetwork(userCredentials UserCredentials, providerName, netConfigFile, channelConfig string)  error{
 	log.Debug("InitNetwork(username:"+ userCredentials.UserName+" providerName:"+ providerName+") : calling method -")
 	initError := fmt.Errorf("InitNetwork return error")
@@ -185,7 +191,7 @@ func getChain(userCredentials UserCredentials, statStorePath, chainID string) (f
 
 	client, err := getClient(userCredentials, statStorePath)
 	if err != nil {
-		return chain, errors.New("getClient return error: %v" + err.Error())
+		return chain, err
 	}
 	chain, err = sdkUtil.GetChain(client, chainID)
 	if err != nil {
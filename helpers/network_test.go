@@ -29,7 +29,7 @@ const(
 	CONSUMERID1	   = "consumer1"
 	CONSUMERID2	   = "consumer2"
 	CONSUMERID3	   = "consumer3"
-	DATATYPE1  	   = "type1"
+	DATATYPE1  	   = "BP"
 	DATAACCESS1	   = "access1"
 	TransactionTimeout = time.Millisecond * 1500
 )
@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"crypto/x509"
+	"io/ioutil"
+	"path"
+	"strings"
+	"github.com/consentv2/certattr"
+	fabricClient "github.com/hyperledger/fabric-sdk-go/fabric-client"
+)
+
+// roles of the users of the API
+const (
+	ROLE_ADMIN   = "admin"
+	ROLE_APP     = "app"
+	ROLE_OWNER   = "owner"
+	ROLE_AUDITOR = "auditor"
+)
+
+var Roles = []string{ROLE_ADMIN, ROLE_APP, ROLE_OWNER, ROLE_AUDITOR}
+
+// GetRoles returns the roles of an enrolled user, read from the attribute roleAttribute of its enrollment certificate
+// (fabric-ca attribute or OU roleAttribute=role, the convention of the chaincode), the unknown roles are ignored
+func GetRoles(userName, statStorePath, roleAttribute string) ([]string, error) {
+	log.Debug("GetRoles(username:"+ userName+") : calling method -")
+	if strings.ContainsAny(userName, "/\\") {
+		return nil, NewError(ERROR_VALIDATION, "username is not valid")
+	}
+	data, err := ioutil.ReadFile(path.Join(statStorePath, userName+".json"))
+	if err != nil {
+		return nil, NewError(ERROR_UNAUTHORIZED, "%s is not enrolled", userName)
+	}
+	var user fabricClient.UserJSON
+	if err = json.Unmarshal(data, &user); err != nil {
+		return nil, NewError(ERROR_INTERNAL, "bad user %s in the state store", userName)
+	}
+	return CertificateRoles(user.EnrollmentCertificate, roleAttribute)
+}
+
+// CertificateRoles returns the roles of a PEM certificate
+func CertificateRoles(certPEM []byte, roleAttribute string) ([]string, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, NewError(ERROR_INTERNAL, "bad enrollment certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, NewError(ERROR_INTERNAL, "x509 ParseCertificate return error: %v", err)
+	}
+	roles := []string{}
+	for _, name := range certattr.Values(cert, roleAttribute) {
+		if isRole(name) && !isRoleIn(name, roles) {
+			roles = append(roles, name)
+		}
+	}
+	return roles, nil
+}
+
+func isRole(name string) bool {
+	return isRoleIn(name, Roles)
+}
+
+func isRoleIn(role string, roles []string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
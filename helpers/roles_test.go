@@ -0,0 +1,66 @@
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+	"github.com/consentv2/certattr"
+	fabricClient "github.com/hyperledger/fabric-sdk-go/fabric-client"
+)
+
+// enrollment certificate with the OU and the attributes of the fabric CA
+func newEnrollmentCertificate(t *testing.T, userName string, ous []string, attributes map[string]string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: userName, OrganizationalUnit: ous},
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	if attributes != nil {
+		value, _ := json.Marshal(certattr.Attributes{Attrs: attributes})
+		template.ExtraExtensions = []pkix.Extension{{Id: certattr.OID, Value: value}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestGetRolesFromCertificate(t *testing.T) {
+	statStorePath, err := ioutil.TempDir("", "statstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(statStorePath)
+	cert := newEnrollmentCertificate(t, "user1", []string{"client", ROLE_ADMIN, "ocms.role=" + ROLE_APP}, map[string]string{"hf.Type": "client", "ocms.role": "auditor, owner,unknown"})
+	data, _ := json.Marshal(fabricClient.UserJSON{EnrollmentCertificate: cert})
+	ioutil.WriteFile(path.Join(statStorePath, "user1.json"), data, 0600)
+
+	roles, err := GetRoles("user1", statStorePath, "ocms.role")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 3 || roles[0] != ROLE_AUDITOR || roles[1] != ROLE_OWNER || roles[2] != ROLE_APP {
+		t.Error("bad roles: ", roles)
+	}
+	// a bare OU is not a role, the OU must be named by the role attribute like in the chaincode
+	roles, _ = GetRoles("user1", statStorePath, "other.role")
+	if len(roles) != 0 {
+		t.Error("bad roles: ", roles)
+	}
+	_, err = GetRoles("user2", statStorePath, "ocms.role")
+	if ErrorCode(err) != ERROR_UNAUTHORIZED {
+		t.Error("roles of a user not enrolled: ", err)
+	}
+}
@@ -0,0 +1,256 @@
+package helpers
+
+import (
+	"sync"
+	"time"
+	fabricClient "github.com/hyperledger/fabric-sdk-go/fabric-client"
+	sdkUtil "github.com/hyperledger/fabric-sdk-go/fabric-client/helpers"
+	"github.com/hyperledger/fabric-sdk-go/fabric-client/events"
+)
+
+// Fabric session of an identity: the client and the chain are built once per identity and reused by the requests,
+// the event hub is shared by all the sessions (one connection for the server)
+type Session struct {
+	UserName	string
+	Client		fabricClient.Client
+	Chain		fabricClient.Chain
+	EventHub	events.EventHub
+	lastUsed	time.Time
+	inUse		int
+}
+
+// helpers which can be initialized from a borrowed session instead of the credentials
+type SessionHelper interface {
+	InitSession(session *Session)
+}
+
+type SessionMetrics struct {
+	Sessions		int	`json:"sessions"`
+	InUse			int	`json:"inuse"`
+	MaxSessions		int	`json:"maxsessions"`
+	IdleTimeout		string	`json:"idletimeout"`
+	Hits			uint64	`json:"hits"`
+	Misses			uint64	`json:"misses"`
+	Evictions		uint64	`json:"evictions"`
+	Rejected		uint64	`json:"rejected"`
+	Errors			uint64	`json:"errors"`
+	EventHubConnected	bool	`json:"eventhubconnected"`
+	EventHubConnects	uint64	`json:"eventhubconnects"`
+}
+
+// SessionManager caches the sessions by identity, a session idle for more than IdleTimeout is evicted,
+// when MaxSessions sessions are open the least recently used idle session is evicted to make room.
+// The identity is verified before (Authenticate): one session by user whatever its credentials (secret or token)
+type SessionManager struct {
+	StatStorePath	string
+	ChainID		string
+	MaxSessions	int
+	IdleTimeout	time.Duration
+	mutex		sync.Mutex
+	sessions	map[string]*Session
+	// removals by user, a session built during a removal is not cached
+	removals	map[string]uint64
+	eventHub	events.EventHub
+	metrics		SessionMetrics
+	stop		chan struct{}
+	// factories, replaced in the tests
+	newSession	func(UserCredentials) (*Session, error)
+	newEventHub	func() (events.EventHub, error)
+}
+
+// NewSessionManager returns a session manager, the idle sessions are evicted in background until Close
+func NewSessionManager(statStorePath, chainID string, maxSessions int, idleTimeout time.Duration) *SessionManager {
+	sm := &SessionManager{StatStorePath: statStorePath, ChainID: chainID, MaxSessions: maxSessions, IdleTimeout: idleTimeout,
+		sessions: map[string]*Session{}, removals: map[string]uint64{}, stop: make(chan struct{}), newEventHub: getEventHub}
+	sm.newSession = sm.createSession
+	if idleTimeout > 0 {
+		go sm.evictLoop()
+	}
+	return sm
+}
+
+// Borrow returns the session of the identity, created on the first request, the session must be released after use
+func (sm *SessionManager) Borrow(userCredentials UserCredentials) (*Session, error) {
+	log.Debug("Borrow(username:"+ userCredentials.UserName+") : calling method -")
+	eventHub, err := sm.sharedEventHub()
+	if err != nil {
+		return nil, err
+	}
+	sm.mutex.Lock()
+	session, ok := sm.sessions[userCredentials.UserName]
+	if ok {
+		session.inUse++
+		session.lastUsed = time.Now()
+		sm.metrics.Hits++
+		sm.mutex.Unlock()
+		return session, nil
+	}
+	sm.metrics.Misses++
+	removals := sm.removals[userCredentials.UserName]
+	sm.mutex.Unlock()
+
+	// the enrollment is slow, the session is built without the lock
+	session, err = sm.newSession(userCredentials)
+	if err != nil {
+		sm.mutex.Lock()
+		sm.metrics.Errors++
+		sm.mutex.Unlock()
+		return nil, err
+	}
+	session.UserName = userCredentials.UserName
+	session.EventHub = eventHub
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.removals[userCredentials.UserName] != removals {
+		// removed (revoked user) while the session was built
+		sm.metrics.Errors++
+		return nil, NewError(ERROR_UNAUTHORIZED, "session of %s is removed", userCredentials.UserName)
+	}
+	if existing, ok := sm.sessions[userCredentials.UserName]; ok {
+		// built by a concurrent request
+		session = existing
+	} else {
+		if sm.MaxSessions > 0 && len(sm.sessions) >= sm.MaxSessions && !sm.evictLeastRecentlyUsed() {
+			sm.metrics.Rejected++
+			return nil, NewError(ERROR_UNAVAILABLE, "too many sessions (%d), retry later", sm.MaxSessions)
+		}
+		sm.sessions[userCredentials.UserName] = session
+	}
+	session.inUse++
+	session.lastUsed = time.Now()
+	return session, nil
+}
+
+// Release gives back a borrowed session
+func (sm *SessionManager) Release(session *Session) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	session.inUse--
+	session.lastUsed = time.Now()
+}
+
+// Remove drops the session of an identity (revoked user), the current borrowers keep it until they release it,
+// a session being built for the identity is not cached
+func (sm *SessionManager) Remove(userName string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	delete(sm.sessions, userName)
+	sm.removals[userName]++
+}
+
+// Evict drops the sessions idle for more than IdleTimeout, returns the number of evicted sessions
+func (sm *SessionManager) Evict() int {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	evicted := 0
+	limit := time.Now().Add(-sm.IdleTimeout)
+	for userName, session := range sm.sessions {
+		if session.inUse == 0 && session.lastUsed.Before(limit) {
+			delete(sm.sessions, userName)
+			evicted++
+		}
+	}
+	sm.metrics.Evictions += uint64(evicted)
+	if evicted > 0 {
+		log.Debug("Evict() : ", evicted, " idle sessions evicted")
+	}
+	return evicted
+}
+
+func (sm *SessionManager) Metrics() SessionMetrics {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	metrics := sm.metrics
+	metrics.Sessions = len(sm.sessions)
+	for _, session := range sm.sessions {
+		if session.inUse > 0 {
+			metrics.InUse++
+		}
+	}
+	metrics.MaxSessions = sm.MaxSessions
+	metrics.IdleTimeout = sm.IdleTimeout.String()
+	metrics.EventHubConnected = sm.eventHub != nil && sm.eventHub.IsConnected()
+	return metrics
+}
+
+// Close stops the eviction, drops the sessions and disconnects the event hub
+func (sm *SessionManager) Close() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	select {
+	case <-sm.stop:
+	default:
+		close(sm.stop)
+	}
+	sm.sessions = map[string]*Session{}
+	if sm.eventHub != nil {
+		sm.eventHub.Disconnect()
+		sm.eventHub = nil
+	}
+}
+
+// the event hub is connected once and reconnected if the connection is lost
+func (sm *SessionManager) sharedEventHub() (events.EventHub, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.eventHub != nil && sm.eventHub.IsConnected() {
+		return sm.eventHub, nil
+	}
+	if sm.eventHub == nil {
+		eventHub, err := sm.newEventHub()
+		if err != nil {
+			sm.metrics.Errors++
+			return nil, err
+		}
+		sm.eventHub = eventHub
+	}
+	if err := sm.eventHub.Connect(); err != nil {
+		sm.metrics.Errors++
+		return nil, ledgerError(err, "Connect event hub return error")
+	}
+	sm.metrics.EventHubConnects++
+	return sm.eventHub, nil
+}
+
+// must be called with the lock, returns false if all the sessions are in use
+func (sm *SessionManager) evictLeastRecentlyUsed() bool {
+	var oldest *Session
+	for _, session := range sm.sessions {
+		if session.inUse == 0 && (oldest == nil || session.lastUsed.Before(oldest.lastUsed)) {
+			oldest = session
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(sm.sessions, oldest.UserName)
+	sm.metrics.Evictions++
+	return true
+}
+
+func (sm *SessionManager) evictLoop() {
+	ticker := time.NewTicker(sm.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sm.Evict()
+		case <-sm.stop:
+			return
+		}
+	}
+}
+
+func (sm *SessionManager) createSession(userCredentials UserCredentials) (*Session, error) {
+	client, err := getClient(userCredentials, sm.StatStorePath)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := sdkUtil.GetChain(client, sm.ChainID)
+	if err != nil {
+		log.Error("Create chain ", sm.ChainID, " failed: ", err)
+		return nil, err
+	}
+	return &Session{Client: client, Chain: chain}, nil
+}
@@ -0,0 +1,168 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"github.com/hyperledger/fabric-sdk-go/fabric-client/events"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+type fakeEventHub struct {
+	connected	bool
+	connects	int
+}
+
+func (eh *fakeEventHub) SetPeerAddr(peerURL string, certificate string, serverHostOverride string) {}
+func (eh *fakeEventHub) IsConnected() bool { return eh.connected }
+func (eh *fakeEventHub) Connect() error { eh.connected = true; eh.connects++; return nil }
+func (eh *fakeEventHub) Disconnect() { eh.connected = false }
+func (eh *fakeEventHub) RegisterChaincodeEvent(ccid string, eventname string, callback func(*events.ChaincodeEvent)) *events.ChainCodeCBE { return nil }
+func (eh *fakeEventHub) UnregisterChaincodeEvent(cbe *events.ChainCodeCBE) {}
+func (eh *fakeEventHub) RegisterTxEvent(txID string, callback func(string, error)) {}
+func (eh *fakeEventHub) UnregisterTxEvent(txID string) {}
+func (eh *fakeEventHub) RegisterBlockEvent(callback func(*common.Block)) {}
+func (eh *fakeEventHub) UnregisterBlockEvent(callback func(*common.Block)) {}
+
+func newTestSessionManager(maxSessions int, idleTimeout time.Duration) (*SessionManager, *fakeEventHub, *int) {
+	eventHub := &fakeEventHub{}
+	created := 0
+	sm := NewSessionManager("", "testchannel", maxSessions, 0)
+	sm.IdleTimeout = idleTimeout
+	sm.newEventHub = func() (events.EventHub, error) { return eventHub, nil }
+	sm.newSession = func(userCredentials UserCredentials) (*Session, error) {
+		if userCredentials.EnrollmentSecret == "bad" {
+			return nil, NewError(ERROR_UNAUTHORIZED, "bad credentials")
+		}
+		created++
+		return &Session{}, nil
+	}
+	return sm, eventHub, &created
+}
+
+func TestSessionReusedByIdentity(t *testing.T) {
+	sm, eventHub, created := newTestSessionManager(10, time.Minute)
+	defer sm.Close()
+	user1 := UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"}
+	session1, err := sm.Borrow(user1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.Release(session1)
+	session2, _ := sm.Borrow(user1)
+	sm.Release(session2)
+	if session1 != session2 || *created != 1 {
+		t.Error("session not reused: ", *created)
+	}
+	session3, _ := sm.Borrow(UserCredentials{UserName: "user2", EnrollmentSecret: "pwd2"})
+	sm.Release(session3)
+	if session3 == session1 || session3.EventHub != session1.EventHub || eventHub.connects != 1 {
+		t.Error("event hub not shared: ", eventHub.connects)
+	}
+	// the identity is verified before: a bearer token (no secret) reuses the session of the basic credentials
+	session4, _ := sm.Borrow(UserCredentials{UserName: "user1"})
+	sm.Release(session4)
+	session5, _ := sm.Borrow(user1)
+	sm.Release(session5)
+	if session4 != session1 || session5 != session1 || *created != 2 {
+		t.Error("session not reused by the other credentials of the identity: ", *created)
+	}
+	metrics := sm.Metrics()
+	if metrics.Hits != 3 || metrics.Misses != 2 || metrics.Sessions != 2 || !metrics.EventHubConnected {
+		t.Error("bad metrics: ", metrics)
+	}
+	_, err = sm.Borrow(UserCredentials{UserName: "user3", EnrollmentSecret: "bad"})
+	if ErrorCode(err) != ERROR_UNAUTHORIZED || sm.Metrics().Errors != 1 {
+		t.Error("bad error: ", err)
+	}
+}
+
+func TestSessionEventHubReconnect(t *testing.T) {
+	sm, eventHub, _ := newTestSessionManager(10, time.Minute)
+	defer sm.Close()
+	session, _ := sm.Borrow(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"})
+	sm.Release(session)
+	eventHub.Disconnect()
+	session, err := sm.Borrow(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"})
+	if err != nil || !eventHub.IsConnected() || eventHub.connects != 2 {
+		t.Error("event hub not reconnected: ", eventHub.connects, err)
+	}
+	sm.Release(session)
+}
+
+func TestSessionIdleEviction(t *testing.T) {
+	sm, _, created := newTestSessionManager(10, time.Millisecond*10)
+	defer sm.Close()
+	borrowed, _ := sm.Borrow(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"})
+	idle, _ := sm.Borrow(UserCredentials{UserName: "user2", EnrollmentSecret: "pwd2"})
+	sm.Release(idle)
+	time.Sleep(time.Millisecond * 20)
+	if evicted := sm.Evict(); evicted != 1 {
+		t.Error("bad number of evicted sessions: ", evicted)
+	}
+	sm.Release(borrowed)
+	sm.Borrow(UserCredentials{UserName: "user2", EnrollmentSecret: "pwd2"})
+	if *created != 3 || sm.Metrics().Evictions != 1 {
+		t.Error("idle session not evicted: ", *created)
+	}
+}
+
+func TestSessionBoundedSize(t *testing.T) {
+	sm, _, _ := newTestSessionManager(2, time.Minute)
+	defer sm.Close()
+	session1, _ := sm.Borrow(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"})
+	session2, _ := sm.Borrow(UserCredentials{UserName: "user2", EnrollmentSecret: "pwd2"})
+	_, err := sm.Borrow(UserCredentials{UserName: "user3", EnrollmentSecret: "pwd3"})
+	if ErrorCode(err) != ERROR_UNAVAILABLE {
+		t.Error("session created over the limit: ", err)
+	}
+	sm.Release(session1)
+	sm.Release(session2)
+	_, err = sm.Borrow(UserCredentials{UserName: "user3", EnrollmentSecret: "pwd3"})
+	metrics := sm.Metrics()
+	if err != nil || metrics.Sessions != 2 || metrics.Evictions != 1 || metrics.Rejected != 1 {
+		t.Error("least recently used session not evicted: ", metrics, err)
+	}
+	if _, ok := sm.sessions["user1"]; ok {
+		t.Error("bad evicted session")
+	}
+}
+
+func TestSessionNotConfigured(t *testing.T) {
+	sm := NewSessionManager("", "testchannel", 1, time.Minute)
+	sm.newEventHub = func() (events.EventHub, error) { return nil, errors.New("No EventHub configuration found") }
+	defer sm.Close()
+	_, err := sm.Borrow(UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"})
+	if err == nil || sm.Metrics().Errors != 1 {
+		t.Error("session without event hub: ", err)
+	}
+}
+
+func TestSessionRemove(t *testing.T) {
+	sm, _, created := newTestSessionManager(10, time.Minute)
+	defer sm.Close()
+	user1 := UserCredentials{UserName: "user1", EnrollmentSecret: "pwd1"}
+	session1, _ := sm.Borrow(user1)
+	sm.Release(session1)
+	sm.Remove("user1")
+	session2, _ := sm.Borrow(user1)
+	sm.Release(session2)
+	if session2 == session1 || *created != 2 {
+		t.Error("removed session handed out again: ", *created)
+	}
+
+	// a session built while the identity is removed is not cached
+	newSession := sm.newSession
+	sm.newSession = func(userCredentials UserCredentials) (*Session, error) {
+		sm.Remove(userCredentials.UserName)
+		return newSession(userCredentials)
+	}
+	sm.Remove("user1")
+	_, err := sm.Borrow(user1)
+	if ErrorCode(err) != ERROR_UNAUTHORIZED {
+		t.Error("session built during a removal handed out: ", err)
+	}
+	if _, ok := sm.sessions["user1"]; ok {
+		t.Error("session built during a removal cached")
+	}
+}
@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"sync"
+	"time"
+	"github.com/hyperledger/fabric-sdk-go/fabric-client/events"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// commit modes of the transactions
+const (
+	// the transaction is sent to the orderer, the commit is not checked
+	COMMIT_SUBMIT = "submit"
+	// the transaction is sent and the commit is waited, its validation code is returned
+	COMMIT_WAIT   = "wait"
+	// the transaction is sent, its commit is tracked and given by the status of the transaction
+	COMMIT_ASYNC  = "async"
+)
+
+var CommitModes = []string{COMMIT_SUBMIT, COMMIT_WAIT, COMMIT_ASYNC}
+
+// status of a transaction
+const (
+	TX_PENDING = "pending"
+	TX_VALID   = "valid"
+	TX_INVALID = "invalid"
+)
+
+type TransactionStatus struct {
+	TxID		string	`json:"txid"`
+	Status		string	`json:"status"`
+	ValidationCode	string	`json:"validationcode,omitempty"`
+	Reason		string	`json:"reason,omitempty"`
+}
+
+// helpers which send transactions in a commit mode
+type CommitHelper interface {
+	SetCommit(commit *Commit)
+}
+
+// Commit gives the commit mode of the transactions of a request and records their status
+type Commit struct {
+	Mode		string
+	Timeout		time.Duration
+	Tracker		*TransactionTracker
+	mutex		sync.Mutex
+	transactions	[]TransactionStatus
+}
+
+func (c *Commit) Record(status TransactionStatus) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.transactions = append(c.transactions, status)
+}
+
+// Transactions returns the status of the transactions sent during the request
+func (c *Commit) Transactions() []TransactionStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]TransactionStatus{}, c.transactions...)
+}
+
+type trackedTransaction struct {
+	status		TransactionStatus
+	expiresAt	time.Time
+	eventHub	events.EventHub
+}
+
+// TransactionTracker keeps the status of the asynchronous transactions during TTL
+type TransactionTracker struct {
+	TTL		time.Duration
+	mutex		sync.Mutex
+	transactions	map[string]*trackedTransaction
+}
+
+func NewTransactionTracker(ttl time.Duration) *TransactionTracker {
+	return &TransactionTracker{TTL: ttl, transactions: map[string]*trackedTransaction{}}
+}
+
+// Track registers a pending transaction, its status is updated by the commit event of the event hub
+func (tt *TransactionTracker) Track(txID string, eventHub events.EventHub) {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	now := time.Now()
+	for id, tracked := range tt.transactions {
+		if now.After(tracked.expiresAt) {
+			if tracked.status.Status == TX_PENDING && tracked.eventHub != nil {
+				tracked.eventHub.UnregisterTxEvent(id)
+			}
+			delete(tt.transactions, id)
+		}
+	}
+	tt.transactions[txID] = &trackedTransaction{status: TransactionStatus{TxID: txID, Status: TX_PENDING}, expiresAt: now.Add(tt.TTL), eventHub: eventHub}
+}
+
+// Set updates the status of a tracked transaction
+func (tt *TransactionTracker) Set(status TransactionStatus) {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	if tracked, ok := tt.transactions[status.TxID]; ok {
+		tracked.status = status
+	}
+}
+
+// Get returns the status of a tracked transaction
+func (tt *TransactionTracker) Get(txID string) (TransactionStatus, bool) {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	tracked, ok := tt.transactions[txID]
+	if !ok || time.Now().After(tracked.expiresAt) {
+		return TransactionStatus{}, false
+	}
+	return tracked.status, true
+}
+
+// status of a committed transaction from its validation code
+func committedStatus(txID string, code int32) TransactionStatus {
+	validationCode := pb.TxValidationCode(code).String()
+	if pb.TxValidationCode(code) == pb.TxValidationCode_VALID {
+		return TransactionStatus{TxID: txID, Status: TX_VALID, ValidationCode: validationCode}
+	}
+	return TransactionStatus{TxID: txID, Status: TX_INVALID, ValidationCode: validationCode, Reason: validationCode}
+}
+
+// error of an invalid transaction, the read conflicts can be retried
+func invalidTransactionError(status TransactionStatus) error {
+	switch status.ValidationCode {
+	case pb.TxValidationCode_MVCC_READ_CONFLICT.String(), pb.TxValidationCode_PHANTOM_READ_CONFLICT.String(), pb.TxValidationCode_DUPLICATE_TXID.String():
+		return NewError(ERROR_CONFLICT, "transaction %s invalid: %s", status.TxID, status.Reason)
+	}
+	return NewError(ERROR_INTERNAL, "transaction %s invalid: %s", status.TxID, status.Reason)
+}
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+func TestTransactionTracker(t *testing.T) {
+	tracker := NewTransactionTracker(time.Minute)
+	tracker.Track("tx1", &fakeEventHub{})
+	status, ok := tracker.Get("tx1")
+	if !ok || status.Status != TX_PENDING {
+		t.Error("transaction not pending: ", status, ok)
+	}
+	tracker.Set(committedStatus("tx1", int32(pb.TxValidationCode_MVCC_READ_CONFLICT)))
+	status, _ = tracker.Get("tx1")
+	if status.Status != TX_INVALID || status.ValidationCode != "MVCC_READ_CONFLICT" {
+		t.Error("bad status: ", status)
+	}
+	if ErrorCode(invalidTransactionError(status)) != ERROR_CONFLICT {
+		t.Error("read conflict not retryable: ", invalidTransactionError(status))
+	}
+	if ErrorCode(invalidTransactionError(committedStatus("tx1", int32(pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE)))) != ERROR_INTERNAL {
+		t.Error("bad error of an endorsement failure")
+	}
+	if _, ok = tracker.Get("tx2"); ok {
+		t.Error("untracked transaction found")
+	}
+	// a status of an untracked transaction is ignored
+	tracker.Set(TransactionStatus{TxID: "tx2", Status: TX_VALID})
+	if _, ok = tracker.Get("tx2"); ok {
+		t.Error("untracked transaction set")
+	}
+}
+
+func TestTransactionTrackerExpiration(t *testing.T) {
+	tracker := NewTransactionTracker(time.Millisecond)
+	tracker.Track("tx1", &fakeEventHub{})
+	time.Sleep(time.Millisecond * 5)
+	if _, ok := tracker.Get("tx1"); ok {
+		t.Error("expired transaction found")
+	}
+	tracker.Track("tx2", &fakeEventHub{})
+	if len(tracker.transactions) != 1 {
+		t.Error("expired transaction not pruned: ", len(tracker.transactions))
+	}
+}
@@ -11,11 +11,13 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type UserHelper struct {
 	StatStorePath   string
+	RoleAttribute   string
 	AdmClient       fabricClient.Client
 	AdmUser		fabricClient.User
 	CaClient       	fabricCAClient.Services
@@ -32,6 +34,7 @@ type UserRegistrer struct {
 	Name 		 string	`json:"name"`
 	Type 		 string	`json:"type"`
 	Affiliation      string	`json:"affiliation"`
+	Roles            []string `json:"roles"`
 }
 
 func (uh *UserHelper) Init (userCredentials UserCredentials) error{
@@ -57,6 +60,15 @@ func (uh *UserHelper) Init (userCredentials UserCredentials) error{
 func (uh *UserHelper) RegisterUser(registerUser UserRegistrer) (string, error) {
 	log.Debug("registerUser(name:"+ registerUser.Name+" Type:" + registerUser.Type +" Affiliation:"+ registerUser.Affiliation+") : calling method -")
 	registerRequest := fabricCAClient.RegistrationRequest{Name: registerUser.Name, Type: registerUser.Type, Affiliation: registerUser.Affiliation}
+	if len(registerUser.Roles) > 0 {
+		for _, role := range registerUser.Roles {
+			if !isRole(role) {
+				return "", NewError(ERROR_VALIDATION, "role %s is not valid", role)
+			}
+		}
+		// the roles are written in the enrollment certificate
+		registerRequest.Attributes = []fabricCAClient.Attribute{{Key: uh.RoleAttribute, Value: strings.Join(registerUser.Roles, ",")}}
+	}
 	enrolmentSecret, err := uh.CaClient.Register(uh.AdmUser, &registerRequest)
 	if err != nil {
 		return "", err
@@ -79,7 +91,7 @@ func (uh *UserHelper) EnrollUser(userCredentials UserCredentials) error{
 	if err != nil {
 		return errors.New("Error write key: %s"+ err.Error())
 	}
-	return nil
+	return saveSecretHash(userCredentials, uh.StatStorePath)
 }
 
 func (uh *UserHelper) ReenrollUser(userCredentials UserCredentials) error{
@@ -166,6 +178,10 @@ func (uh *UserHelper) GetUser(userCredentials UserCredentials) (fabricClient.Use
 		if user == nil {
 			return user, errors.New("client.GetUserContext return nil")
 		}
+		err = saveSecretHash(userCredentials, uh.StatStorePath)
+		if err != nil {
+			return user, err
+		}
 	}
 	return user, nil
 }
@@ -173,10 +189,17 @@ func (uh *UserHelper) GetUser(userCredentials UserCredentials) (fabricClient.Use
 
 func getClient(userCredentials UserCredentials, statStorePath string) (fabricClient.Client, error) {
 	log.Debug("GetClient(username:"+ userCredentials.UserName+") : calling method -")
+	enrolled := isEnrolled(userCredentials.UserName, statStorePath)
 	client, err := sdkUtil.GetClient(userCredentials.UserName, userCredentials.EnrollmentSecret, statStorePath)
 	if err != nil {
 		log.Debug("getClient return error: %v" + err.Error())
-		return client, errors.New("getClient return error: %v" + err.Error())
+		return client, enrollmentError(err)
+	}
+	// a user not in the state store has been enrolled with the secret
+	if !enrolled && userCredentials.EnrollmentSecret != "" {
+		if err = saveSecretHash(userCredentials, statStorePath); err != nil {
+			return client, err
+		}
 	}
 	return client, nil
 }
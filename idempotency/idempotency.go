@@ -0,0 +1,198 @@
+// Package idempotency keeps the responses of the write requests sent with an idempotency key.
+// A request retried with the same key gets the stored response again instead of sending a new transaction,
+// the key reused with another request is rejected. The records are json files of a local directory, kept during a TTL.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMismatch   = errors.New("idempotency: key already used with another request")
+	ErrInProgress = errors.New("idempotency: a request with this key is in progress")
+	ErrNoPath     = errors.New("idempotency: store path is mandatory")
+)
+
+// Record is the response stored for a key.
+type Record struct {
+	RequestHash string      `json:"requesthash"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+	TxID        string      `json:"txid,omitempty"`
+	CreatedAt   int64       `json:"createdat"`
+	ExpiresAt   int64       `json:"expiresat"`
+}
+
+// Store keeps the records in the files of a directory, one file by key.
+type Store struct {
+	TTL     time.Duration
+	path    string
+	mutex   sync.Mutex
+	pending map[string]string
+}
+
+// NewStore creates the directory of the records if needed and removes the expired ones.
+func NewStore(path string, ttl time.Duration) (*Store, error) {
+	if path == "" {
+		return nil, ErrNoPath
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{TTL: ttl, path: path, pending: map[string]string{}}
+	if err := s.Prune(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RequestHash is the hash of a request, the same key must be sent with the same request.
+func RequestHash(method, uri string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + uri + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Begin returns the record of the key when the request is a retry, else the key is reserved until Save or Abort.
+func (s *Store) Begin(key, requestHash string) (*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if hash, ok := s.pending[key]; ok {
+		if hash != requestHash {
+			return nil, ErrMismatch
+		}
+		return nil, ErrInProgress
+	}
+	record, err := s.read(key)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		if record.RequestHash != requestHash {
+			return nil, ErrMismatch
+		}
+		return record, nil
+	}
+	s.pending[key] = requestHash
+	return nil, nil
+}
+
+// Save stores the response of a reserved key.
+func (s *Store) Save(key string, record Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.pending, key)
+	now := time.Now()
+	record.CreatedAt = now.Unix()
+	record.ExpiresAt = now.Add(s.TTL).Unix()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	// the file is renamed once written, a crash does not leave a partial record
+	file := s.file(key)
+	if err = ioutil.WriteFile(file+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// Abort releases a reserved key without response, the request can be retried with the key.
+func (s *Store) Abort(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.pending, key)
+}
+
+// Prune removes the expired records, the directory is read without lock (see PruneLoop).
+func (s *Store) Prune() error {
+	files, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, info := range files {
+		if !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+		file := filepath.Join(s.path, info.Name())
+		if record, err := readFile(file); err == nil && record.ExpiresAt > now {
+			continue
+		}
+		s.removeExpired(file, now)
+	}
+	return nil
+}
+
+// PruneLoop removes the expired records every interval, until stop is closed; errors are given to onError.
+func (s *Store) PruneLoop(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Prune(); err != nil && onError != nil {
+				onError(err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// the record is read again with the lock, it may have been replaced by Save since the directory was read
+func (s *Store) removeExpired(file string, now int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	record, err := readFile(file)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil || record.ExpiresAt <= now {
+		os.Remove(file)
+	}
+}
+
+func (s *Store) read(key string) (*Record, error) {
+	record, err := readFile(s.file(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.ExpiresAt <= time.Now().Unix() {
+		return nil, nil
+	}
+	return record, nil
+}
+
+func readFile(file string) (*Record, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	record := &Record{}
+	if err = json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// the name of the file is the hash of the key, the keys are given by the clients
+func (s *Store) file(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(s.path, hex.EncodeToString(hash[:])+".json")
+}
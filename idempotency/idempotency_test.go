@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, ttl time.Duration) (*Store, string) {
+	dir, err := ioutil.TempDir("", "idempotency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(dir, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, dir
+}
+
+func TestReplayAndMismatch(t *testing.T) {
+	store, dir := newTestStore(t, time.Hour)
+	defer os.RemoveAll(dir)
+	hash := RequestHash("POST", "/ocms/v3/api/apps/app1/consents", []byte(`{"ownerid":"o1"}`))
+	record, err := store.Begin("user1 key1", hash)
+	if record != nil || err != nil {
+		t.Fatal("new key not reserved: ", record, err)
+	}
+	if _, err = store.Begin("user1 key1", hash); err != ErrInProgress {
+		t.Error(ErrInProgress, " expected, but ", err, " received")
+	}
+	err = store.Save("user1 key1", Record{RequestHash: hash, Status: http.StatusCreated, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(`{"consentid":"c1"}`), TxID: "tx1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the record is read by a new store (restart)
+	store, err = NewStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err = store.Begin("user1 key1", hash)
+	if err != nil || record == nil || record.Status != http.StatusCreated || string(record.Body) != `{"consentid":"c1"}` || record.TxID != "tx1" {
+		t.Error("bad replay: ", record, err)
+	}
+	other := RequestHash("POST", "/ocms/v3/api/apps/app1/consents", []byte(`{"ownerid":"o2"}`))
+	if _, err = store.Begin("user1 key1", other); err != ErrMismatch {
+		t.Error(ErrMismatch, " expected, but ", err, " received")
+	}
+
+	// an aborted key can be used again
+	store.Begin("user1 key2", hash)
+	store.Abort("user1 key2")
+	if record, err = store.Begin("user1 key2", other); record != nil || err != nil {
+		t.Error("aborted key not released: ", record, err)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	store, dir := newTestStore(t, -time.Second)
+	defer os.RemoveAll(dir)
+	store.Begin("key1", "hash1")
+	if err := store.Save("key1", Record{RequestHash: "hash1", Status: http.StatusOK}); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := store.Begin("key1", "hash2"); record != nil || err != nil {
+		t.Error("expired record replayed: ", record, err)
+	}
+	store.Prune()
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Error("expired record not removed: ", len(files))
+	}
+	if _, err := NewStore("", time.Hour); err != ErrNoPath {
+		t.Error(ErrNoPath, " expected, but ", err, " received")
+	}
+}
+
+func TestPruneLoop(t *testing.T) {
+	store, dir := newTestStore(t, -time.Second)
+	defer os.RemoveAll(dir)
+	store.Begin("key1", "hash1")
+	if err := store.Save("key1", Record{RequestHash: "hash1", Status: http.StatusOK}); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		store.PruneLoop(10*time.Millisecond, stop, nil)
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		if files, _ := ioutil.ReadDir(dir); len(files) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+	<-done
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Error("expired record not removed in background: ", len(files))
+	}
+}
@@ -4,7 +4,14 @@ import(
 	"github.com/pascallimeux/ocmsV2/helpers"
 	"github.com/pascallimeux/ocmsV2/api"
 	"github.com/pascallimeux/ocmsV2/settings"
+	"github.com/pascallimeux/ocmsV2/attestation"
+	"github.com/pascallimeux/ocmsV2/auth"
+	"github.com/pascallimeux/ocmsV2/idempotency"
+	"github.com/pascallimeux/ocmsV2/tlsconfig"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 	"github.com/op/go-logging"
 	"github.com/gorilla/mux"
@@ -45,6 +52,61 @@ func main() {
 		Repo:                   configuration.Repo,
 		StatStorePath:          configuration.StatstorePath,
 		ChainID:         	configuration.ChainID,
+		AttestationIssuer:      configuration.AttestationIssuer,
+		AttestationTTL:         configuration.AttestationTTL,
+		LogAccessOnIsConsent:   configuration.LogAccessOnIsConsent,
+		DuplicateMode:          configuration.DuplicateMode,
+		CommitMode:             configuration.CommitMode,
+		CommitTimeout:          configuration.CommitTimeout,
+		Transactions:           helpers.NewTransactionTracker(configuration.TransactionTTL),
+		DocumentStorePath:      configuration.DocumentStorePath,
+		DocumentMaxSize:        configuration.DocumentMaxSize,
+		BackupPath:             configuration.BackupPath,
+		BackupPageSize:         configuration.BackupPageSize,
+		Sessions:               helpers.NewSessionManager(configuration.StatstorePath, configuration.ChainID, configuration.SessionMaxSize, configuration.SessionIdleTimeout),
+		AllowBasicAuth:         configuration.AllowBasicAuth,
+		RolePolicy:             api.RolePolicy{
+			RoleAttribute:  configuration.RoleAttribute,
+			Groups:         configuration.RouteRoles,
+			BootstrapAdmin: configuration.BootstrapAdmin,
+			StatStorePath:  configuration.StatstorePath},
+	}
+	// Init attestations, the private key is given by the deployment (no default key)
+	if configuration.AttestationEnabled {
+		if configuration.AttestationKeyFile == "" {
+			log.Fatal("attestation is enabled without key file")
+		}
+		appContext.AttestationKey, err = attestation.LoadPrivateKey(configuration.AttestationKeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Init the responses of the idempotent writes, disabled without store path
+	if configuration.IdempotencyStorePath != "" {
+		appContext.Idempotency, err = idempotency.NewStore(configuration.IdempotencyStorePath, configuration.IdempotencyTTL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go appContext.Idempotency.PruneLoop(time.Minute, nil, func(err error) {
+			log.Error("Idempotency prune return error: ", err)
+		})
+	}
+
+	// Init bearer tokens, without key file the tokens are lost at restart
+	var authKey []byte
+	if configuration.AuthKeyFile != "" {
+		authKey, err = auth.LoadKey(configuration.AuthKeyFile)
+	} else {
+		log.Warning("no auth key file, tokens are signed with a random key")
+		authKey, err = auth.GenerateKey()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext.Tokens, err = auth.NewManager(authKey, configuration.AuthIssuer, configuration.AuthTTL, configuration.AuthRefreshTTL, configuration.AuthRevocationFile)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Init routes for application
@@ -53,12 +115,41 @@ func main() {
 
 	s := &http.Server{
 		Addr:         configuration.HttpHostUrl,
-		Handler:      router,
+		Handler:      api.RequestID(api.Authenticate(appContext.Tokens, appContext.AllowBasicAuth, appContext.StatStorePath, api.ClientCertIdentity(api.Authorize(appContext.RolePolicy, api.BorrowSessions(appContext.Sessions, api.Idempotency(appContext.Idempotency, api.Commits(appContext.CommitMode, appContext.CommitTimeout, appContext.Transactions, api.ValidateRequests(router)))))))),
 		ReadTimeout:  configuration.ReadTimeout * time.Nanosecond,
 		WriteTimeout: configuration.WriteTimeout * time.Nanosecond,
 	}
-	log.Fatal(s.ListenAndServe().Error())
+	if configuration.TLS.CertFile == "" {
+		log.Fatal(s.ListenAndServe().Error())
+	}
+
+	// Init TLS, the certificates are reloaded without restart
+	reloader, err := tlsconfig.NewReloader(configuration.TLS)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.TLSConfig = reloader.TLSConfig()
+	go reloadTLS(reloader, configuration.TLSReloadInterval)
+	log.Fatal(s.ListenAndServeTLS("", "").Error())
 
 	defer configuration.CloseLogger()
 
 }
+
+// reload the certificates on SIGHUP or when the files change
+func reloadTLS(reloader *tlsconfig.Reloader, interval time.Duration) {
+	if interval > 0 {
+		go reloader.Watch(interval, nil, func(err error) {
+			log.Error("TLS reload return error: ", err)
+		})
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := reloader.Reload(); err != nil {
+			log.Error("TLS reload return error: ", err)
+			continue
+		}
+		log.Info("TLS certificates reloaded")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 	"github.com/op/go-logging"
+	"github.com/pascallimeux/ocmsV2/tlsconfig"
 )
 
 type Settings struct {
@@ -20,6 +21,8 @@ type Settings struct {
 	LogFile		   *os.File
 	ReadTimeout        time.Duration
 	WriteTimeout       time.Duration
+	TLS                tlsconfig.Settings
+	TLSReloadInterval  time.Duration
 
 	ChainCodePath      string
 	ChainCodeVersion   string
@@ -33,6 +36,39 @@ type Settings struct {
 	Adminusername	   string
 	AdminPwd           string
 
+	AttestationEnabled bool
+	AttestationKeyFile string
+	AttestationIssuer  string
+	AttestationTTL     time.Duration
+
+	LogAccessOnIsConsent bool
+	DuplicateMode      string
+	CommitMode         string
+	CommitTimeout      time.Duration
+	TransactionTTL     time.Duration
+
+	DocumentStorePath  string
+	DocumentMaxSize    int64
+
+	BackupPath         string
+	BackupPageSize     int
+
+	IdempotencyStorePath string
+	IdempotencyTTL     time.Duration
+
+	SessionMaxSize     int
+	SessionIdleTimeout time.Duration
+
+	AuthKeyFile        string
+	AuthIssuer         string
+	AuthTTL            time.Duration
+	AuthRefreshTTL     time.Duration
+	AuthRevocationFile string
+	AllowBasicAuth     bool
+
+	RoleAttribute      string
+	RouteRoles         map[string][]string
+	BootstrapAdmin     string
 
 }
 var log = logging.MustGetLogger("ocms.settings")
@@ -40,6 +76,9 @@ var log = logging.MustGetLogger("ocms.settings")
 func (s *Settings) ToString() string {
 	st :=     "Logger          --> file:" + s.LogFileName + " in " + s.LogMode + " mode \n"
 	st = st + "Server          --> url :" + s.HttpHostUrl
+	if s.TLS.CertFile != "" {
+		st = st + " (https, client auth: " + s.TLS.ClientAuth + ")"
+	}
 	return st
 }
 
@@ -110,6 +149,15 @@ func GetSettings(configPath, configFileName string) (Settings, error) {
 		}
 		configuration.ReadTimeout = viper.GetDuration("server.readTimeout")
 		configuration.WriteTimeout = viper.GetDuration("server.writeTimeout")
+		configuration.TLS = tlsconfig.Settings{
+			CertFile:     viper.GetString("server.tlsCertFile"),
+			KeyFile:      viper.GetString("server.tlsKeyFile"),
+			MinVersion:   viper.GetString("server.tlsMinVersion"),
+			CipherPolicy: viper.GetString("server.tlsCipherPolicy"),
+			ClientCAFile: viper.GetString("server.tlsClientCAFile"),
+			ClientAuth:   viper.GetString("server.tlsClientAuth"),
+		}
+		configuration.TLSReloadInterval = viper.GetDuration("server.tlsReloadInterval")
 
 		configuration.ChainCodePath = viper.GetString("chaincode.chainCodePath")
 		configuration.ChainCodeVersion = viper.GetString("chaincode.chainCodeVersion")
@@ -125,6 +173,43 @@ func GetSettings(configPath, configFileName string) (Settings, error) {
 		configuration.Adminusername = viper.GetString("admin.adminUsername")
 		configuration.AdminPwd = viper.GetString("admin.adminPwd")
 
+		configuration.AttestationEnabled = viper.GetBool("attestation.enabled")
+		configuration.AttestationKeyFile = viper.GetString("attestation.keyFile")
+		configuration.AttestationIssuer = viper.GetString("attestation.issuer")
+		configuration.AttestationTTL = viper.GetDuration("attestation.ttl")
+
+		configuration.LogAccessOnIsConsent = viper.GetBool("consent.logAccessOnIsConsent")
+		configuration.DuplicateMode = viper.GetString("consent.duplicateMode")
+		configuration.CommitMode = viper.GetString("consent.commitMode")
+		configuration.CommitTimeout = viper.GetDuration("consent.commitTimeout")
+		configuration.TransactionTTL = viper.GetDuration("consent.transactionTTL")
+
+		configuration.DocumentStorePath = viper.GetString("document.storePath")
+		configuration.DocumentMaxSize = viper.GetInt64("document.maxSize")
+
+		configuration.BackupPath = viper.GetString("backup.path")
+		configuration.BackupPageSize = viper.GetInt("backup.pageSize")
+
+		configuration.IdempotencyStorePath = viper.GetString("idempotency.storePath")
+		configuration.IdempotencyTTL = viper.GetDuration("idempotency.ttl")
+
+		configuration.SessionMaxSize = viper.GetInt("session.maxSessions")
+		configuration.SessionIdleTimeout = viper.GetDuration("session.idleTimeout")
+
+		configuration.AuthKeyFile = viper.GetString("auth.keyFile")
+		configuration.AuthIssuer = viper.GetString("auth.issuer")
+		configuration.AuthTTL = viper.GetDuration("auth.ttl")
+		configuration.AuthRefreshTTL = viper.GetDuration("auth.refreshTTL")
+		configuration.AuthRevocationFile = viper.GetString("auth.revocationFile")
+		configuration.AllowBasicAuth = viper.GetBool("auth.allowBasicAuth")
+
+		configuration.RoleAttribute = viper.GetString("rbac.roleAttribute")
+		configuration.BootstrapAdmin = viper.GetString("rbac.bootstrapAdmin")
+		configuration.RouteRoles = map[string][]string{}
+		for _, group := range []string{"consent", "dashboard", "admin"} {
+			configuration.RouteRoles[group] = viper.GetStringSlice("rbac." + group)
+		}
+
 		fmt.Println("Application configuration: \n" + configuration.ToString())
 		return configuration, nil
 	}
@@ -0,0 +1,222 @@
+// Package tlsconfig builds the TLS configuration of the ocms server.
+// The server certificate and the client CA are reloaded on file change or on demand (SIGHUP):
+// the new configuration is used by the next handshakes, the open connections are kept.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// cipher policies
+const (
+	// TLS 1.2 ECDHE suites with AEAD only (TLS 1.3 suites are not configurable)
+	POLICY_MODERN = "modern"
+	// modern suites and the ECDHE CBC suites for older clients
+	POLICY_INTERMEDIATE = "intermediate"
+	// suites of the Go runtime
+	POLICY_DEFAULT = "default"
+)
+
+// client authentication modes
+const (
+	CLIENTAUTH_NONE     = "none"
+	CLIENTAUTH_OPTIONAL = "optional"
+	CLIENTAUTH_REQUIRE  = "require"
+)
+
+var (
+	ErrNoCertificate = errors.New("tlsconfig: certificate and key files are mandatory")
+	ErrNoClientCA    = errors.New("tlsconfig: no certificate in the client CA file")
+)
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var modernCiphers = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+}
+
+var intermediateCiphers = append(append([]uint16{}, modernCiphers...),
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+)
+
+// Settings of the TLS server.
+type Settings struct {
+	CertFile     string
+	KeyFile      string
+	MinVersion   string
+	CipherPolicy string
+	ClientCAFile string
+	ClientAuth   string
+}
+
+// Reloader serves the last loaded configuration, built from the settings.
+type Reloader struct {
+	settings Settings
+	mutex    sync.RWMutex
+	config   *tls.Config
+	modTimes map[string]time.Time
+}
+
+// NewReloader loads the certificate, the key and the client CA of the settings.
+func NewReloader(settings Settings) (*Reloader, error) {
+	if settings.CertFile == "" || settings.KeyFile == "" {
+		return nil, ErrNoCertificate
+	}
+	r := &Reloader{settings: settings}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the configuration of the server, each handshake gets the last loaded configuration.
+func (r *Reloader) TLSConfig() *tls.Config {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	config := r.config.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+		return r.config, nil
+	}
+	return config
+}
+
+// Reload reads the files again, the current configuration is kept on error.
+func (r *Reloader) Reload() error {
+	config, err := build(r.settings)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.config = config
+	r.modTimes = r.readModTimes()
+	return nil
+}
+
+// Changed returns true when a file has been modified since the last reload.
+func (r *Reloader) Changed() bool {
+	modTimes := r.readModTimes()
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for file, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch reloads the files when they change, until stop is closed; errors are given to onError.
+func (r *Reloader) Watch(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if r.Changed() {
+				if err := r.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (r *Reloader) readModTimes() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	for _, file := range []string{r.settings.CertFile, r.settings.KeyFile, r.settings.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+func build(settings Settings) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	config.MinVersion, err = MinVersion(settings.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	config.CipherSuites, err = CipherSuites(settings.CipherPolicy)
+	if err != nil {
+		return nil, err
+	}
+	switch settings.ClientAuth {
+	case "", CLIENTAUTH_NONE:
+		config.ClientAuth = tls.NoClientCert
+	case CLIENTAUTH_OPTIONAL:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case CLIENTAUTH_REQUIRE:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("tlsconfig: unknown client authentication %s", settings.ClientAuth)
+	}
+	if config.ClientAuth != tls.NoClientCert {
+		data, err := ioutil.ReadFile(settings.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(data) {
+			return nil, ErrNoClientCA
+		}
+	}
+	return config, nil
+}
+
+// MinVersion returns the TLS version of "1.0" to "1.3", TLS 1.2 by default.
+func MinVersion(version string) (uint16, error) {
+	if version == "" {
+		return tls.VersionTLS12, nil
+	}
+	v, ok := versions[version]
+	if !ok {
+		return 0, fmt.Errorf("tlsconfig: unknown TLS version %s", version)
+	}
+	return v, nil
+}
+
+// CipherSuites returns the suites of a policy, nil for the Go defaults.
+func CipherSuites(policy string) ([]uint16, error) {
+	switch policy {
+	case "", POLICY_MODERN:
+		return modernCiphers, nil
+	case POLICY_INTERMEDIATE:
+		return intermediateCiphers, nil
+	case POLICY_DEFAULT:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("tlsconfig: unknown cipher policy %s", policy)
+}
@@ -0,0 +1,166 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newCert(t *testing.T, cn string, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if keyFile == "" {
+		return
+	}
+	der, _ := x509.MarshalECPrivateKey(c.key)
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestMutualTLSAndReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings := Settings{CertFile: filepath.Join(dir, "server.pem"), KeyFile: filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"), ClientAuth: CLIENTAUTH_REQUIRE, MinVersion: "1.2", CipherPolicy: POLICY_MODERN}
+	ca := newCert(t, "ca", 1, nil)
+	ca.write(t, settings.ClientCAFile, "")
+	newCert(t, "server1", 2, ca).write(t, settings.CertFile, settings.KeyFile)
+	client := newCert(t, "user1", 3, ca)
+
+	reloader, err := NewReloader(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}))
+	server.TLS = reloader.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certificates []tls.Certificate) (string, string, error) {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates}}}
+		response, err := httpClient.Get(server.URL)
+		if err != nil {
+			return "", "", err
+		}
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		return string(body), response.TLS.PeerCertificates[0].Subject.CommonName, nil
+	}
+	identity, serverName, err := get([]tls.Certificate{client.tlsCertificate()})
+	if err != nil || identity != "user1" || serverName != "server1" {
+		t.Error("bad handshake: ", identity, serverName, err)
+	}
+	_, _, err = get(nil)
+	if err == nil {
+		t.Error("client without certificate accepted")
+	}
+
+	// the new certificate is served without restart
+	if reloader.Changed() {
+		t.Error("files not changed")
+	}
+	time.Sleep(time.Millisecond * 10)
+	newCert(t, "server2", 4, ca).write(t, settings.CertFile, settings.KeyFile)
+	os.Chtimes(settings.CertFile, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	if !reloader.Changed() {
+		t.Error("changed files not detected")
+	}
+	if err = reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	_, serverName, err = get([]tls.Certificate{client.tlsCertificate()})
+	if err != nil || serverName != "server2" {
+		t.Error("certificate not reloaded: ", serverName, err)
+	}
+
+	// a bad file keeps the current configuration
+	ioutil.WriteFile(settings.KeyFile, []byte("bad key"), 0600)
+	if err = reloader.Reload(); err == nil {
+		t.Error("bad key loaded")
+	}
+	_, serverName, err = get([]tls.Certificate{client.tlsCertificate()})
+	if err != nil || serverName != "server2" {
+		t.Error("configuration lost: ", serverName, err)
+	}
+}
+
+func TestVersionsAndPolicies(t *testing.T) {
+	version, err := MinVersion("")
+	if err != nil || version != tls.VersionTLS12 {
+		t.Error("bad default version: ", version, err)
+	}
+	if _, err = MinVersion("2.0"); err == nil {
+		t.Error("unknown version accepted")
+	}
+	suites, err := CipherSuites(POLICY_INTERMEDIATE)
+	if err != nil || len(suites) != len(modernCiphers)+4 {
+		t.Error("bad suites: ", suites, err)
+	}
+	if _, err = CipherSuites("weak"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+	if _, err = NewReloader(Settings{}); err != ErrNoCertificate {
+		t.Error(ErrNoCertificate, " expected, but ", err, " received")
+	}
+}